@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,12 +14,21 @@ import (
 )
 
 type QueryRequest struct {
-	Query string `json:"query"`
+	Query  string                 `json:"query"`
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Rows requests the result's rows along with the results of each variable
+	Rows bool `json:"rows,omitempty"`
+	// ConfirmDeletion lets DETACH DELETE clauses delete resources. Without it they delete nothing,
+	// and the query fails with the plan of what they would delete.
+	ConfirmDeletion bool `json:"confirmDeletion,omitempty"`
 }
 
 type QueryResponse struct {
-	Result string `json:"result"`
-	Graph  string `json:"graph"`
+	Result    string   `json:"result"`
+	Graph     string   `json:"graph"`
+	Rows      string   `json:"rows,omitempty"`
+	Deletions string   `json:"deletions,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
 }
 
 type ContextInfo struct {
@@ -46,7 +56,8 @@ func handleQuery(c *gin.Context) {
 
 	// Create the API server provider
 	p, err := apiserver.NewAPIServerProviderWithOptions(&apiserver.APIServerProviderConfig{
-		DryRun: DryRun,
+		DryRun:            DryRun,
+		PropagationPolicy: core.Cascade,
 	})
 	if err != nil {
 		fmt.Printf("Provider error: %v\n", err)
@@ -63,7 +74,7 @@ func handleQuery(c *gin.Context) {
 	}
 
 	// Parse the query
-	ast, err := core.ParseQuery(req.Query)
+	ast, err := core.ParseQueryWithParams(req.Query, req.Params)
 	if err != nil {
 		fmt.Printf("Parse error: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error parsing query: %v", err)})
@@ -71,7 +82,24 @@ func handleQuery(c *gin.Context) {
 	}
 
 	// Execute the query
-	result, err := executor.Execute(ast, "")
+	result, err := executor.ExecuteWithOptions(ast, "", core.ExecuteOptions{
+		Rows: req.Rows,
+		ConfirmDeletion: func(plan *core.DeletionPlan) bool {
+			return req.ConfirmDeletion
+		},
+	})
+	if errors.Is(err, core.ErrDeletionCancelled) {
+		deletionsData, err := json.Marshal(result.Deletions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error marshalling deletions: %v", err)})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "DETACH DELETE wasn't confirmed: review the deletions and send the query again with confirmDeletion set to delete them",
+			"deletions": string(deletionsData),
+		})
+		return
+	}
 	if err != nil {
 		fmt.Printf("Execution error: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error executing query: %v", err)})
@@ -101,8 +129,31 @@ func handleQuery(c *gin.Context) {
 
 	// Return the response with both result and graph as strings
 	response := QueryResponse{
-		Result: string(resultData),
-		Graph:  string(graphData),
+		Result:   string(resultData),
+		Graph:    string(graphData),
+		Warnings: result.Warnings,
+	}
+
+	if req.Rows {
+		rows := result.Rows
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		rowsData, err := json.Marshal(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error marshalling rows: %v", err)})
+			return
+		}
+		response.Rows = string(rowsData)
+	}
+
+	if result.Deletions != nil {
+		deletionsData, err := json.Marshal(result.Deletions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error marshalling deletions: %v", err)})
+			return
+		}
+		response.Deletions = string(deletionsData)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -3,9 +3,12 @@ package main
 import (
 	"bufio"
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -18,8 +21,14 @@ type Macro struct {
 	Args        []string
 	Statements  []string
 	Description string
+	// ArgTypes holds the types declared for arguments, such as int for "count:int".
+	// Arguments without a declared type are strings.
+	ArgTypes map[string]string
 }
 
+// macroArgTypes are the types macro arguments may be declared with
+var macroArgTypes = []string{"string", "int", "float", "bool"}
+
 type MacroManager struct {
 	Macros map[string]*Macro
 }
@@ -45,7 +54,7 @@ func executeMacro(input string) (string, error) {
 	macroName = parts[0]
 	args := parts[1:]
 
-	statements, err := macroManager.ExecuteMacro(macroName, args)
+	statements, params, err := macroManager.ExecuteMacro(macroName, args)
 	if err != nil {
 		return "", err
 	}
@@ -53,7 +62,7 @@ func executeMacro(input string) (string, error) {
 	var results []string
 	var graph core.Graph
 	for i, stmt := range statements {
-		result, graphInternal, err := processQuery(stmt)
+		result, graphInternal, err := processStatement(stmt, params)
 		if err != nil {
 			return "", fmt.Errorf("error executing statement %d: %w", i+1, err)
 		}
@@ -77,25 +86,101 @@ func executeMacro(input string) (string, error) {
 
 // Execute the query against the Kubernetes API.
 
-func (mm *MacroManager) ExecuteMacro(name string, args []string) ([]string, error) {
+// ExecuteMacro returns the statements of a macro along with its arguments bound as query parameters.
+// Arguments are bound as strings unless the macro declares another type for them. Arguments quoted
+// inside the macro's strings, such as "app-$name", are substituted into the string.
+func (mm *MacroManager) ExecuteMacro(name string, args []string) ([]string, map[string]interface{}, error) {
 	macro, exists := mm.Macros[name]
 	if !exists {
-		return nil, fmt.Errorf("macro '%s' not found", name)
+		return nil, nil, fmt.Errorf("macro '%s' not found", name)
 	}
 
 	if len(args) != len(macro.Args) {
-		return nil, fmt.Errorf("macro '%s' expects %d arguments, got %d", name, len(macro.Args), len(args))
+		return nil, nil, fmt.Errorf("macro '%s' expects %d arguments, got %d", name, len(macro.Args), len(args))
+	}
+
+	params := make(map[string]interface{}, len(args))
+	for i, arg := range macro.Args {
+		value, err := macroArgValue(macro.ArgTypes[arg], args[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("macro '%s' argument %s: %w", name, arg, err)
+		}
+		params[arg] = value
 	}
 
 	statements := make([]string, len(macro.Statements))
 	for i, stmt := range macro.Statements {
-		for j, arg := range macro.Args {
-			stmt = strings.ReplaceAll(stmt, "$"+arg, args[j])
+		stmt = substituteQuotedArgs(stmt, params)
+		if _, err := core.ParseQueryWithParams(strings.TrimSuffix(strings.TrimSpace(stmt), ";"), params); err != nil && strings.Contains(stmt, "$") {
+			return nil, nil, fmt.Errorf("macro '%s' statement %d: %w (arguments are bound as query parameters, which only stand for values and kinds)", name, i+1, err)
 		}
 		statements[i] = stmt
 	}
 
-	return statements, nil
+	return statements, params, nil
+}
+
+// macroArgValue converts an argument given to a macro to the type declared for it
+func macroArgValue(argType, value string) (interface{}, error) {
+	switch argType {
+	case "int":
+		return strconv.Atoi(value)
+	case "float":
+		return strconv.ParseFloat(value, 64)
+	case "bool":
+		return strconv.ParseBool(value)
+	default:
+		return value, nil
+	}
+}
+
+// substituteQuotedArgs substitutes the arguments found inside the double-quoted strings of a
+// statement, where they would otherwise be taken literally, escaping them as string content
+func substituteQuotedArgs(stmt string, params map[string]interface{}) string {
+	var result strings.Builder
+	inString := false
+	for i := 0; i < len(stmt); i++ {
+		ch := stmt[i]
+		switch {
+		case ch == '"':
+			inString = !inString
+		case ch == '\\' && inString && i+1 < len(stmt):
+			result.WriteByte(ch)
+			i++
+			ch = stmt[i]
+		case ch == '$' && inString:
+			end := i + 1
+			for end < len(stmt) && (stmt[end] == '_' || unicode.IsLetter(rune(stmt[end])) || unicode.IsDigit(rune(stmt[end]))) {
+				end++
+			}
+			if value, ok := params[stmt[i+1:end]]; ok {
+				encoded, _ := json.Marshal(fmt.Sprint(value))
+				result.Write(encoded[1 : len(encoded)-1])
+				i = end - 1
+				continue
+			}
+		}
+		result.WriteByte(ch)
+	}
+	return result.String()
+}
+
+// parseMacroArgs parses the arguments of a macro definition, each optionally followed by
+// its type, such as "count:int"
+func parseMacroArgs(parts []string, lineNumber int) ([]string, map[string]string, error) {
+	args := make([]string, len(parts))
+	argTypes := make(map[string]string)
+	for i, part := range parts {
+		name, argType, typed := strings.Cut(part, ":")
+		if typed {
+			if !slices.Contains(macroArgTypes, argType) {
+				return nil, nil, fmt.Errorf("unknown type '%s' for argument '%s' at line %d, expected one of %s", argType, name, lineNumber, strings.Join(macroArgTypes, ", "))
+			}
+			argTypes[name] = argType
+		}
+		args[i] = name
+	}
+	return args, argTypes, nil
 }
 
 func (mm *MacroManager) LoadMacrosFromFile(filename string) error {
@@ -137,7 +222,6 @@ func (mm *MacroManager) LoadMacrosFromFile(filename string) error {
 				return fmt.Errorf("invalid macro definition at line %d: missing macro name", lineNumber)
 			}
 			name := parts[0]
-			args := parts[1:]
 			if name == "" {
 				return fmt.Errorf("macro has no name (line %d)", lineNumber)
 			}
@@ -147,7 +231,12 @@ func (mm *MacroManager) LoadMacrosFromFile(filename string) error {
 				return fmt.Errorf("invalid macro name '%s' at line %d", name, lineNumber)
 			}
 
-			currentMacro = &Macro{Name: name, Args: args}
+			args, argTypes, err := parseMacroArgs(parts[1:], lineNumber)
+			if err != nil {
+				return err
+			}
+
+			currentMacro = &Macro{Name: name, Args: args, ArgTypes: argTypes}
 
 			currentStatement.Reset()
 		} else if currentMacro == nil {
@@ -239,7 +328,12 @@ func (mm *MacroManager) loadMacros(source string, reader io.Reader) error {
 				return fmt.Errorf("invalid macro name '%s' at line %d", name, lineNumber)
 			}
 
-			currentMacro = &Macro{Name: name, Args: args, Description: description}
+			args, argTypes, err := parseMacroArgs(args, lineNumber)
+			if err != nil {
+				return err
+			}
+
+			currentMacro = &Macro{Name: name, Args: args, Description: description, ArgTypes: argTypes}
 		} else if currentMacro == nil {
 			return fmt.Errorf("statement found outside of macro definition at line %d", lineNumber)
 		} else {
@@ -3,8 +3,11 @@ package main
 import (
 	_ "embed"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/avitaltamir/cyphernetes/pkg/core"
 )
 
 //go:embed default_macros.txt
@@ -68,7 +71,7 @@ func TestLoadDefaultMacros(t *testing.T) {
 	// Test executing a few loaded macros
 	macrosToTest := []string{"getpo", "getdeploy", "getsvc"}
 	for _, macroName := range macrosToTest {
-		statements, err := mm.ExecuteMacro(macroName, []string{})
+		statements, _, err := mm.ExecuteMacro(macroName, []string{})
 		if err != nil {
 			t.Fatalf("Failed to execute '%s' macro: %v", macroName, err)
 		}
@@ -86,6 +89,33 @@ func TestLoadDefaultMacros(t *testing.T) {
 	}
 }
 
+func TestDefaultMacrosParse(t *testing.T) {
+	mm := NewMacroManager()
+	if err := mm.loadMacros("default_macros.txt", strings.NewReader(defaultMacrosContent)); err != nil {
+		t.Fatalf("Failed to load default macros: %v", err)
+	}
+
+	for name, macro := range mm.Macros {
+		args := make([]string, len(macro.Args))
+		for i, arg := range macro.Args {
+			args[i] = "web"
+			if macro.ArgTypes[arg] == "int" {
+				args[i] = "3"
+			}
+		}
+
+		statements, params, err := mm.ExecuteMacro(name, args)
+		if err != nil {
+			t.Fatalf("Failed to execute '%s' macro: %v", name, err)
+		}
+		for _, stmt := range statements {
+			if _, err := core.ParseQueryWithParams(strings.TrimSuffix(stmt, ";"), params); err != nil {
+				t.Errorf("Macro '%s' statement %q failed to parse: %v", name, stmt, err)
+			}
+		}
+	}
+}
+
 func TestAddMacroNoOverwrite(t *testing.T) {
 	mm := NewMacroManager()
 	macro1 := &Macro{Name: "test", Args: []string{"arg1"}, Statements: []string{"stmt1"}}
@@ -104,7 +134,7 @@ func TestExecuteMacroIncorrectArgCount(t *testing.T) {
 	macro := &Macro{Name: "test", Args: []string{"arg1"}, Statements: []string{"stmt1"}}
 	mm.AddMacro(macro, false)
 
-	_, err := mm.ExecuteMacro("test", []string{})
+	_, _, err := mm.ExecuteMacro("test", []string{})
 	if err == nil {
 		t.Errorf("Expected error for incorrect argument count, got nil")
 	}
@@ -156,16 +186,94 @@ func TestAddMacroWithOverwrite(t *testing.T) {
 
 func TestExecuteMacroWithArgs(t *testing.T) {
 	mm := NewMacroManager()
-	macro := &Macro{Name: "test", Args: []string{"arg1"}, Statements: []string{"MATCH (n:$arg1) RETURN n"}}
+	macro := &Macro{
+		Name:       "test",
+		Args:       []string{"arg1", "arg2", "arg3"},
+		ArgTypes:   map[string]string{"arg2": "int"},
+		Statements: []string{"MATCH (n:$arg1 {name: $arg3}) SET n.spec.replicas = $arg2"},
+	}
 	mm.AddMacro(macro, false)
 
-	statements, err := mm.ExecuteMacro("test", []string{"Node"})
+	statements, params, err := mm.ExecuteMacro("test", []string{"Node", "3", "true"})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if len(statements) != 1 || statements[0] != "MATCH (n:Node) RETURN n" {
+	if len(statements) != 1 || statements[0] != "MATCH (n:$arg1 {name: $arg3}) SET n.spec.replicas = $arg2" {
 		t.Errorf("Unexpected result: %v", statements)
 	}
+	expectedParams := map[string]interface{}{"arg1": "Node", "arg2": 3, "arg3": "true"}
+	if !reflect.DeepEqual(params, expectedParams) {
+		t.Errorf("Unexpected params: %v", params)
+	}
+}
+
+func TestExecuteMacroQuotedArgs(t *testing.T) {
+	mm := NewMacroManager()
+	macro := &Macro{
+		Name:       "test",
+		Args:       []string{"name", "names"},
+		Statements: []string{`MATCH (d:Deployment {name: "$name"}) WHERE d.metadata.labels.app = "app-$name" AND d.metadata.labels.team = "$names" RETURN d`},
+	}
+	mm.AddMacro(macro, false)
+
+	statements, _, err := mm.ExecuteMacro("test", []string{`web"`, "all"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `MATCH (d:Deployment {name: "web\""}) WHERE d.metadata.labels.app = "app-web\"" AND d.metadata.labels.team = "all" RETURN d`
+	if len(statements) != 1 || statements[0] != expected {
+		t.Errorf("Unexpected result: %v, want %v", statements, expected)
+	}
+}
+
+func TestExecuteMacroArgErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		macro   *Macro
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "mistyped argument",
+			macro:   &Macro{Name: "test", Args: []string{"count"}, ArgTypes: map[string]string{"count": "int"}, Statements: []string{"MATCH (d:Deployment) SET d.spec.replicas = $count"}},
+			args:    []string{"three"},
+			wantErr: "macro 'test' argument count",
+		},
+		{
+			name:    "argument in a non-value position",
+			macro:   &Macro{Name: "test", Args: []string{"n"}, Statements: []string{"MATCH (d:Deployment) RETURN d LIMIT $n"}},
+			args:    []string{"3"},
+			wantErr: "only stand for values and kinds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mm := NewMacroManager()
+			mm.AddMacro(tt.macro, false)
+			_, _, err := mm.ExecuteMacro(tt.macro.Name, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExecuteMacro() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMacrosArgTypes(t *testing.T) {
+	mm := NewMacroManager()
+	err := mm.LoadMacrosFromString("test", ":scale name count:int # Scale a deployment\nMATCH (d:Deployment {name: $name}) SET d.spec.replicas = $count;")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	macro := mm.Macros["scale"]
+	if !reflect.DeepEqual(macro.Args, []string{"name", "count"}) || !reflect.DeepEqual(macro.ArgTypes, map[string]string{"count": "int"}) {
+		t.Errorf("Unexpected arguments: %v %v", macro.Args, macro.ArgTypes)
+	}
+
+	err = mm.LoadMacrosFromString("test", ":scale count:integer\nMATCH (d:Deployment) SET d.spec.replicas = $count;")
+	if err == nil || !strings.Contains(err.Error(), "unknown type 'integer'") {
+		t.Errorf("Expected an unknown type error, got %v", err)
+	}
 }
 
 func TestInvalidMacroName(t *testing.T) {
@@ -211,7 +319,7 @@ stmt2`
 
 func TestExecuteNonExistentMacro(t *testing.T) {
 	mm := NewMacroManager()
-	_, err := mm.ExecuteMacro("non_existent", []string{})
+	_, _, err := mm.ExecuteMacro("non_existent", []string{})
 	if err == nil {
 		t.Errorf("Expected error for non-existent macro, got nil")
 	}
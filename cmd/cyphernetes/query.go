@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/avitaltamir/cyphernetes/pkg/core"
 	"github.com/avitaltamir/cyphernetes/pkg/provider/apiserver"
@@ -12,11 +15,16 @@ import (
 )
 
 var (
-	parseQuery       = core.ParseQuery
+	parseQuery       = core.ParseQueryWithParams
 	newQueryExecutor = core.NewQueryExecutor
-	executeMethod    = (*core.QueryExecutor).Execute
+	executeMethod    = (*core.QueryExecutor).ExecuteWithOptions
+	queryParams      []string
+	returnRows       bool
+	assumeYes        bool
 )
 
+const confirmDeletionPrompt = "Delete these resources? [y/N] "
+
 var queryCmd = &cobra.Command{
 	Use:   "query [Cypher-inspired query]",
 	Short: "Execute a Cypher-inspired query against Kubernetes",
@@ -36,6 +44,9 @@ var queryCmd = &cobra.Command{
 		if err := core.InitResourceSpecs(executor.Provider()); err != nil {
 			fmt.Printf("Error initializing resource specs: %v\n", err)
 		}
+		core.ConfirmDeletion = func(plan *core.DeletionPlan) bool {
+			return confirmDeletion(plan, os.Stdin, os.Stderr, assumeYes)
+		}
 		runQuery(args, os.Stdout)
 	},
 }
@@ -43,7 +54,8 @@ var queryCmd = &cobra.Command{
 func runQuery(args []string, w io.Writer) {
 	// Create the API server provider
 	p, err := apiserver.NewAPIServerProviderWithOptions(&apiserver.APIServerProviderConfig{
-		DryRun: DryRun,
+		DryRun:            DryRun,
+		PropagationPolicy: core.Cascade,
 	})
 	if err != nil {
 		fmt.Fprintln(w, "Error creating provider: ", err)
@@ -57,22 +69,39 @@ func runQuery(args []string, w io.Writer) {
 		return
 	}
 
+	params, err := parseParams(queryParams)
+	if err != nil {
+		fmt.Fprintln(w, "Error parsing parameters: ", err)
+		return
+	}
+
 	// Parse the query to get an AST
-	ast, err := parseQuery(args[0])
+	ast, err := parseQuery(args[0], params)
 	if err != nil {
 		fmt.Fprintln(w, "Error parsing query: ", err)
 		return
 	}
 
 	// Execute the query against the Kubernetes API.
-	results, err := executeMethod(executor, ast, "")
+	results, err := executeMethod(executor, ast, "", core.ExecuteOptions{Rows: returnRows})
 	if err != nil {
 		fmt.Fprintln(w, "Error executing query: ", err)
 		return
 	}
+	for _, warning := range results.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
 
-	// Print the results as pretty JSON.
-	json, err := json.MarshalIndent(results.Data, "", "  ")
+	// Print the results, or their rows, as pretty JSON.
+	var output interface{} = results.Data
+	if returnRows {
+		rows := results.Rows
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		output = rows
+	}
+	json, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		fmt.Fprintln(w, "Error marshalling results: ", err)
 		return
@@ -86,7 +115,58 @@ func runQuery(args []string, w io.Writer) {
 	}
 }
 
+// confirmDeletion prints the plan of a DETACH DELETE clause and asks whether to go ahead with it,
+// unless assumeYes is set
+func confirmDeletion(plan *core.DeletionPlan, r io.Reader, w io.Writer, assumeYes bool) bool {
+	fmt.Fprint(w, plan.String())
+	if assumeYes {
+		return true
+	}
+	fmt.Fprint(w, confirmDeletionPrompt)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	return isConfirmation(answer)
+}
+
+// isConfirmation reports whether an answer to a yes/no question is yes
+func isConfirmation(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// parseParams parses key=value pairs into query parameters
+func parseParams(pairs []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected key=value", pair)
+		}
+		params[key] = parseParamValue(value)
+	}
+	return params, nil
+}
+
+// parseParamValue types a parameter given on the command line: numbers, booleans,
+// null and quoted strings are read as JSON, anything else is taken as a plain string.
+// Digits with leading zeros, such as 007, are strings too, like they are in JSON.
+func parseParamValue(value string) interface{} {
+	if intVal, err := strconv.Atoi(value); err == nil && strconv.Itoa(intVal) == value {
+		return intVal
+	}
+	var typed interface{}
+	if err := json.Unmarshal([]byte(value), &typed); err == nil {
+		switch typed.(type) {
+		case float64, bool, string, nil:
+			return typed
+		}
+	}
+	return value
+}
+
 func init() {
 	rootCmd.AddCommand(queryCmd)
 	queryCmd.PersistentFlags().BoolVarP(&returnRawJsonOutput, "raw-output", "r", false, "Disable JSON output formatting")
+	queryCmd.PersistentFlags().BoolVar(&returnRows, "rows", false, "Print one row per match of the returned variables instead of the results of each variable")
+	queryCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Delete the resources of DETACH DELETE clauses without asking for confirmation")
+	queryCmd.PersistentFlags().StringArrayVar(&queryParams, "param", []string{}, "Bind a query parameter, e.g. --param name=nginx (can be used multiple times)")
 }
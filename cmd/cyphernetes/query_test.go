@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/avitaltamir/cyphernetes/pkg/core"
@@ -37,7 +39,7 @@ func TestRunQuery(t *testing.T) {
 		args            []string
 		setup           func()
 		wantOut         string
-		mockParseQuery  func(string) (*core.Expression, error)
+		mockParseQuery  func(string, map[string]interface{}) (*core.Expression, error)
 		mockExecute     func(*core.Expression, string) (core.QueryResult, error)
 		mockNewExecutor func(provider.Provider) (*core.QueryExecutor, error)
 	}{
@@ -52,7 +54,7 @@ func TestRunQuery(t *testing.T) {
 		{
 			name: "Successful query",
 			args: []string{"MATCH (n:Pod)"},
-			mockParseQuery: func(query string) (*core.Expression, error) {
+			mockParseQuery: func(query string, params map[string]interface{}) (*core.Expression, error) {
 				return &core.Expression{}, nil
 			},
 			mockExecute: func(expr *core.Expression, namespace string) (core.QueryResult, error) {
@@ -65,12 +67,39 @@ func TestRunQuery(t *testing.T) {
 			wantOut: `{
   "test": "data"
 }
+`,
+		},
+		{
+			name: "Successful query with rows",
+			args: []string{"MATCH (d:Deployment)->(rs:ReplicaSet) RETURN d.metadata.name AS deployment, rs.metadata.name AS replicaset"},
+			setup: func() {
+				returnRows = true
+			},
+			mockParseQuery: func(query string, params map[string]interface{}) (*core.Expression, error) {
+				return &core.Expression{}, nil
+			},
+			mockExecute: func(expr *core.Expression, namespace string) (core.QueryResult, error) {
+				return core.QueryResult{
+					Data: map[string]interface{}{
+						"d": []interface{}{map[string]interface{}{"deployment": "web"}},
+					},
+					Rows: []map[string]interface{}{
+						{"deployment": "web", "replicaset": "web-1"},
+					},
+				}, nil
+			},
+			wantOut: `[
+  {
+    "deployment": "web",
+    "replicaset": "web-1"
+  }
+]
 `,
 		},
 		{
 			name: "Parse query error",
 			args: []string{"INVALID QUERY"},
-			mockParseQuery: func(query string) (*core.Expression, error) {
+			mockParseQuery: func(query string, params map[string]interface{}) (*core.Expression, error) {
 				return nil, fmt.Errorf("parse error")
 			},
 			wantOut: "Error parsing query:  parse error\n",
@@ -78,7 +107,7 @@ func TestRunQuery(t *testing.T) {
 		{
 			name: "Execute error",
 			args: []string{"MATCH (n:Pod)"},
-			mockParseQuery: func(query string) (*core.Expression, error) {
+			mockParseQuery: func(query string, params map[string]interface{}) (*core.Expression, error) {
 				return &core.Expression{}, nil
 			},
 			mockExecute: func(expr *core.Expression, namespace string) (core.QueryResult, error) {
@@ -105,7 +134,7 @@ func TestRunQuery(t *testing.T) {
 				newQueryExecutor = func(p provider.Provider) (*core.QueryExecutor, error) {
 					return &core.QueryExecutor{}, nil
 				}
-				executeMethod = func(_ *core.QueryExecutor, expr *core.Expression, namespace string) (core.QueryResult, error) {
+				executeMethod = func(_ *core.QueryExecutor, expr *core.Expression, namespace string, _ core.ExecuteOptions) (core.QueryResult, error) {
 					return tt.mockExecute(expr, namespace)
 				}
 			}
@@ -120,6 +149,90 @@ func TestRunQuery(t *testing.T) {
 			// Reset mocks after test
 			parseQuery = originalParseQuery
 			newQueryExecutor = originalNewQueryExecutor
+			returnRows = false
+		})
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:  "typed values",
+			pairs: []string{"name=nginx", "replicas=3", "ratio=0.5", "enabled=true", "owner=null", `id="42"`, "expr=a=b", "agent=007"},
+			want: map[string]interface{}{
+				"name":     "nginx",
+				"replicas": 3,
+				"ratio":    0.5,
+				"enabled":  true,
+				"owner":    nil,
+				"id":       "42",
+				"expr":     "a=b",
+				"agent":    "007",
+			},
+		},
+		{
+			name:    "missing value",
+			pairs:   []string{"name"},
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			pairs:   []string{"=nginx"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseParams(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmDeletion(t *testing.T) {
+	plan := &core.DeletionPlan{
+		Deleted:   []string{"deployment/web in namespace default"},
+		Collected: []string{"replicaset/web-1 in namespace default"},
+	}
+	tests := []struct {
+		name      string
+		answer    string
+		assumeYes bool
+		want      bool
+	}{
+		{name: "yes", answer: "y\n", want: true},
+		{name: "spelled out", answer: " Yes \n", want: true},
+		{name: "no", answer: "n\n", want: false},
+		{name: "default", answer: "\n", want: false},
+		{name: "no input", answer: "", want: false},
+		{name: "assume yes", answer: "", assumeYes: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := confirmDeletion(plan, strings.NewReader(tt.answer), &out, tt.assumeYes); got != tt.want {
+				t.Errorf("confirmDeletion() = %v, want %v", got, tt.want)
+			}
+			// The plan is printed even when not asking
+			want := plan.String()
+			if !tt.assumeYes {
+				want += confirmDeletionPrompt
+			}
+			if out.String() != want {
+				t.Errorf("confirmDeletion() printed %q, want %q", out.String(), want)
+			}
 		})
 	}
 }
@@ -89,6 +89,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&core.NoColor, "no-color", false, "Disable colored output in shell and query results")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "Show version and exit")
 	rootCmd.PersistentFlags().BoolVar(&DryRun, "dry-run", false, "Enable dry-run mode for all operations")
+	rootCmd.PersistentFlags().StringVar(&core.Cascade, "cascade", "", "Propagation policy of deletions (background, foreground or orphan), the API server's default for each resource when not set")
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
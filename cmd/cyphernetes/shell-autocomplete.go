@@ -106,7 +106,7 @@ func (c *CyphernetesCompleter) Do(line []rune, pos int) ([][]rune, int) {
 				}
 			}
 		} else {
-			keywords := []string{"match", "where", "return", "set", "delete", "create", "as", "sum", "count", "in", "contains"}
+			keywords := []string{"match", "optional", "with", "where", "return", "set", "detach", "delete", "create", "merge", "on", "remove", "as", "sum", "count", "avg", "min", "max", "collect", "in", "contains", "starts", "ends", "is", "exists", "any", "and", "or", "not", "order", "by", "asc", "desc", "skip", "limit", "union", "all", "distinct", "unwind"}
 			for _, k := range keywords {
 				if strings.HasPrefix(k, prefix) {
 					suggestion := k[len(prefix):]
@@ -132,8 +132,8 @@ func (m *MockMacroManager) AddMacro(macro *Macro, overwrite bool) {
 	m.Macros[macro.Name] = macro
 }
 
-func (m *MockMacroManager) ExecuteMacro(name string, args []string) ([]string, error) {
-	return nil, nil
+func (m *MockMacroManager) ExecuteMacro(name string, args []string) ([]string, map[string]interface{}, error) {
+	return nil, nil, nil
 }
 
 func (m *MockMacroManager) LoadMacrosFromFile(filename string) error {
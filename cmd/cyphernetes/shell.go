@@ -36,7 +36,8 @@ var ShellCmd = &cobra.Command{
 
 		// Create provider with dry-run config
 		provider, err := apiserver.NewAPIServerProviderWithOptions(&apiserver.APIServerProviderConfig{
-			DryRun: DryRun,
+			DryRun:            DryRun,
+			PropagationPolicy: core.Cascade,
 		})
 		if err != nil {
 			fmt.Printf("Error creating provider: %v\n", err)
@@ -130,7 +131,7 @@ func getCurrentContextFromConfig() (string, string, error) {
 type syntaxHighlighter struct{}
 
 var (
-	keywordsRegex   = regexp.MustCompile(`(?i)\b(match|where|contains|set|delete|create|sum|count|as|in)\b`)
+	keywordsRegex   = regexp.MustCompile(`(?i)\b(match|optional|with|where|contains|starts|ends|is|exists|any|set|delete|create|merge|on|remove|sum|count|avg|min|max|collect|as|in|and|or|not|order|by|asc|desc|skip|limit|union|all|distinct|unwind)\b`)
 	bracketsRegex   = regexp.MustCompile(`[\(\)\[\]\{\}\<\>]`)
 	variableRegex   = regexp.MustCompile(`"(.*?)"`)
 	identifierRegex = regexp.MustCompile(`0m(\w+):([.\w]+)`)
@@ -287,6 +288,15 @@ func initAndRunShell(_ *cobra.Command, _ []string) {
 		return line, pos, false
 	})
 
+	// DETACH DELETE clauses ask before deleting anything
+	core.ConfirmDeletion = func(plan *core.DeletionPlan) bool {
+		fmt.Print(plan.String())
+		rl.SetPrompt(confirmDeletionPrompt)
+		defer rl.SetPrompt(shellPrompt())
+		answer, err := rl.Readline()
+		return err == nil && isConfirmation(answer)
+	}
+
 	// Set up a channel to receive interrupt signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -499,7 +509,7 @@ func processQuery(query string) (string, core.Graph, error) {
 		macroName = parts[0]
 		args := parts[1:]
 
-		statements, err := macroManager.ExecuteMacro(macroName, args)
+		statements, params, err := macroManager.ExecuteMacro(macroName, args)
 		if err != nil {
 			return "", core.Graph{}, err
 		}
@@ -507,7 +517,7 @@ func processQuery(query string) (string, core.Graph, error) {
 		var results []string
 		var graphInternal core.Graph
 		for i, stmt := range statements {
-			result, err := executeStatementFunc(stmt)
+			result, err := executeStatementFunc(stmt, params)
 			if err != nil {
 				return "", core.Graph{}, fmt.Errorf("error executing statement %d: %w", i+1, err)
 			}
@@ -532,23 +542,36 @@ func processQuery(query string) (string, core.Graph, error) {
 
 		result = strings.Join(results, "\n")
 	} else {
-		res, err := executeStatement(query)
+		result, graph, err = processStatement(query, nil)
 		if err != nil {
 			return "", core.Graph{}, err
 		}
-		var resultMap map[string]interface{}
-		err = json.Unmarshal([]byte(res), &resultMap)
-		if err != nil {
-			return "", core.Graph{}, fmt.Errorf("error unmarshalling result: %w", err)
-		}
-
-		buildDataAndGraph(resultMap, &result, &graph)
 	}
 
 	execTime = time.Since(startTime)
 	return result, graph, err
 }
 
+// processStatement executes a single statement and returns its data and graph
+func processStatement(query string, params map[string]interface{}) (string, core.Graph, error) {
+	var result string
+	var graph core.Graph
+
+	query = strings.TrimSuffix(query, ";")
+	res, err := executeStatement(query, params)
+	if err != nil {
+		return "", core.Graph{}, err
+	}
+	var resultMap map[string]interface{}
+	err = json.Unmarshal([]byte(res), &resultMap)
+	if err != nil {
+		return "", core.Graph{}, fmt.Errorf("error unmarshalling result: %w", err)
+	}
+
+	buildDataAndGraph(resultMap, &result, &graph)
+	return result, graph, nil
+}
+
 func buildDataAndGraph(resultMap map[string]interface{}, result *string, graph *core.Graph) error {
 	// check if interface is nil
 	if graphInternal, ok := resultMap["Graph"]; ok {
@@ -589,8 +612,8 @@ func buildDataAndGraph(resultMap map[string]interface{}, result *string, graph *
 	return nil
 }
 
-func executeStatement(query string) (string, error) {
-	ast, err := core.ParseQuery(query)
+func executeStatement(query string, params map[string]interface{}) (string, error) {
+	ast, err := core.ParseQueryWithParams(query, params)
 	if err != nil {
 		return "", fmt.Errorf("error parsing query >> %s", err)
 	}
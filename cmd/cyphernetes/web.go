@@ -32,7 +32,8 @@ func runWeb(cmd *cobra.Command, args []string) {
 
 	// Create the API server provider
 	providerConfig := &apiserver.APIServerProviderConfig{
-		DryRun: DryRun,
+		DryRun:            DryRun,
+		PropagationPolicy: core.Cascade,
 	}
 	provider, err := apiserver.NewAPIServerProviderWithOptions(providerConfig)
 	if err != nil {
@@ -534,6 +534,9 @@ func (r *DynamicOperatorReconciler) executeStatement(statement string, objMap ma
 			return fmt.Errorf("error executing statement: %v", err)
 		}
 	}
+	for _, warning := range result.Warnings {
+		log.Log.Info("Statement returned a warning", "statement", sanitizedStatement, "warning", warning)
+	}
 
 	// Check if we need to add owner references to created resources
 	if createClause := findCreateClause(ast); createClause != nil {
@@ -347,7 +347,7 @@ MATCH (d:Deployment {name: "child-of-{{$.metadata.name}}"})
 CREATE (d)->(s:Service);
 `,
 					OnDelete: `
-MATCH (d:Deployment {name: "child-of-{{$.metadata.name}}"})->(s:Service)
+MATCH (d:Deployment {name: "child-of-{{$.metadata.name}}"})<-(s:Service)
 DELETE d, s;
 `,
 				},
@@ -533,10 +533,10 @@ DELETE d, s;
 					ResourceKind: "deployments",
 					Namespace:    "default",
 					OnUpdate: `
-						MATCH (d:Deployment {name: "{{$.metadata.name}}"})->(s:Service)->(i:Ingress)
+						MATCH (d:Deployment {name: "{{$.metadata.name}}"})<-(s:Service)<-(i:Ingress)
 						WHERE d.spec.replicas = 0
 						SET i.spec.ingressClassName = "inactive";
-						MATCH (d:Deployment {name: "{{$.metadata.name}}"})->(s:Service)->(i:Ingress)
+						MATCH (d:Deployment {name: "{{$.metadata.name}}"})<-(s:Service)<-(i:Ingress)
 						WHERE d.spec.replicas > 0
 						SET i.spec.ingressClassName = "active";
 					`,
@@ -551,7 +551,7 @@ DELETE d, s;
 				if err == nil {
 					executor, err := core.NewQueryExecutor(p)
 					if err == nil {
-						ast, _ := core.ParseQuery(`MATCH (d:Deployment)->(s:Service)->(i:Ingress) RETURN d,s,i`)
+						ast, _ := core.ParseQuery(`MATCH (d:Deployment)<-(s:Service)<-(i:Ingress) RETURN d,s,i`)
 						result, err := executor.Execute(ast, "default")
 						if err == nil {
 							fmt.Printf("Current relationships: %+v\n", result.Graph)
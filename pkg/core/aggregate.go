@@ -0,0 +1,437 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/AvitalTamir/jsonpath"
+)
+
+// aggregateResources computes the aggregation of a return item's path over the given resources.
+// Distinct aggregations are computed over the distinct values found at the path. Paths with a
+// wildcard yield a list per resource, its elements are aggregated as values of their own.
+func aggregateResources(aggregate string, distinct bool, pathStr string, resources []map[string]interface{}) (interface{}, error) {
+	var aggregateResult interface{}
+	var aggregateInputs []interface{}
+
+	kind := pathQuantityKind(pathStr)
+	isCPUResource, isMemoryResource := kind == cpuQuantity, kind == memoryQuantity
+	isWildcard := strings.Contains(pathStr, "[*]")
+
+	if distinct {
+		for _, resource := range resources {
+			result, err := jsonpath.JsonPathLookup(resource, pathStr)
+			if err != nil {
+				result = nil
+			}
+			aggregateInputs = appendAggregateInput(aggregateInputs, result, isWildcard)
+		}
+		return aggregateValues(aggregate, distinctValues(aggregateInputs), isCPUResource, isMemoryResource)
+	}
+
+	for _, resource := range resources {
+		result, err := jsonpath.JsonPathLookup(resource, pathStr)
+		if err != nil {
+			result = nil
+		}
+
+		switch aggregate {
+		case "SUM":
+			if result != nil {
+				if aggregateResult == nil {
+					aggregateResult = reflect.ValueOf(result).Interface()
+				} else {
+					v1 := reflect.ValueOf(aggregateResult)
+					v2 := reflect.ValueOf(result)
+					v1 = reflect.ValueOf(v1.Interface()).Convert(v1.Type())
+					if v1.Kind() == reflect.Ptr {
+						v1 = v1.Elem()
+					}
+					if v2.Kind() == reflect.Ptr {
+						v2 = v2.Elem()
+					}
+
+					switch v1.Kind() {
+					case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+						aggregateResult = v1.Int() + v2.Int()
+					case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+						aggregateResult = v1.Uint() + v2.Uint()
+					case reflect.Float32, reflect.Float64:
+						aggregateResult = v1.Float() + v2.Float()
+					case reflect.String:
+						if isCPUResource {
+							v1Cpu, err := convertToMilliCPU(v1.String())
+							if err != nil {
+								return nil, fmt.Errorf("error processing cpu resources value: %v", err)
+							}
+							v2Cpu, err := convertToMilliCPU(v2.String())
+							if err != nil {
+								return nil, fmt.Errorf("error processing cpu resources value: %v", err)
+							}
+
+							aggregateResult = convertMilliCPUToStandard(v1Cpu + v2Cpu)
+						} else if isMemoryResource {
+							v1Mem, err := convertMemoryToBytes(v1.String())
+							if err != nil {
+								return nil, fmt.Errorf("error processing memory resources value: %v", err)
+							}
+							v2Mem, err := convertMemoryToBytes(v2.String())
+							if err != nil {
+								return nil, fmt.Errorf("error processing memory resources value: %v", err)
+							}
+
+							aggregateResult = convertBytesToMemory(v1Mem + v2Mem)
+						}
+					case reflect.Slice:
+						v1Strs, err := convertToStringSlice(v1)
+						if err != nil {
+							return nil, fmt.Errorf("error converting v1 to string slice: %v", err)
+						}
+
+						v2Strs, err := convertToStringSlice(v2)
+						if err != nil {
+							return nil, fmt.Errorf("error converting v2 to string slice: %v", err)
+						}
+
+						if isCPUResource {
+							v1CpuSum, err := sumMilliCPU(v1Strs)
+							if err != nil {
+								return nil, fmt.Errorf("error processing v1 cpu value: %v", err)
+							}
+
+							v2CpuSum, err := sumMilliCPU(v2Strs)
+							if err != nil {
+								return nil, fmt.Errorf("error processing v2 cpu value: %v", err)
+							}
+
+							aggregateResult = []string{convertMilliCPUToStandard(v1CpuSum + v2CpuSum)}
+						} else if isMemoryResource {
+							v1MemSum, err := sumMemoryBytes(v1Strs)
+							if err != nil {
+								return nil, fmt.Errorf("error processing v1 memory value: %v", err)
+							}
+
+							v2MemSum, err := sumMemoryBytes(v2Strs)
+							if err != nil {
+								return nil, fmt.Errorf("error processing v2 memory value: %v", err)
+							}
+
+							aggregateResult = []string{convertBytesToMemory(v1MemSum + v2MemSum)}
+						}
+					default:
+						// Handle unsupported types or error out
+						return nil, fmt.Errorf("unsupported type for SUM: %v", v1.Kind())
+					}
+				}
+			}
+		case "COUNT", "AVG", "MIN", "MAX", "COLLECT":
+			aggregateInputs = appendAggregateInput(aggregateInputs, result, isWildcard)
+		}
+	}
+
+	switch aggregate {
+	case "COUNT", "AVG", "MIN", "MAX", "COLLECT":
+		return aggregateValues(aggregate, aggregateInputs, isCPUResource, isMemoryResource)
+	}
+
+	if slice, ok := aggregateResult.([]interface{}); ok && len(slice) == 0 {
+		aggregateResult = nil
+	} else if strSlice, ok := aggregateResult.([]string); ok && len(strSlice) == 1 {
+		aggregateResult = strSlice[0]
+	}
+	return aggregateResult, nil
+}
+
+// aggregateKey returns the key under which an aggregated return item is reported
+func aggregateKey(item *ReturnItem) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+
+	nodeId := strings.Split(item.JsonPath, ".")[0]
+	pathStr := toJsonPath(item.JsonPath)
+	key := strings.ToLower(item.Aggregate) + ":"
+	if item.Distinct {
+		key += "distinct "
+	}
+	return key + nodeId + "." + strings.Replace(pathStr, "$.", "", 1)
+}
+
+// returnItemKey returns the key under which a non-aggregated return item is reported in grouped
+// and computed results: its alias, its function call or its path
+func returnItemKey(item *ReturnItem) string {
+	switch {
+	case item.Alias != "":
+		return item.Alias
+	case item.Function != nil:
+		return item.Function.String()
+	default:
+		return item.JsonPath
+	}
+}
+
+// toJsonPath converts a return path such as "p.metadata.name" into the JSONPath
+// used to look it up in the node's resources ("$.metadata.name")
+func toJsonPath(path string) string {
+	pathParts := strings.Split(path, ".")[1:]
+	pathStr := "$." + strings.Join(pathParts, ".")
+	if pathStr == "$." {
+		pathStr = "$"
+	}
+	return pathStr
+}
+
+// getGroupItems returns the non-aggregated items of a return clause that mixes plain
+// paths with aggregations. Following Cypher, these items become the grouping keys.
+func getGroupItems(c *ReturnClause) []*ReturnItem {
+	var groupItems, aggregateItems []*ReturnItem
+	for _, item := range c.Items {
+		if item.Aggregate == "" {
+			groupItems = append(groupItems, item)
+		} else {
+			aggregateItems = append(aggregateItems, item)
+		}
+	}
+	if len(groupItems) == 0 || len(aggregateItems) == 0 {
+		return nil
+	}
+	return groupItems
+}
+
+// applyGrouping groups the rows of the variables the items of a return clause refer to by the
+// values of the group items, and reports one row per group, holding the grouping values and the
+// aggregations computed over the resources bound to the aggregated variables in the group's rows.
+// Like aggregations that aren't grouped, they're computed over each resource once.
+func (q *QueryExecutor) applyGrouping(c *ReturnClause, groupItems []*ReturnItem, nodes []*NodePattern, relationships []*Relationship, paths []*PathPattern, unwinds []*UnwindClause, results *QueryResult) error {
+	type group struct {
+		values   []interface{}
+		bindings []rowBinding
+	}
+
+	var variables []string
+	for _, item := range c.Items {
+		if name := strings.Split(item.JsonPath, ".")[0]; !slices.Contains(variables, name) {
+			variables = append(variables, name)
+		}
+	}
+	bindings, err := q.rowBindings(variables, nodes, relationships, paths, unwinds)
+	if err != nil {
+		return err
+	}
+
+	var groups []*group
+	groupsByKey := make(map[string]*group)
+	for _, binding := range bindings {
+		values := make([]interface{}, len(groupItems))
+		for i, item := range groupItems {
+			value, err := rowValue(binding, item.JsonPath, item.Function)
+			if err != nil {
+				return err
+			}
+			values[i] = value
+		}
+
+		keyBytes, err := json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("error grouping by %v: %v", values, err)
+		}
+		key := string(keyBytes)
+
+		if groupsByKey[key] == nil {
+			groupsByKey[key] = &group{values: values}
+			groups = append(groups, groupsByKey[key])
+		}
+		groupsByKey[key].bindings = append(groupsByKey[key].bindings, binding)
+	}
+
+	rows := []interface{}{}
+	for _, g := range groups {
+		row := make(map[string]interface{})
+		for i, item := range groupItems {
+			row[returnItemKey(item)] = g.values[i]
+		}
+
+		for _, item := range c.Items {
+			if item.Aggregate == "" {
+				continue
+			}
+			name := strings.Split(item.JsonPath, ".")[0]
+			var resources []map[string]interface{}
+			seen := make(map[string]bool)
+			for _, binding := range g.bindings {
+				resource := binding[name]
+				if resource == nil || seen[bindingKey(resource)] {
+					continue
+				}
+				seen[bindingKey(resource)] = true
+				resources = append(resources, resource)
+			}
+			aggregateResult, err := aggregateResources(item.Aggregate, item.Distinct, toJsonPath(item.JsonPath), resources)
+			if err != nil {
+				return err
+			}
+			row[aggregateKey(item)] = aggregateResult
+		}
+		rows = append(rows, row)
+	}
+
+	results.Data["aggregate"] = rows
+	return nil
+}
+
+// aggregateValues computes an AVG, MIN, MAX or COLLECT aggregation over the values
+// gathered for a return item, or any aggregation over the distinct values of a DISTINCT
+// one. Values of CPU and memory resource paths are compared, summed and averaged as
+// quantities, so "500m" is less than "1" and "512Mi" is less than "1Gi".
+func aggregateValues(aggregate string, values []interface{}, isCPUResource, isMemoryResource bool) (interface{}, error) {
+	switch aggregate {
+	case "COLLECT":
+		collected := []interface{}{}
+		for _, value := range values {
+			if value != nil {
+				collected = append(collected, value)
+			}
+		}
+		return collected, nil
+	case "COUNT":
+		count := 0
+		for _, value := range values {
+			if value != nil {
+				count++
+			}
+		}
+		return count, nil
+	}
+
+	// Paths with a wildcard return a list per resource, aggregate over their elements
+	var flattened []interface{}
+	for _, value := range values {
+		if list, ok := value.([]interface{}); ok {
+			for _, element := range list {
+				if element != nil {
+					flattened = append(flattened, element)
+				}
+			}
+		} else if value != nil {
+			flattened = append(flattened, value)
+		}
+	}
+	if len(flattened) == 0 {
+		return nil, nil
+	}
+
+	var kind quantityKind
+	if isCPUResource {
+		kind = cpuQuantity
+	} else if isMemoryResource {
+		kind = memoryQuantity
+	}
+	isQuantity := kind != 0
+
+	switch aggregate {
+	case "MIN", "MAX":
+		selected := flattened[0]
+		for _, value := range flattened[1:] {
+			var cmp int
+			if isQuantity {
+				a, err := quantityValue(value, kind)
+				if err != nil {
+					return nil, fmt.Errorf("error processing %s value: %v", aggregate, err)
+				}
+				b, err := quantityValue(selected, kind)
+				if err != nil {
+					return nil, fmt.Errorf("error processing %s value: %v", aggregate, err)
+				}
+				cmp = compareFloats(a, b)
+			} else {
+				cmp = compareOrderValues(value, selected)
+			}
+
+			if (aggregate == "MIN" && cmp < 0) || (aggregate == "MAX" && cmp > 0) {
+				selected = value
+			}
+		}
+		return selected, nil
+
+	case "SUM":
+		if isQuantity {
+			var sum float64
+			for _, value := range flattened {
+				number, err := quantityValue(value, kind)
+				if err != nil {
+					return nil, fmt.Errorf("error processing SUM value: %v", err)
+				}
+				sum += number
+			}
+			if isCPUResource {
+				return convertMilliCPUToStandard(int(math.Round(sum))), nil
+			}
+			return convertBytesToMemory(int64(math.Round(sum))), nil
+		}
+
+		var intSum int64
+		var floatSum float64
+		isInt := true
+		for _, value := range flattened {
+			if i, ok := toInt64(value); ok {
+				intSum += i
+				continue
+			}
+			number, err := toFloat64(value)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported value for SUM: %v", value)
+			}
+			floatSum += number
+			isInt = false
+		}
+		if isInt {
+			return intSum, nil
+		}
+		return floatSum + float64(intSum), nil
+
+	case "AVG":
+		var sum float64
+		for _, value := range flattened {
+			var number float64
+			var err error
+			if isQuantity {
+				number, err = quantityValue(value, kind)
+			} else {
+				number, err = toFloat64(value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unsupported value for AVG: %v", value)
+			}
+			sum += number
+		}
+		avg := sum / float64(len(flattened))
+
+		if isCPUResource {
+			return convertMilliCPUToStandard(int(math.Round(avg))), nil
+		} else if isMemoryResource {
+			return convertBytesToMemory(int64(math.Round(avg))), nil
+		}
+		return avg, nil
+	}
+
+	return nil, fmt.Errorf("unsupported aggregate function: %s", aggregate)
+}
+
+// appendAggregateInput appends the value found at an aggregated path in a resource to the values
+// to aggregate, or the elements of the list found at a path with a wildcard
+func appendAggregateInput(inputs []interface{}, value interface{}, isWildcard bool) []interface{} {
+	if list, ok := value.([]interface{}); ok && isWildcard {
+		return append(inputs, list...)
+	}
+	return append(inputs, value)
+}
+
+// distinctValues returns the distinct non-nil values
+func distinctValues(values []interface{}) []interface{} {
+	values = slices.DeleteFunc(slices.Clone(values), func(value interface{}) bool { return value == nil })
+	return distinctRows(values)
+}
@@ -0,0 +1,336 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggregateValues(t *testing.T) {
+	tests := []struct {
+		name             string
+		aggregate        string
+		values           []interface{}
+		isCPUResource    bool
+		isMemoryResource bool
+		expected         interface{}
+		wantErr          bool
+	}{
+		{
+			name:      "avg of numbers",
+			aggregate: "AVG",
+			values:    []interface{}{int64(1), int64(2), nil, float64(6)},
+			expected:  float64(3),
+		},
+		{
+			name:          "avg of cpu",
+			aggregate:     "AVG",
+			values:        []interface{}{[]interface{}{"500m", "1"}, []interface{}{"1.5"}},
+			isCPUResource: true,
+			expected:      "1",
+		},
+		{
+			name:             "avg of memory",
+			aggregate:        "AVG",
+			values:           []interface{}{"1Gi", "512Mi"},
+			isMemoryResource: true,
+			expected:         "768Mi",
+		},
+		{
+			name:      "avg of strings",
+			aggregate: "AVG",
+			values:    []interface{}{"nginx"},
+			wantErr:   true,
+		},
+		{
+			name:          "min of cpu",
+			aggregate:     "MIN",
+			values:        []interface{}{[]interface{}{"2", "250m"}, []interface{}{"1"}},
+			isCPUResource: true,
+			expected:      "250m",
+		},
+		{
+			name:             "max of memory",
+			aggregate:        "MAX",
+			values:           []interface{}{[]interface{}{"512Mi", "1G"}, []interface{}{"900Mi"}},
+			isMemoryResource: true,
+			expected:         "1G",
+		},
+		{
+			name:      "max of timestamps",
+			aggregate: "MAX",
+			values:    []interface{}{"2024-05-01T10:00:00Z", "2024-06-01T10:00:00Z", "2024-04-01T10:00:00Z"},
+			expected:  "2024-06-01T10:00:00Z",
+		},
+		{
+			name:      "min of nothing",
+			aggregate: "MIN",
+			values:    []interface{}{nil},
+			expected:  nil,
+		},
+		{
+			name:      "collect",
+			aggregate: "COLLECT",
+			values:    []interface{}{"nginx", nil, []interface{}{"a", "b"}},
+			expected:  []interface{}{"nginx", []interface{}{"a", "b"}},
+		},
+		{
+			name:      "count of distinct values",
+			aggregate: "COUNT",
+			values:    distinctValues([]interface{}{[]interface{}{"nginx", "envoy"}, []interface{}{"nginx"}, nil}),
+			expected:  2,
+		},
+		{
+			name:          "sum of distinct cpu",
+			aggregate:     "SUM",
+			values:        distinctValues([]interface{}{"500m", "1", "500m"}),
+			isCPUResource: true,
+			expected:      "1.5",
+		},
+		{
+			name:      "sum of numbers",
+			aggregate: "SUM",
+			values:    []interface{}{int64(2), 3},
+			expected:  int64(5),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := aggregateValues(tt.aggregate, tt.values, tt.isCPUResource, tt.isMemoryResource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("aggregateValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("aggregateValues() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAggregateResourcesCount(t *testing.T) {
+	resources := []map[string]interface{}{
+		mockResource("Pod", "web-1", onNode("node-a")),
+		mockResource("Pod", "web-2", onNode("node-a")),
+		mockResource("Pod", "pending", nil),
+	}
+	containers := []map[string]interface{}{
+		mockResource("Pod", "web", map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"image": "nginx:1.25"},
+			map[string]interface{}{"image": "envoy:1.30"},
+			map[string]interface{}{"image": "nginx:1.25"},
+		}}}),
+	}
+
+	tests := []struct {
+		name      string
+		distinct  bool
+		path      string
+		resources []map[string]interface{}
+		expected  interface{}
+	}{
+		{name: "resources", path: "$", resources: resources, expected: 3},
+		{name: "values skip missing ones", path: "$.spec.nodeName", resources: resources, expected: 2},
+		{name: "distinct values skip missing ones", distinct: true, path: "$.spec.nodeName", resources: resources, expected: 1},
+		{name: "no resources", path: "$.spec.nodeName", expected: 0},
+		{name: "wildcard values", path: "$.spec.containers[*].image", resources: containers, expected: 3},
+		{name: "distinct wildcard values", distinct: true, path: "$.spec.containers[*].image", resources: containers, expected: 2},
+		{name: "lists without a wildcard", path: "$.spec.containers", resources: containers, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := aggregateResources("COUNT", tt.distinct, tt.path, tt.resources)
+			if err != nil {
+				t.Fatalf("aggregateResources() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("aggregateResources() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetGroupItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []*ReturnItem
+		expected int
+	}{
+		{
+			name:     "no aggregations",
+			items:    []*ReturnItem{{JsonPath: "p.metadata.name"}},
+			expected: 0,
+		},
+		{
+			name:     "only aggregations",
+			items:    []*ReturnItem{{JsonPath: "p", Aggregate: "COUNT"}},
+			expected: 0,
+		},
+		{
+			name: "mixed",
+			items: []*ReturnItem{
+				{JsonPath: "p.spec.nodeName"},
+				{JsonPath: "p.metadata.namespace", Alias: "ns"},
+				{JsonPath: "p", Aggregate: "COUNT"},
+			},
+			expected: 2,
+		},
+		{
+			name: "aggregation of another node",
+			items: []*ReturnItem{
+				{JsonPath: "d.metadata.name"},
+				{JsonPath: "p", Aggregate: "COUNT"},
+			},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getGroupItems(&ReturnClause{Items: tt.items})
+			if len(got) != tt.expected {
+				t.Errorf("getGroupItems() returned %d items, want %d", len(got), tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteGrouping(t *testing.T) {
+	pod := func(name, node string, cpus ...string) map[string]interface{} {
+		var containers []interface{}
+		for i, cpu := range cpus {
+			containers = append(containers, map[string]interface{}{
+				"name":      fmt.Sprintf("%s-%d", name, i),
+				"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": cpu}},
+			})
+		}
+		return mockResource("Pod", name, labeled(map[string]interface{}{"app": name[:3]}), ownedBy(name[:3]), onNode(node), map[string]interface{}{
+			"spec": map[string]interface{}{"containers": containers},
+		})
+	}
+	resources := map[string][]map[string]interface{}{
+		"pods": {pod("web-a", "node-a", "500m"), pod("api-a", "node-b", "1", "250m"), pod("web-b", "node-a", "750m")},
+		"replicasets": {
+			mockResource("ReplicaSet", "web", nil),
+			mockResource("ReplicaSet", "api", nil),
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []interface{}
+	}{
+		{
+			name: "grouped by a path of the aggregated node",
+			query: `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COUNT{p} AS pods,
+				MAX{p.spec.containers[*].resources.requests.cpu}`,
+			want: []interface{}{
+				map[string]interface{}{"node": "node-a", "pods": 2, "max:p.spec.containers[*].resources.requests.cpu": "750m"},
+				map[string]interface{}{"node": "node-b", "pods": 1, "max:p.spec.containers[*].resources.requests.cpu": "1"},
+			},
+		},
+		{
+			name:  "grouped by a related node",
+			query: `MATCH (rs:ReplicaSet)->(p:Pod) RETURN rs.metadata.name AS replicaset, COLLECT{p.metadata.name} AS pods`,
+			want: []interface{}{
+				map[string]interface{}{"replicaset": "web", "pods": []interface{}{"web-a", "web-b"}},
+				map[string]interface{}{"replicaset": "api", "pods": []interface{}{"api-a"}},
+			},
+		},
+		{
+			name:  "grouped by the node unwound elements come from",
+			query: `MATCH (p:Pod) UNWIND p.spec.containers AS c RETURN p.metadata.name, COUNT{c.name}`,
+			want: []interface{}{
+				map[string]interface{}{"p.metadata.name": "web-a", "count:c.name": 1},
+				map[string]interface{}{"p.metadata.name": "api-a", "count:c.name": 2},
+				map[string]interface{}{"p.metadata.name": "web-b", "count:c.name": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data["aggregate"], tt.want) {
+				t.Errorf("got %v, want %v", result.Data["aggregate"], tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteGroupedOrdering(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"pods": {
+			mockResource("Pod", "a", onNode("node-b")),
+			mockResource("Pod", "b", onNode("node-a")),
+			mockResource("Pod", "c", onNode("node-b")),
+			mockResource("Pod", "d", onNode("node-c")),
+			mockResource("Pod", "e", onNode("node-b")),
+			mockResource("Pod", "f", onNode("node-a")),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:  "by aggregation alias",
+			query: `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COUNT{p.metadata.name} AS n ORDER BY n DESC LIMIT 1`,
+			want: []interface{}{
+				map[string]interface{}{"node": "node-b", "n": 3},
+			},
+		},
+		{
+			name:  "by grouping key path",
+			query: `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COUNT{p.metadata.name} AS n ORDER BY p.spec.nodeName`,
+			want: []interface{}{
+				map[string]interface{}{"node": "node-a", "n": 2},
+				map[string]interface{}{"node": "node-b", "n": 3},
+				map[string]interface{}{"node": "node-c", "n": 1},
+			},
+		},
+		{
+			name:  "by grouping key alias, skipped",
+			query: `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COUNT{p.metadata.name} AS n ORDER BY node DESC SKIP 1`,
+			want: []interface{}{
+				map[string]interface{}{"node": "node-b", "n": 3},
+				map[string]interface{}{"node": "node-a", "n": 2},
+			},
+		},
+		{
+			name:    "by a path that isn't a grouping key",
+			query:   `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COUNT{p.metadata.name} AS n ORDER BY p.metadata.name`,
+			wantErr: "can't order grouped results by p.metadata.name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("executeMockQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data["aggregate"], tt.want) {
+				t.Errorf("got %v, want %v", result.Data["aggregate"], tt.want)
+			}
+			if len(result.Rows) != len(tt.want) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConfirmDeletion, when set, is shown the plan of a DETACH DELETE clause before anything is
+// deleted. Nothing is deleted unless it returns true.
+var ConfirmDeletion func(plan *DeletionPlan) bool
+
+// ErrDeletionCancelled is returned when the plan of a DETACH DELETE clause isn't confirmed
+var ErrDeletionCancelled = errors.New("DETACH DELETE cancelled")
+
+// DeletionPlan describes the resources a DETACH DELETE clause deletes, by kind, name and namespace
+type DeletionPlan struct {
+	// Deleted are deleted explicitly: the matched resources followed by their dependents
+	Deleted []string
+	// Collected are deleted by the garbage collector along with their owners
+	Collected []string
+}
+
+// ownedKinds maps the ownership relationships to the kind of the owned resources, which the
+// garbage collector deletes along with their owner
+var ownedKinds = map[RelationshipType]string{
+	DeploymentOwnReplicaset: "replicasets",
+	ReplicasetOwnPod:        "pods",
+	StatefulsetOwnPod:       "pods",
+	DaemonsetOwnPod:         "pods",
+	JobOwnPod:               "pods",
+	CronJobOwnJob:           "jobs",
+	CronJobOwnPod:           "pods",
+}
+
+// dependentKinds maps the relationships DETACH DELETE cascades along to the kind of the dependent
+// resources. Dependents aren't owned by the resources they depend on, so the garbage collector
+// leaves them behind.
+var dependentKinds = map[RelationshipType]string{
+	ServiceExposeDeployment:  "services",
+	ServiceExposeStatefulset: "services",
+	ServiceExposeDaemonset:   "services",
+	ServiceExposeReplicaset:  "services",
+	ServiceExposePod:         "services",
+	HPAScaleDeployment:       "horizontalpodautoscalers",
+	PDBProtectPod:            "poddisruptionbudgets",
+	Route:                    "ingresses",
+}
+
+// detachPlan lists the resources a DETACH DELETE clause deletes
+type detachPlan struct {
+	// deleted are deleted explicitly: the matched resources followed by their dependents
+	deleted []map[string]interface{}
+	// collected are deleted by the garbage collector along with their owners
+	collected []map[string]interface{}
+
+	kinds    map[string][]map[string]interface{}
+	included map[string]bool
+}
+
+// detachDelete deletes the resources of the given node variables along with their dependents.
+// The plan of everything that goes is added to the results, and confirmed first with confirm,
+// or ConfirmDeletion when confirm is nil, if either is set.
+func (q *QueryExecutor) detachDelete(nodeIds []string, confirm func(plan *DeletionPlan) bool, results *QueryResult) error {
+	plan, err := q.planDetachDelete(nodeIds)
+	if err != nil {
+		return err
+	}
+	deletions := plan.describe()
+	if results.Deletions == nil {
+		results.Deletions = &DeletionPlan{}
+	}
+	results.Deletions.Deleted = append(results.Deletions.Deleted, deletions.Deleted...)
+	results.Deletions.Collected = append(results.Deletions.Collected, deletions.Collected...)
+	if confirm == nil {
+		confirm = ConfirmDeletion
+	}
+	if confirm != nil && !confirm(deletions) {
+		return ErrDeletionCancelled
+	}
+
+	for _, resource := range plan.deleted {
+		kind := resource["kind"].(string)
+		metadata := resource["metadata"].(map[string]interface{})
+		name := metadata["name"].(string)
+
+		err := q.provider.DeleteK8sResources(kind, name, getNamespaceName(metadata))
+		if err != nil {
+			return fmt.Errorf("error deleting resource %s/%s: %v", kind, name, err)
+		}
+	}
+
+	for _, nodeId := range nodeIds {
+		delete(resultMap, nodeId)
+	}
+	return nil
+}
+
+// planDetachDelete walks the relationship rules from the resources of the given node variables.
+// Owned resources are followed unless they're orphaned, so that their dependents are found too.
+// A dependent is only deleted when all the resources it depends on are deleted.
+func (q *QueryExecutor) planDetachDelete(nodeIds []string) (*detachPlan, error) {
+	plan := &detachPlan{
+		kinds:    make(map[string][]map[string]interface{}),
+		included: make(map[string]bool),
+	}
+
+	for _, nodeId := range nodeIds {
+		if resultMap[nodeId] == nil {
+			return nil, fmt.Errorf("node identifier %s not found in result map", nodeId)
+		}
+		for _, resource := range resultMap[nodeId].([]map[string]interface{}) {
+			gvr, err := q.findGVR(resource["kind"].(string))
+			if err != nil {
+				return nil, fmt.Errorf("error finding API resource >> %s", err)
+			}
+			plan.add(gvr.Resource, resource, false)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range relationshipRules {
+			for _, kinds := range [][2]string{{rule.KindA, rule.KindB}, {rule.KindB, rule.KindA}} {
+				kind, otherKind := strings.ToLower(kinds[0]), strings.ToLower(kinds[1])
+				owned := ownedKinds[rule.Relationship] == otherKind && !strings.EqualFold(Cascade, "orphan")
+				dependent := dependentKinds[rule.Relationship] == otherKind
+				if len(plan.kinds[kind]) == 0 || (!owned && !dependent) {
+					continue
+				}
+
+				candidates, err := q.getHopResources(otherKind)
+				if err != nil {
+					return nil, err
+				}
+				for _, candidate := range candidates {
+					if plan.included[planKey(otherKind, candidate)] {
+						continue
+					}
+					related, _ := relateByRule(rule, kind, plan.kinds[kind], []map[string]interface{}{candidate})
+					if len(related) == 0 {
+						continue
+					}
+
+					if dependent {
+						// Dependents of resources that aren't deleted are kept
+						all, err := q.getHopResources(kind)
+						if err != nil {
+							return nil, err
+						}
+						dependencies, _ := relateByRule(rule, kind, all, []map[string]interface{}{candidate})
+						if !plan.includesAll(kind, dependencies) {
+							continue
+						}
+					}
+
+					plan.add(otherKind, candidate, owned)
+					changed = true
+				}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// relateByRule returns the resources of kind and the candidates that are related to each other by a rule
+func relateByRule(rule RelationshipRule, kind string, resources, candidates []map[string]interface{}) ([]map[string]interface{}, []map[string]interface{}) {
+	if strings.EqualFold(rule.KindA, kind) {
+		matched := applyRelationshipRule(resources, candidates, rule, Right)
+		return matched["left"].([]map[string]interface{}), matched["right"].([]map[string]interface{})
+	}
+	matched := applyRelationshipRule(candidates, resources, rule, Right)
+	return matched["right"].([]map[string]interface{}), matched["left"].([]map[string]interface{})
+}
+
+func (p *detachPlan) add(kind string, resource map[string]interface{}, collected bool) {
+	key := planKey(kind, resource)
+	if p.included[key] {
+		return
+	}
+	p.included[key] = true
+	p.kinds[kind] = append(p.kinds[kind], resource)
+	if collected {
+		p.collected = append(p.collected, resource)
+	} else {
+		p.deleted = append(p.deleted, resource)
+	}
+}
+
+func (p *detachPlan) includesAll(kind string, resources []map[string]interface{}) bool {
+	for _, resource := range resources {
+		if !p.included[planKey(kind, resource)] {
+			return false
+		}
+	}
+	return true
+}
+
+// describe returns the description of a plan
+func (p *detachPlan) describe() *DeletionPlan {
+	deletions := &DeletionPlan{Deleted: []string{}, Collected: []string{}}
+	for _, resource := range p.deleted {
+		deletions.Deleted = append(deletions.Deleted, describeResource(resource))
+	}
+	for _, resource := range p.collected {
+		deletions.Collected = append(deletions.Collected, describeResource(resource))
+	}
+	return deletions
+}
+
+// String returns the preview of a plan
+func (p *DeletionPlan) String() string {
+	var preview strings.Builder
+	preview.WriteString("DETACH DELETE will delete:\n")
+	for _, resource := range p.Deleted {
+		preview.WriteString("  " + resource + "\n")
+	}
+	if len(p.Collected) > 0 {
+		preview.WriteString("The garbage collector will delete the resources they own:\n")
+		for _, resource := range p.Collected {
+			preview.WriteString("  " + resource + "\n")
+		}
+	}
+	return preview.String()
+}
+
+func planKey(kind string, resource map[string]interface{}) string {
+	return kind + "/" + resourceKey(resource)
+}
+
+// describeResource returns a resource's kind and name, along with its namespace if it has one
+func describeResource(resource map[string]interface{}) string {
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	description := fmt.Sprintf("%s/%v", strings.ToLower(fmt.Sprintf("%v", resource["kind"])), metadata["name"])
+	if namespace := getNamespaceName(metadata); namespace != "" && resource["kind"] != "Namespace" {
+		description += " in namespace " + namespace
+	}
+	return description
+}
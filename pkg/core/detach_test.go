@@ -0,0 +1,205 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestExecuteDetachDelete(t *testing.T) {
+	newResources := func() map[string][]map[string]interface{} {
+		return map[string][]map[string]interface{}{
+			"deployments": {
+				mockResource("Deployment", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+				mockResource("Deployment", "api", matchingLabels(map[string]interface{}{"app": "api"})),
+			},
+			"replicasets": {
+				mockResource("ReplicaSet", "web-1", ownedBy("web")),
+				mockResource("ReplicaSet", "api-1", ownedBy("api")),
+			},
+			"pods": {
+				mockResource("Pod", "web-1-a", ownedBy("web-1"), labeled(map[string]interface{}{"app": "web", "tier": "frontend"})),
+				mockResource("Pod", "api-1-a", ownedBy("api-1"), labeled(map[string]interface{}{"app": "api", "tier": "frontend"})),
+			},
+			"services": {
+				mockResource("Service", "web", selecting(map[string]interface{}{"app": "web"})),
+				mockResource("Service", "frontend", selecting(map[string]interface{}{"tier": "frontend"})),
+			},
+			"horizontalpodautoscalers": {
+				mockResource("HorizontalPodAutoscaler", "web", map[string]interface{}{
+					"spec": map[string]interface{}{"scaleTargetRef": map[string]interface{}{"name": "web"}},
+				}),
+			},
+			"poddisruptionbudgets": {
+				mockResource("PodDisruptionBudget", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+			},
+			"ingresses": {
+				mockResource("Ingress", "web", routingTo("web")),
+				mockResource("Ingress", "site", routingTo("web", "frontend")),
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		cascade     string
+		query       string
+		wantDeleted []string
+	}{
+		{
+			name:    "dependents are deleted along with the matched resources",
+			cascade: "background",
+			query:   `MATCH (d:Deployment {name: "web"}) DETACH DELETE d`,
+			wantDeleted: []string{
+				"Deployment/web",
+				"HorizontalPodAutoscaler/web",
+				"Ingress/web",
+				"PodDisruptionBudget/web",
+				"Service/web",
+			},
+		},
+		{
+			name:    "dependents of orphaned resources are kept",
+			cascade: "orphan",
+			query:   `MATCH (d:Deployment {name: "web"}) DETACH DELETE d`,
+			wantDeleted: []string{
+				"Deployment/web",
+				"HorizontalPodAutoscaler/web",
+				"Ingress/web",
+				"Service/web",
+			},
+		},
+		{
+			name:        "plain delete",
+			cascade:     "background",
+			query:       `MATCH (d:Deployment {name: "web"}) DELETE d`,
+			wantDeleted: []string{"Deployment/web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Cascade = tt.cascade
+			defer func() { Cascade = "" }()
+
+			provider := &mockProvider{resources: newResources()}
+			ast, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			executor, err := NewQueryExecutor(provider)
+			if err != nil {
+				t.Fatalf("NewQueryExecutor() error = %v", err)
+			}
+			if _, err := executor.Execute(ast, "default"); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if provider.deleted[0] != "Deployment/web" {
+				t.Errorf("matched resources should be deleted first, got %v", provider.deleted)
+			}
+			slices.Sort(provider.deleted)
+			if !reflect.DeepEqual(provider.deleted, tt.wantDeleted) {
+				t.Errorf("deleted %v, want %v", provider.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestDetachDeleteConfirmation(t *testing.T) {
+	newResources := func() map[string][]map[string]interface{} {
+		return map[string][]map[string]interface{}{
+			"deployments": {
+				mockResource("Deployment", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+			},
+			"replicasets": {
+				mockResource("ReplicaSet", "web-1", ownedBy("web")),
+			},
+			"services": {
+				mockResource("Service", "web", selecting(map[string]interface{}{"app": "web"})),
+			},
+		}
+	}
+	wantPlan := &DeletionPlan{
+		Deleted:   []string{"deployment/web in namespace default", "service/web in namespace default"},
+		Collected: []string{"replicaset/web-1 in namespace default"},
+	}
+
+	tests := []struct {
+		name         string
+		confirm      bool
+		perExecution bool
+		wantErr      error
+		wantDeleted  []string
+	}{
+		{
+			name:        "confirmed",
+			confirm:     true,
+			wantDeleted: []string{"Deployment/web", "Service/web"},
+		},
+		{
+			name:    "cancelled",
+			confirm: false,
+			wantErr: ErrDeletionCancelled,
+		},
+		{
+			name:         "confirmed per execution",
+			confirm:      true,
+			perExecution: true,
+			wantDeleted:  []string{"Deployment/web", "Service/web"},
+		},
+		{
+			name:         "cancelled per execution",
+			confirm:      false,
+			perExecution: true,
+			wantErr:      ErrDeletionCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shown *DeletionPlan
+			confirm := func(plan *DeletionPlan) bool {
+				shown = plan
+				return tt.confirm
+			}
+			var opts ExecuteOptions
+			ConfirmDeletion = confirm
+			if tt.perExecution {
+				// The confirmation of an execution takes the place of the package's
+				ConfirmDeletion = func(plan *DeletionPlan) bool {
+					t.Errorf("ConfirmDeletion called for an execution confirming its own deletions")
+					return true
+				}
+				opts.ConfirmDeletion = confirm
+			}
+			defer func() { ConfirmDeletion = nil }()
+
+			provider := &mockProvider{resources: newResources()}
+			ast, err := ParseQuery(`MATCH (d:Deployment {name: "web"}) DETACH DELETE d`)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			executor, err := NewQueryExecutor(provider)
+			if err != nil {
+				t.Fatalf("NewQueryExecutor() error = %v", err)
+			}
+			result, err := executor.ExecuteWithOptions(ast, "default", opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(shown, wantPlan) {
+				t.Errorf("confirmed plan %+v, want %+v", shown, wantPlan)
+			}
+			if !reflect.DeepEqual(result.Deletions, wantPlan) {
+				t.Errorf("Deletions = %+v, want %+v", result.Deletions, wantPlan)
+			}
+			slices.Sort(provider.deleted)
+			if !reflect.DeepEqual(provider.deleted, tt.wantDeleted) {
+				t.Errorf("deleted %v, want %v", provider.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
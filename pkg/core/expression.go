@@ -0,0 +1,276 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// evaluateValue evaluates the value of a SET item for a resource of the node it sets.
+// Literal values are returned as they are.
+func (q *QueryExecutor) evaluateValue(value interface{}, nodeId string, resource map[string]interface{}, relationships []*Relationship) (interface{}, error) {
+	switch v := value.(type) {
+	case *FieldReference:
+		return q.resolveFieldReference(v, nodeId, resource, relationships)
+	case *ArithmeticExpression:
+		left, err := q.evaluateValue(v.Left, nodeId, resource, relationships)
+		if err != nil {
+			return nil, err
+		}
+		right, err := q.evaluateValue(v.Right, nodeId, resource, relationships)
+		if err != nil {
+			return nil, err
+		}
+		return applyArithmetic(v.Operator, left, right, arithmeticQuantityKind(v))
+	case *FunctionCall:
+		args := make([]interface{}, len(v.Args))
+		for i, arg := range v.Args {
+			value, err := q.evaluateValue(arg, nodeId, resource, relationships)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		return callFunction(v.Name, args)
+	default:
+		return value, nil
+	}
+}
+
+// resolveFieldReference returns the value of a referenced field. A reference to the node being
+// set reads the resource being patched, a reference to another node reads its resource that
+// relates to it.
+func (q *QueryExecutor) resolveFieldReference(ref *FieldReference, nodeId string, resource map[string]interface{}, relationships []*Relationship) (interface{}, error) {
+	refNodeId, path := splitKeyPath(ref.JsonPath)
+
+	source := resource
+	if refNodeId != nodeId {
+		var err error
+		source, err = q.referencedResource(refNodeId, nodeId, resource, relationships)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, ok := lookupField(source, path)
+	if !ok {
+		return nil, fmt.Errorf("field %s not found in %s", ref.JsonPath, describeResource(source))
+	}
+	return value, nil
+}
+
+// referencedResource returns the resource of another node a SET value refers to. The node must
+// either have a single resource, or be related to the node being set so that a single one of its
+// resources relates to the resource being patched.
+func (q *QueryExecutor) referencedResource(refNodeId, nodeId string, resource map[string]interface{}, relationships []*Relationship) (map[string]interface{}, error) {
+	if resultMap[refNodeId] == nil {
+		return nil, fmt.Errorf("node identifier %s not found in result map", refNodeId)
+	}
+	resources := resultMap[refNodeId].([]map[string]interface{})
+	if len(resources) == 1 {
+		return resources[0], nil
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("node %s has no resources to reference", refNodeId)
+	}
+
+	var rel *Relationship
+	for _, r := range relationships {
+		left, right := r.LeftNode.ResourceProperties.Name, r.RightNode.ResourceProperties.Name
+		if (left == nodeId && right == refNodeId) || (left == refNodeId && right == nodeId) {
+			rel = r
+			break
+		}
+	}
+	if rel == nil {
+		return nil, fmt.Errorf("node %s has %d resources and isn't related to %s", refNodeId, len(resources), nodeId)
+	}
+
+	kind, err := q.findGVR(resource["kind"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+	refKind, err := q.findGVR(resources[0]["kind"].(string))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+
+	_, related, err := q.relateResources([]map[string]interface{}{resource}, resources, kind.Resource, refKind.Resource, rel, nodeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(related) != 1 {
+		return nil, fmt.Errorf("%d resources of %s are related to %s, expected one", len(related), refNodeId, describeResource(resource))
+	}
+	return related[0], nil
+}
+
+// lookupField returns the value at the given path of a resource, and reports whether it exists
+func lookupField(resource map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = resource
+	for _, part := range path {
+		part, indices := splitIndices(part)
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = node[part]; !ok {
+			return nil, false
+		}
+		for _, index := range indices {
+			items, ok := current.([]interface{})
+			i, err := strconv.Atoi(index)
+			if !ok || err != nil || i < 0 || i >= len(items) {
+				return nil, false
+			}
+			current = items[i]
+		}
+	}
+	return current, true
+}
+
+// arithmeticQuantityKind returns the kind of quantity held by the CPU or memory fields an
+// arithmetic expression refers to, so that the numeric strings read from them, such as "1" core,
+// are computed as quantities
+func arithmeticQuantityKind(value interface{}) quantityKind {
+	switch v := value.(type) {
+	case *FieldReference:
+		return pathQuantityKind(v.JsonPath)
+	case *ArithmeticExpression:
+		if kind := arithmeticQuantityKind(v.Left); kind != 0 {
+			return kind
+		}
+		return arithmeticQuantityKind(v.Right)
+	}
+	return 0
+}
+
+// applyArithmetic applies an arithmetic operator to two values. Numbers are computed as such,
+// CPU and memory quantities are computed in their base unit and formatted back, and adding
+// strings concatenates them. Numeric strings are quantities of the given kind, if any.
+func applyArithmetic(operator string, left, right interface{}, kind quantityKind) (interface{}, error) {
+	_, leftIsQuantity := parseQuantity(left, kind)
+	_, rightIsQuantity := parseQuantity(right, kind)
+	if leftIsQuantity || rightIsQuantity {
+		return applyQuantityArithmetic(operator, left, right, kind)
+	}
+
+	leftNum, leftIsNumber := left.(float64)
+	rightNum, rightIsNumber := right.(float64)
+	leftInt, leftIsInt := toInt64(left)
+	rightInt, rightIsInt := toInt64(right)
+	if leftIsInt {
+		leftNum, leftIsNumber = float64(leftInt), true
+	}
+	if rightIsInt {
+		rightNum, rightIsNumber = float64(rightInt), true
+	}
+
+	if leftIsNumber && rightIsNumber {
+		if operator == "/" && rightNum == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if leftIsInt && rightIsInt {
+			switch operator {
+			case "+":
+				return leftInt + rightInt, nil
+			case "-":
+				return leftInt - rightInt, nil
+			case "*":
+				return leftInt * rightInt, nil
+			case "/":
+				if leftInt%rightInt == 0 {
+					return leftInt / rightInt, nil
+				}
+			}
+		}
+		return applyOperator(operator, leftNum, rightNum), nil
+	}
+
+	_, leftIsString := left.(string)
+	_, rightIsString := right.(string)
+	if operator == "+" && (leftIsString || rightIsString) && left != nil && right != nil {
+		return fmt.Sprintf("%v%v", left, right), nil
+	}
+
+	return nil, fmt.Errorf("cannot apply %s to %v and %v", operator, left, right)
+}
+
+// applyQuantityArithmetic applies an arithmetic operator to a CPU or memory quantity and another
+// quantity of the same kind or a number. Quantities can be added to and subtracted from each
+// other, where plain numbers stand for cores or bytes, and divided by each other into a ratio.
+// They can be multiplied and divided by numbers.
+func applyQuantityArithmetic(operator string, left, right interface{}, kind quantityKind) (interface{}, error) {
+	leftQuantity, leftIsQuantity := parseQuantity(left, kind)
+	rightQuantity, rightIsQuantity := parseQuantity(right, kind)
+
+	if leftIsQuantity && rightIsQuantity {
+		if leftQuantity.kind != rightQuantity.kind {
+			return nil, fmt.Errorf("cannot apply %s to CPU and memory quantities %v and %v", operator, left, right)
+		}
+		switch operator {
+		case "+", "-":
+			return formatQuantity(leftQuantity.kind, applyOperator(operator, leftQuantity.value, rightQuantity.value)), nil
+		case "/":
+			if rightQuantity.value == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return leftQuantity.value / rightQuantity.value, nil
+		default:
+			return nil, fmt.Errorf("cannot multiply quantities %v and %v", left, right)
+		}
+	}
+
+	// One side is a quantity, the other must be a number
+	qty, number := leftQuantity, right
+	if rightIsQuantity {
+		qty, number = rightQuantity, left
+	}
+	n, err := toFloat64(number)
+	if err != nil {
+		return nil, fmt.Errorf("cannot apply %s to quantity and %v", operator, number)
+	}
+
+	switch operator {
+	case "+", "-":
+		n = plainQuantity(qty.kind, n)
+		if rightIsQuantity {
+			return formatQuantity(qty.kind, applyOperator(operator, n, qty.value)), nil
+		}
+		return formatQuantity(qty.kind, applyOperator(operator, qty.value, n)), nil
+	case "*":
+		return formatQuantity(qty.kind, qty.value*n), nil
+	default:
+		if rightIsQuantity {
+			return nil, fmt.Errorf("cannot divide %v by quantity %v", left, right)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return formatQuantity(qty.kind, qty.value/n), nil
+	}
+}
+
+func applyOperator(operator string, left, right float64) float64 {
+	switch operator {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	default:
+		return left / right
+	}
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
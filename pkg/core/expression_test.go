@@ -0,0 +1,156 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecuteSetExpressions(t *testing.T) {
+	// SET updates the resources in place, each test starts from fresh ones
+	limits := func(cpu, memory string) map[string]interface{} {
+		return map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "app", "resources": map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": cpu, "memory": memory},
+			}},
+		}}}
+	}
+	newResources := func() map[string][]map[string]interface{} {
+		return map[string][]map[string]interface{}{
+			"namespaces": {
+				mockResource("Namespace", "payments", labeled(map[string]interface{}{"team": "billing"})),
+				mockResource("Namespace", "search", labeled(map[string]interface{}{"team": "discovery"})),
+			},
+			"deployments": {
+				mockResource("Deployment", "checkout", map[string]interface{}{
+					"metadata": map[string]interface{}{"namespace": "payments"},
+					"spec":     map[string]interface{}{"replicas": 2, "template": limits("1", "536870912")},
+				}),
+				mockResource("Deployment", "indexer", map[string]interface{}{
+					"metadata": map[string]interface{}{"namespace": "search"},
+					"spec":     map[string]interface{}{"replicas": 3, "template": limits("0.5", "256Mi")},
+				}),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		path    []string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:  "reference to the current node",
+			query: "MATCH (d:Deployment) SET d.spec.replicas = d.spec.replicas * 2 + 1 RETURN d.spec.replicas",
+			path:  []string{"spec", "replicas"},
+			want:  map[string]interface{}{"checkout": int64(5), "indexer": int64(7)},
+		},
+		{
+			name:  "reference to a related node",
+			query: `MATCH (ns:Namespace)->(d:Deployment) SET d.metadata.labels.team = ns.metadata.labels.team + "-" + d.metadata.name RETURN d.metadata.labels.team`,
+			path:  []string{"metadata", "labels", "team"},
+			want:  map[string]interface{}{"checkout": "billing-checkout", "indexer": "discovery-indexer"},
+		},
+		{
+			name:  "function call",
+			query: `MATCH (ns:Namespace)->(d:Deployment) SET d.metadata.labels.team = toUpper(ns.metadata.labels.team) RETURN d.metadata.labels.team`,
+			path:  []string{"metadata", "labels", "team"},
+			want:  map[string]interface{}{"checkout": "BILLING", "indexer": "DISCOVERY"},
+		},
+		{
+			name:  "whole cores",
+			query: "MATCH (d:Deployment) SET d.spec.template.spec.containers[0].resources.limits.cpu = d.spec.template.spec.containers[0].resources.limits.cpu * 2 RETURN d.spec.template",
+			path:  []string{"spec", "template", "spec", "containers[0]", "resources", "limits", "cpu"},
+			want:  map[string]interface{}{"checkout": "2", "indexer": "1"},
+		},
+		{
+			name:  "plain bytes",
+			query: "MATCH (d:Deployment) SET d.spec.template.spec.containers[0].resources.limits.memory = d.spec.template.spec.containers[0].resources.limits.memory + 1048576 RETURN d.spec.template",
+			path:  []string{"spec", "template", "spec", "containers[0]", "resources", "limits", "memory"},
+			want:  map[string]interface{}{"checkout": "513Mi", "indexer": "257Mi"},
+		},
+		{
+			name:    "reference to a missing field",
+			query:   "MATCH (d:Deployment) SET d.spec.replicas = d.spec.minReplicas + 1 RETURN d.spec.replicas",
+			wantErr: "field d.spec.minReplicas not found",
+		},
+		{
+			name:    "reference to an unrelated node with several resources",
+			query:   "MATCH (ns:Namespace), (d:Deployment) SET d.metadata.labels.team = ns.metadata.labels.team RETURN d.metadata.labels",
+			wantErr: "node ns has 2 resources and isn't related to d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, newResources())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			got := make(map[string]interface{})
+			for _, resource := range result.Data["d"].([]interface{}) {
+				resource := resource.(map[string]interface{})
+				got[resource["name"].(string)], _ = lookupField(resource, tt.path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyArithmetic(t *testing.T) {
+	tests := []struct {
+		operator string
+		left     interface{}
+		right    interface{}
+		kind     quantityKind
+		want     interface{}
+		wantErr  bool
+	}{
+		{operator: "+", left: 2, right: int64(3), want: int64(5)},
+		{operator: "/", left: 10, right: 4, want: 2.5},
+		{operator: "/", left: 10, right: 5, want: int64(2)},
+		{operator: "*", left: 1.5, right: 2, want: 3.0},
+		{operator: "/", left: 1, right: 0, wantErr: true},
+		{operator: "+", left: "web", right: "-1", want: "web-1"},
+		{operator: "+", left: "web", right: 1, want: "web1"},
+		{operator: "-", left: "web", right: 1, wantErr: true},
+		{operator: "+", left: "500m", right: "750m", want: "1.25"},
+		{operator: "+", left: "500m", right: "1", want: "1.5"},
+		{operator: "*", left: "250m", right: 2, want: "500m"},
+		{operator: "/", left: "250m", right: "1000m", want: 0.25},
+		{operator: "*", left: "128Mi", right: 2, want: "256Mi"},
+		{operator: "+", left: "1Gi", right: "512Mi", want: "1536Mi"},
+		{operator: "-", left: "1G", right: "500M", want: "500M"},
+		{operator: "/", left: "1Gi", right: 4, want: "256Mi"},
+		{operator: "+", left: "1Gi", right: "500m", wantErr: true},
+		{operator: "*", left: "1Gi", right: "1Gi", wantErr: true},
+		{operator: "/", left: 2, right: "1Gi", wantErr: true},
+		{operator: "*", left: "1", right: 2, kind: cpuQuantity, want: "2"},
+		{operator: "*", left: "0.5", right: 3, kind: cpuQuantity, want: "1.5"},
+		{operator: "-", left: "1", right: "250m", kind: cpuQuantity, want: "750m"},
+		{operator: "*", left: "1048576", right: 2, kind: memoryQuantity, want: "2Mi"},
+		{operator: "*", left: "1", right: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := applyArithmetic(tt.operator, tt.left, tt.right, tt.kind)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("applyArithmetic(%q, %v, %v) error = %v, wantErr %v", tt.operator, tt.left, tt.right, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) && !tt.wantErr {
+			t.Errorf("applyArithmetic(%q, %v, %v) = %v (%T), want %v (%T)", tt.operator, tt.left, tt.right, got, got, tt.want, tt.want)
+		}
+	}
+}
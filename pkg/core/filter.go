@@ -0,0 +1,311 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AvitalTamir/jsonpath"
+)
+
+// getFilterNodeNames returns the unique node names referenced by a filter and its operands
+func getFilterNodeNames(filter *Filter) []string {
+	var nodeNames []string
+	if filter.Type == KeyValuePairFilter {
+		return []string{getFilterNodeName(filter.KeyValuePair.Key)}
+	}
+	if filter.Type == AnyFilter {
+		// The operand of ANY references the list's elements rather than nodes
+		return []string{getFilterNodeName(filter.ListPath)}
+	}
+	if filter.Type == PatternFilter {
+		for _, node := range filter.Pattern.Nodes {
+			if node.ResourceProperties.Name != "" && !slices.Contains(nodeNames, node.ResourceProperties.Name) {
+				nodeNames = append(nodeNames, node.ResourceProperties.Name)
+			}
+		}
+		return nodeNames
+	}
+	for _, operand := range filter.Operands {
+		for _, name := range getFilterNodeNames(operand) {
+			if !slices.Contains(nodeNames, name) {
+				nodeNames = append(nodeNames, name)
+			}
+		}
+	}
+	return nodeNames
+}
+
+// condition is the value of a WHERE expression under three-valued logic. A condition on a missing
+// value, or comparing values that have no order, is null rather than false, and so is its negation.
+type condition int
+
+const (
+	conditionFalse condition = iota
+	conditionTrue
+	conditionNull
+)
+
+// conditionOf returns the condition holding a boolean value
+func conditionOf(holds bool) condition {
+	if holds {
+		return conditionTrue
+	}
+	return conditionFalse
+}
+
+// evaluateFilter reports whether a WHERE expression tree holds for a single resource, i.e. whether
+// it's true rather than false or null.
+// patternMatches holds the keys of the resources satisfying each pattern predicate.
+func evaluateFilter(resource map[string]interface{}, filter *Filter, nodeName string, patternMatches map[*Filter]map[string]bool) bool {
+	return evaluateCondition(resource, filter, nodeName, patternMatches) == conditionTrue
+}
+
+// evaluateCondition evaluates a WHERE expression tree against a single resource. AND is false if
+// any operand is false, OR is true if any operand is true, and both are null otherwise when an
+// operand is null.
+func evaluateCondition(resource map[string]interface{}, filter *Filter, nodeName string, patternMatches map[*Filter]map[string]bool) condition {
+	switch filter.Type {
+	case AndFilter:
+		result := conditionTrue
+		for _, operand := range filter.Operands {
+			switch evaluateCondition(resource, operand, nodeName, patternMatches) {
+			case conditionFalse:
+				return conditionFalse
+			case conditionNull:
+				result = conditionNull
+			}
+		}
+		return result
+	case OrFilter:
+		result := conditionFalse
+		for _, operand := range filter.Operands {
+			switch evaluateCondition(resource, operand, nodeName, patternMatches) {
+			case conditionTrue:
+				return conditionTrue
+			case conditionNull:
+				result = conditionNull
+			}
+		}
+		return result
+	case NotFilter:
+		switch evaluateCondition(resource, filter.Operands[0], nodeName, patternMatches) {
+		case conditionTrue:
+			return conditionFalse
+		case conditionFalse:
+			return conditionTrue
+		default:
+			return conditionNull
+		}
+	case PatternFilter:
+		return conditionOf(patternMatches[filter][resourceKey(resource)])
+	case AnyFilter:
+		list, err := jsonpath.JsonPathLookup(resource, filterPath(filter.ListPath, nodeName))
+		if err != nil {
+			return conditionNull
+		}
+		items, ok := list.([]interface{})
+		if !ok {
+			return conditionNull
+		}
+		result := conditionFalse
+		for _, item := range items {
+			// The element is looked up by its variable name, like values that aren't bound to a node
+			switch evaluateCondition(map[string]interface{}{filter.Variable: item}, filter.Operands[0], "", patternMatches) {
+			case conditionTrue:
+				return conditionTrue
+			case conditionNull:
+				result = conditionNull
+			}
+		}
+		return result
+	default:
+		return matchKeyValuePair(resource, filter.KeyValuePair, nodeName)
+	}
+}
+
+// filterPath converts the key of a condition on a node to a JSONPath on the node's resources
+func filterPath(key, nodeName string) string {
+	if nodeName == "" {
+		// Values that aren't bound to a node, such as WITH aliases, are looked up by name
+		return "$." + key
+	}
+	return strings.Replace(key, nodeName+".", "$.", 1)
+}
+
+// matchKeyValuePair compares the value found at the filter's JSONPath with the filter value.
+// A condition on a path with a wildcard such as p.spec.containers[*].image holds if it holds
+// for any of the values found. Missing values, and function calls that fail, are null.
+func matchKeyValuePair(resource map[string]interface{}, filter *KeyValuePair, nodeName string) condition {
+	path := filterPath(filter.Key, nodeName)
+
+	// Get value using jsonpath, or by calling the function
+	var value interface{}
+	var err error
+	if filter.Function != nil {
+		value, err = evaluateFunctionCall(filter.Function, nodeName, resource)
+		if err != nil {
+			return conditionNull
+		}
+	} else {
+		value, err = jsonpath.JsonPathLookup(resource, path)
+		if err != nil {
+			value = nil
+		}
+	}
+
+	// Function calls such as ago("24h") are compared by their result
+	compared := filter.Value
+	if call, ok := filter.Value.(*FunctionCall); ok {
+		compared, err = evaluateFunctionCall(call, nodeName, resource)
+		if err != nil {
+			return conditionNull
+		}
+	}
+
+	if wildcards := strings.Count(filter.Key, "[*]"); filter.Function == nil && wildcards > 0 && value != nil {
+		result := conditionFalse
+		for _, item := range flattenWildcardValues(value, wildcards) {
+			switch matchValue(item, compared, filter.Operator) {
+			case conditionTrue:
+				return conditionTrue
+			case conditionNull:
+				result = conditionNull
+			}
+		}
+		return result
+	}
+
+	return matchValue(value, compared, filter.Operator)
+}
+
+// flattenWildcardValues flattens the values found at a path with wildcards, which are nested
+// in a list for each wildcard
+func flattenWildcardValues(value interface{}, wildcards int) []interface{} {
+	items, _ := value.([]interface{})
+	if wildcards == 1 {
+		return items
+	}
+	var flattened []interface{}
+	for _, item := range items {
+		flattened = append(flattened, flattenWildcardValues(item, wildcards-1)...)
+	}
+	return flattened
+}
+
+// matchValue matches a value with the value of a condition using the condition's operator.
+// Conditions on null values are null, except for IS NULL and IS NOT NULL.
+func matchValue(value, compared interface{}, operator string) condition {
+	switch operator {
+	case "IS_NULL":
+		return conditionOf(value == nil)
+	case "IS_NOT_NULL":
+		return conditionOf(value != nil)
+	}
+	if value == nil {
+		return conditionNull
+	}
+
+	switch operator {
+	case "IN":
+		items, ok := compared.([]interface{})
+		if !ok {
+			return conditionNull
+		}
+		result := conditionFalse
+		for _, item := range items {
+			switch compareValues(value, item, "EQUALS") {
+			case conditionTrue:
+				return conditionTrue
+			case conditionNull:
+				result = conditionNull
+			}
+		}
+		return result
+	case "STARTS_WITH", "ENDS_WITH":
+		str, isString := value.(string)
+		affix, affixIsString := compared.(string)
+		if !isString || !affixIsString {
+			return conditionNull
+		}
+		if operator == "STARTS_WITH" {
+			return conditionOf(strings.HasPrefix(str, affix))
+		}
+		return conditionOf(strings.HasSuffix(str, affix))
+	}
+
+	return compareValues(value, compared, operator)
+}
+
+// compareValues compares a resource value with a filter value using a comparison operator.
+// Numbers, timestamps and quantities are ordered by value and strings lexicographically, values
+// of other types, or of different types, have no order and comparing their order is null.
+func compareValues(value, compared interface{}, operator string) condition {
+	if value == nil || compared == nil {
+		return conditionNull
+	}
+
+	// Convert and compare values
+	resourceValue, filterValue, err := convertToComparableTypes(value, compared)
+	if err != nil {
+		return conditionNull
+	}
+
+	// Compare based on operator
+	switch operator {
+	case "EQUALS", "=", "==":
+		return conditionOf(resourceValue == filterValue)
+	case "NOT_EQUALS", "!=":
+		return conditionOf(resourceValue != filterValue)
+	case "GREATER_THAN", ">", "LESS_THAN", "<", "GREATER_THAN_EQUALS", ">=", "LESS_THAN_EQUALS", "<=":
+		order, ok := orderValues(value, compared, resourceValue, filterValue)
+		if !ok {
+			return conditionNull
+		}
+		switch operator {
+		case "GREATER_THAN", ">":
+			return conditionOf(order > 0)
+		case "LESS_THAN", "<":
+			return conditionOf(order < 0)
+		case "GREATER_THAN_EQUALS", ">=":
+			return conditionOf(order >= 0)
+		default:
+			return conditionOf(order <= 0)
+		}
+	case "CONTAINS":
+		strA := fmt.Sprintf("%v", resourceValue)
+		strB := fmt.Sprintf("%v", filterValue)
+		return conditionOf(strings.Contains(strA, strB))
+	case "REGEX_COMPARE":
+		filterValueStr, ok := filterValue.(string)
+		resultValueStr, isString := resourceValue.(string)
+		if !ok || !isString {
+			return conditionNull
+		}
+		regex, err := regexp.Compile(filterValueStr)
+		if err != nil {
+			return conditionNull
+		}
+		return conditionOf(regex.MatchString(resultValueStr))
+	}
+
+	return conditionNull
+}
+
+// orderValues orders two values converted to comparable types: as numbers when both converted to
+// one, or as strings when both were strings. It reports false when the values have no order.
+func orderValues(value, compared, resourceValue, filterValue interface{}) (int, bool) {
+	if a, ok := resourceValue.(float64); ok {
+		if b, ok := filterValue.(float64); ok {
+			return cmp.Compare(a, b), true
+		}
+	}
+	_, valueIsString := value.(string)
+	_, comparedIsString := compared.(string)
+	if valueIsString && comparedIsString {
+		return strings.Compare(fmt.Sprintf("%v", resourceValue), fmt.Sprintf("%v", filterValue)), true
+	}
+	return 0, false
+}
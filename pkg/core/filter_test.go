@@ -0,0 +1,206 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvaluateFilter(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC) }
+
+	pod := map[string]interface{}{
+		"kind": "Pod",
+		"metadata": map[string]interface{}{
+			"name":              "api-7d9f",
+			"namespace":         "default",
+			"creationTimestamp": "2024-05-01T10:00:00Z",
+		},
+		"spec": map[string]interface{}{
+			"overhead": map[string]interface{}{"cpu": "250m", "memory": "768Mi"},
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name":  "api",
+					"image": "api:1.4",
+					"ports": []interface{}{map[string]interface{}{"containerPort": 8080}},
+				},
+				map[string]interface{}{
+					"name":  "proxy",
+					"image": "envoy:latest",
+					"args":  []interface{}{"--log-level", "debug"},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":        "Running",
+			"restartCount": int64(7),
+		},
+	}
+
+	leaf := func(key string, operator string, value interface{}) *Filter {
+		return &Filter{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: key, Operator: operator, Value: value}}
+	}
+
+	tests := []struct {
+		name     string
+		filter   *Filter
+		expected bool
+	}{
+		{"leaf match", leaf("p.status.phase", "EQUALS", "Running"), true},
+		{"leaf mismatch", leaf("p.status.phase", "EQUALS", "Failed"), false},
+		{"missing path", leaf("p.status.reason", "NOT_EQUALS", "Evicted"), false},
+		{
+			"not over a missing path",
+			&Filter{Type: NotFilter, Operands: []*Filter{leaf("p.status.reason", "EQUALS", "Evicted")}},
+			false,
+		},
+		{"order of values of different types", leaf("p.status.phase", "GREATER_THAN", 5), false},
+		{
+			"not over the order of values of different types",
+			&Filter{Type: NotFilter, Operands: []*Filter{leaf("p.status.phase", "GREATER_THAN", 5)}},
+			false,
+		},
+		{"order of strings", leaf("p.status.phase", "GREATER_THAN", "Pending"), true},
+		{
+			"or with a null operand and a true operand",
+			&Filter{Type: OrFilter, Operands: []*Filter{
+				leaf("p.status.reason", "EQUALS", "Evicted"),
+				leaf("p.status.phase", "EQUALS", "Running"),
+			}},
+			true,
+		},
+		{
+			"not over an and with a null operand and a false operand",
+			&Filter{Type: NotFilter, Operands: []*Filter{{Type: AndFilter, Operands: []*Filter{
+				leaf("p.status.reason", "EQUALS", "Evicted"),
+				leaf("p.status.phase", "EQUALS", "Failed"),
+			}}}},
+			true,
+		},
+		{
+			"not over an or with a null operand and a false operand",
+			&Filter{Type: NotFilter, Operands: []*Filter{{Type: OrFilter, Operands: []*Filter{
+				leaf("p.status.reason", "EQUALS", "Evicted"),
+				leaf("p.status.phase", "EQUALS", "Failed"),
+			}}}},
+			false,
+		},
+		{
+			"not over a missing list",
+			&Filter{Type: NotFilter, Operands: []*Filter{
+				{Type: AnyFilter, Variable: "c", ListPath: "p.spec.initContainers", Operands: []*Filter{leaf("c.name", "EQUALS", "init")}},
+			}},
+			false,
+		},
+		{
+			"or with one matching operand",
+			&Filter{Type: OrFilter, Operands: []*Filter{
+				leaf("p.status.phase", "EQUALS", "Failed"),
+				leaf("p.status.restartCount", "GREATER_THAN", 5),
+			}},
+			true,
+		},
+		{
+			"or with no matching operand",
+			&Filter{Type: OrFilter, Operands: []*Filter{
+				leaf("p.status.phase", "EQUALS", "Failed"),
+				leaf("p.status.restartCount", "GREATER_THAN", 10),
+			}},
+			false,
+		},
+		{
+			"and with one failing operand",
+			&Filter{Type: AndFilter, Operands: []*Filter{
+				leaf("p.metadata.name", "REGEX_COMPARE", "^api-"),
+				leaf("p.metadata.namespace", "EQUALS", "kube-system"),
+			}},
+			false,
+		},
+		{
+			"not",
+			&Filter{Type: NotFilter, Operands: []*Filter{leaf("p.metadata.name", "CONTAINS", "worker")}},
+			true,
+		},
+		{"memory greater than", leaf("p.spec.overhead.memory", "GREATER_THAN", "512Mi"), true},
+		{"memory less than", leaf("p.spec.overhead.memory", "LESS_THAN", "1Gi"), true},
+		{"memory equals in another unit", leaf("p.spec.overhead.memory", "EQUALS", "0.75Gi"), true},
+		{"cpu less than cores", leaf("p.spec.overhead.cpu", "LESS_THAN", 1), true},
+		{"cpu greater than", leaf("p.spec.overhead.cpu", "GREATER_THAN", "0.5"), false},
+		{"timestamp before", leaf("p.metadata.creationTimestamp", "LESS_THAN", "2024-01-01T00:00:00Z"), false},
+		{"timestamp in another zone", leaf("p.metadata.creationTimestamp", "EQUALS", "2024-05-01T12:00:00+02:00"), true},
+		{"younger than a day", leaf("p.metadata.creationTimestamp", "GREATER_THAN", &FunctionCall{Name: "ago", Args: []interface{}{"24h"}}), true},
+		{"younger than an hour", leaf("p.metadata.creationTimestamp", "GREATER_THAN", &FunctionCall{Name: "ago", Args: []interface{}{"1h"}}), false},
+		{"in list", leaf("p.status.phase", "IN", []interface{}{"Failed", "Running"}), true},
+		{"not in list", leaf("p.status.phase", "IN", []interface{}{"Failed", "Unknown"}), false},
+		{"number in list", leaf("p.status.restartCount", "IN", []interface{}{5, 7}), true},
+		{"starts with", leaf("p.metadata.name", "STARTS_WITH", "api-"), true},
+		{"ends with", leaf("p.metadata.name", "ENDS_WITH", "api"), false},
+		{"missing path is null", leaf("p.spec.priorityClassName", "IS_NULL", nil), true},
+		{"existing path is not null", leaf("p.status.phase", "IS_NOT_NULL", nil), true},
+		{"missing path is not not null", leaf("p.spec.priorityClassName", "IS_NOT_NULL", nil), false},
+		{"wildcard with a matching element", leaf("p.spec.containers[*].image", "REGEX_COMPARE", ":latest$"), true},
+		{"wildcard with no matching element", leaf("p.spec.containers[*].name", "EQUALS", "worker"), false},
+		{"nested wildcards", leaf("p.spec.containers[*].ports[*].containerPort", "IN", []interface{}{80, 8080}), true},
+		{
+			"any with a matching element",
+			&Filter{Type: AnyFilter, Variable: "c", ListPath: "p.spec.containers", Operands: []*Filter{
+				{Type: AndFilter, Operands: []*Filter{
+					leaf("c.image", "ENDS_WITH", ":latest"),
+					leaf("c.name", "EQUALS", "proxy"),
+				}},
+			}},
+			true,
+		},
+		{
+			"any with no matching element",
+			&Filter{Type: AnyFilter, Variable: "c", ListPath: "p.spec.containers", Operands: []*Filter{
+				{Type: AndFilter, Operands: []*Filter{
+					leaf("c.image", "ENDS_WITH", ":latest"),
+					leaf("c.name", "EQUALS", "api"),
+				}},
+			}},
+			false,
+		},
+		{
+			"nested any over strings",
+			&Filter{Type: AnyFilter, Variable: "c", ListPath: "p.spec.containers", Operands: []*Filter{
+				{Type: AnyFilter, Variable: "a", ListPath: "c.args", Operands: []*Filter{leaf("a", "EQUALS", "debug")}},
+			}},
+			true,
+		},
+		{
+			"any over a missing list",
+			&Filter{Type: AnyFilter, Variable: "c", ListPath: "p.spec.initContainers", Operands: []*Filter{leaf("c.name", "IS_NOT_NULL", nil)}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateFilter(pod, tt.filter, "p", nil); got != tt.expected {
+				t.Errorf("evaluateFilter() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetFilterNodeNames(t *testing.T) {
+	filter := &Filter{Type: OrFilter, Operands: []*Filter{
+		{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.status.phase"}},
+		{Type: NotFilter, Operands: []*Filter{
+			{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "d.spec.replicas"}},
+		}},
+		{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.metadata.name"}},
+		{Type: PatternFilter, Pattern: &NodeRelationshipList{Nodes: []*NodePattern{
+			{ResourceProperties: &ResourceProperties{Name: "s"}},
+			{ResourceProperties: &ResourceProperties{Kind: "Endpoints"}},
+		}}},
+	}}
+
+	got := getFilterNodeNames(filter)
+	expected := []string{"p", "d", "s"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("getFilterNodeNames() = %v, want %v", got, expected)
+	}
+}
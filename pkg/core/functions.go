@@ -0,0 +1,520 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// Function is a built-in function that can be called in RETURN and WHERE clauses and in SET values
+type Function struct {
+	// MinArgs and MaxArgs bound the number of arguments, a negative MaxArgs allows any number
+	MinArgs int
+	MaxArgs int
+	// Call computes the result of the function from its evaluated arguments
+	Call func(args []interface{}) (interface{}, error)
+}
+
+var (
+	functionsLock sync.RWMutex
+	// functions are keyed by their lowercased name, function names are case-insensitive
+	functions = map[string]Function{
+		// Strings
+		"tolower":   {MinArgs: 1, MaxArgs: 1, Call: stringFunction(strings.ToLower)},
+		"toupper":   {MinArgs: 1, MaxArgs: 1, Call: stringFunction(strings.ToUpper)},
+		"trim":      {MinArgs: 1, MaxArgs: 1, Call: stringFunction(strings.TrimSpace)},
+		"replace":   {MinArgs: 3, MaxArgs: 3, Call: replaceFunction},
+		"substring": {MinArgs: 2, MaxArgs: 3, Call: substringFunction},
+		"split":     {MinArgs: 2, MaxArgs: 2, Call: splitFunction},
+		"tostring":  {MinArgs: 1, MaxArgs: 1, Call: toStringFunction},
+
+		// Lists and maps
+		"size":     {MinArgs: 1, MaxArgs: 1, Call: sizeFunction},
+		"head":     {MinArgs: 1, MaxArgs: 1, Call: listFunction(func(list []interface{}) interface{} { return list[0] })},
+		"last":     {MinArgs: 1, MaxArgs: 1, Call: listFunction(func(list []interface{}) interface{} { return list[len(list)-1] })},
+		"keys":     {MinArgs: 1, MaxArgs: 1, Call: keysFunction},
+		"join":     {MinArgs: 2, MaxArgs: 2, Call: joinFunction},
+		"coalesce": {MinArgs: 1, MaxArgs: -1, Call: coalesceFunction},
+
+		// Time and durations
+		"now":      {MinArgs: 0, MaxArgs: 0, Call: nowFunction},
+		"age":      {MinArgs: 1, MaxArgs: 1, Call: ageFunction},
+		"duration": {MinArgs: 1, MaxArgs: 1, Call: durationFunction},
+		"ago":      {MinArgs: 1, MaxArgs: 1, Call: agoFunction},
+
+		// Kubernetes quantities
+		"millicores": {MinArgs: 1, MaxArgs: 1, Call: millicoresFunction},
+		"bytes":      {MinArgs: 1, MaxArgs: 1, Call: bytesFunction},
+
+		// Relationships and paths
+		"type":          {MinArgs: 1, MaxArgs: 1, Call: typeFunction},
+		"nodes":         {MinArgs: 1, MaxArgs: 1, Call: pathFunction(func(path map[string]interface{}) interface{} { return path["nodes"] })},
+		"relationships": {MinArgs: 1, MaxArgs: 1, Call: pathFunction(func(path map[string]interface{}) interface{} { return path["relationships"] })},
+		"length":        {MinArgs: 1, MaxArgs: 1, Call: pathFunction(func(path map[string]interface{}) interface{} { return len(path["relationships"].([]interface{})) })},
+	}
+
+	// timeNow returns the current time, tests replace it to get stable results
+	timeNow = time.Now
+
+	durationRegex = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)(ms|[ydhms])`)
+)
+
+// RegisterFunction makes a function callable from queries, replacing any function
+// registered under the same name. Function names are case-insensitive.
+func RegisterFunction(name string, fn Function) {
+	functionsLock.Lock()
+	defer functionsLock.Unlock()
+	functions[strings.ToLower(name)] = fn
+}
+
+func lookupFunction(name string) (Function, bool) {
+	functionsLock.RLock()
+	defer functionsLock.RUnlock()
+	fn, ok := functions[strings.ToLower(name)]
+	return fn, ok
+}
+
+// callFunction calls a registered function with evaluated arguments
+func callFunction(name string, args []interface{}) (interface{}, error) {
+	fn, ok := lookupFunction(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if err := checkArity(name, fn, len(args)); err != nil {
+		return nil, err
+	}
+	result, err := fn.Call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return result, nil
+}
+
+// checkArity checks that a function is called with a number of arguments it accepts
+func checkArity(name string, fn Function, count int) error {
+	if count >= fn.MinArgs && (fn.MaxArgs < 0 || count <= fn.MaxArgs) {
+		return nil
+	}
+	var expected string
+	switch {
+	case fn.MaxArgs < 0:
+		expected = fmt.Sprintf("at least %d", fn.MinArgs)
+	case fn.MinArgs == fn.MaxArgs:
+		expected = strconv.Itoa(fn.MinArgs)
+	default:
+		expected = fmt.Sprintf("%d to %d", fn.MinArgs, fn.MaxArgs)
+	}
+	return fmt.Errorf("%s expects %s arguments, got %d", name, expected, count)
+}
+
+// String returns the function call as written in a query, it's the key of an unaliased return item
+func (f *FunctionCall) String() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		switch v := arg.(type) {
+		case *FieldReference:
+			args[i] = v.JsonPath
+		case *FunctionCall:
+			args[i] = v.String()
+		case string:
+			args[i] = strconv.Quote(v)
+		case nil:
+			args[i] = "NULL"
+		default:
+			args[i] = fmt.Sprintf("%v", v)
+		}
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// functionNodeIds returns the nodes referenced by the arguments of a function call
+func functionNodeIds(f *FunctionCall) []string {
+	var nodeIds []string
+	for _, arg := range f.Args {
+		var ids []string
+		switch v := arg.(type) {
+		case *FieldReference:
+			ids = []string{getFilterNodeName(v.JsonPath)}
+		case *FunctionCall:
+			ids = functionNodeIds(v)
+		}
+		for _, id := range ids {
+			if !slices.Contains(nodeIds, id) {
+				nodeIds = append(nodeIds, id)
+			}
+		}
+	}
+	return nodeIds
+}
+
+// evaluateFunctionCall evaluates a function call against a resource of the node it refers to.
+// Missing fields evaluate to null. With no node name, as for WITH aliases, field references
+// are looked up in the resource by their full path.
+func evaluateFunctionCall(f *FunctionCall, nodeName string, resource map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(f.Args))
+	for i, arg := range f.Args {
+		switch v := arg.(type) {
+		case *FieldReference:
+			nodeId, path := splitKeyPath(v.JsonPath)
+			if nodeName == "" {
+				path = append([]string{nodeId}, path...)
+			}
+			args[i], _ = lookupField(resource, path)
+		case *FunctionCall:
+			value, err := evaluateFunctionCall(v, nodeName, resource)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		default:
+			args[i] = v
+		}
+	}
+	return callFunction(f.Name, args)
+}
+
+// typeFunction returns the type of a relationship bound to a variable, e.g. type(r) for -[r]->
+func typeFunction(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		if isRelationshipEntry(v) {
+			return v["type"], nil
+		}
+		return nil, fmt.Errorf("expected a relationship, got %s", describeResource(v))
+	default:
+		return nil, fmt.Errorf("expected a relationship, got %v", v)
+	}
+}
+
+// pathFunction turns a function of a path bound to a variable, e.g. p = (i:Ingress)->(s:Service),
+// into a function of a single path argument
+func pathFunction(fn func(map[string]interface{}) interface{}) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return nil, nil
+		case map[string]interface{}:
+			if isPathEntry(v) {
+				return fn(v), nil
+			}
+			return nil, fmt.Errorf("expected a path, got %s", describeResource(v))
+		default:
+			return nil, fmt.Errorf("expected a path, got %v", v)
+		}
+	}
+}
+
+// stringFunction turns a string transformation into a function of a single string argument
+func stringFunction(transform func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		s, err := stringArgument(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return transform(s), nil
+	}
+}
+
+// listFunction turns a function of a non-empty list into a function of a single list argument,
+// empty lists yield null
+func listFunction(fn func([]interface{}) interface{}) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		list, ok := args[0].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list, got %v", args[0])
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+		return fn(list), nil
+	}
+}
+
+func replaceFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	strs := make([]string, 3)
+	for i, arg := range args {
+		s, err := stringArgument(arg)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+// substringFunction returns the part of a string starting at an index, optionally limited to a length
+func substringFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+
+	start, err := intArgument(args[1])
+	if err != nil {
+		return nil, err
+	}
+	start = min(max(start, 0), len(runes))
+	end := len(runes)
+	if len(args) == 3 {
+		length, err := intArgument(args[2])
+		if err != nil {
+			return nil, err
+		}
+		end = min(start+max(length, 0), len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+func splitFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	delimiter, err := stringArgument(args[1])
+	if err != nil {
+		return nil, err
+	}
+	parts := []interface{}{}
+	for _, part := range strings.Split(s, delimiter) {
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// toStringFunction converts a value to a string, lists and maps are converted to JSON
+func toStringFunction(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// sizeFunction returns the number of characters of a string, or of items of a list or map
+func sizeFunction(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return len([]rune(v)), nil
+	case []interface{}:
+		return len(v), nil
+	case map[string]interface{}:
+		return len(v), nil
+	default:
+		return nil, fmt.Errorf("expected a string, list or map, got %v", v)
+	}
+}
+
+// keysFunction returns the sorted keys of a map
+func keysFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	m, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a map, got %v", args[0])
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		result[i] = key
+	}
+	return result, nil
+}
+
+func joinFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %v", args[0])
+	}
+	delimiter, err := stringArgument(args[1])
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(list))
+	for i, item := range list {
+		strs[i] = fmt.Sprintf("%v", item)
+	}
+	return strings.Join(strs, delimiter), nil
+}
+
+// coalesceFunction returns its first non-null argument
+func coalesceFunction(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func nowFunction(args []interface{}) (interface{}, error) {
+	return timeNow().UTC().Format(time.RFC3339), nil
+}
+
+// ageFunction returns the time elapsed since an RFC3339 timestamp, in the format used by kubectl (e.g. 3d4h)
+func ageFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %s", s)
+	}
+	return duration.HumanDuration(timeNow().Sub(timestamp)), nil
+}
+
+// durationFunction converts a duration such as 90s, 1h30m or 3d4h to a number of seconds
+func durationFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := parseDurationSeconds(s)
+	if err != nil {
+		return nil, err
+	}
+	if seconds == math.Trunc(seconds) {
+		return int64(seconds), nil
+	}
+	return seconds, nil
+}
+
+// agoFunction returns the RFC3339 timestamp of the time a duration such as 24h ago
+func agoFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArgument(args[0])
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := parseDurationSeconds(s)
+	if err != nil {
+		return nil, err
+	}
+	ago := timeNow().Add(-time.Duration(seconds * float64(time.Second)))
+	return ago.UTC().Format(time.RFC3339), nil
+}
+
+// parseDurationSeconds parses a duration made of numbers followed by the units y, d, h, m, s
+// or ms, and returns its length in seconds
+func parseDurationSeconds(s string) (float64, error) {
+	units := map[string]float64{"y": 365 * 24 * 3600, "d": 24 * 3600, "h": 3600, "m": 60, "s": 1, "ms": 0.001}
+
+	matches := durationRegex.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	var seconds float64
+	end := 0
+	for _, match := range matches {
+		if match[0] != end {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		value, err := strconv.ParseFloat(s[match[2]:match[3]], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		seconds += value * units[s[match[4]:match[5]]]
+		end = match[1]
+	}
+	if end != len(s) {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	return seconds, nil
+}
+
+// millicoresFunction converts a CPU quantity such as 500m or 1.5 to millicores
+func millicoresFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	milliCPU, err := convertToMilliCPU(fmt.Sprintf("%v", args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return milliCPU, nil
+}
+
+// bytesFunction converts a memory quantity such as 512Mi or 1G to bytes
+func bytesFunction(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	bytes, err := convertMemoryToBytes(fmt.Sprintf("%v", args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return bytes, nil
+}
+
+func stringArgument(arg interface{}) (string, error) {
+	s, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %v", arg)
+	}
+	return s, nil
+}
+
+func intArgument(arg interface{}) (int, error) {
+	if i, ok := toInt64(arg); ok {
+		return int(i), nil
+	}
+	if f, ok := arg.(float64); ok && f == math.Trunc(f) {
+		return int(f), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %v", arg)
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallFunction(t *testing.T) {
+	defer func(now func() time.Time) { timeNow = now }(timeNow)
+	timeNow = func() time.Time { return time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		args    []interface{}
+		want    interface{}
+		wantErr string
+	}{
+		{name: "toLower", args: []interface{}{"NGINX"}, want: "nginx"},
+		{name: "TOUPPER", args: []interface{}{"nginx"}, want: "NGINX"},
+		{name: "toLower", args: []interface{}{nil}, want: nil},
+		{name: "toLower", args: []interface{}{3}, wantErr: "toLower: expected a string, got 3"},
+		{name: "trim", args: []interface{}{"  web "}, want: "web"},
+		{name: "replace", args: []interface{}{"nginx:1.25", ":", "@"}, want: "nginx@1.25"},
+		{name: "substring", args: []interface{}{"nginx-7d9f", 6}, want: "7d9f"},
+		{name: "substring", args: []interface{}{"nginx-7d9f", 0, 5}, want: "nginx"},
+		{name: "substring", args: []interface{}{"nginx", 2, 10}, want: "inx"},
+		{name: "split", args: []interface{}{"a,b", ","}, want: []interface{}{"a", "b"}},
+		{name: "toString", args: []interface{}{int64(3)}, want: "3"},
+		{name: "toString", args: []interface{}{map[string]interface{}{"app": "web"}}, want: `{"app":"web"}`},
+		{name: "size", args: []interface{}{[]interface{}{"a", "b"}}, want: 2},
+		{name: "size", args: []interface{}{"nginx"}, want: 5},
+		{name: "size", args: []interface{}{map[string]interface{}{"a": 1}}, want: 1},
+		{name: "size", args: []interface{}{true}, wantErr: "expected a string, list or map"},
+		{name: "head", args: []interface{}{[]interface{}{"a", "b"}}, want: "a"},
+		{name: "last", args: []interface{}{[]interface{}{"a", "b"}}, want: "b"},
+		{name: "head", args: []interface{}{[]interface{}{}}, want: nil},
+		{name: "keys", args: []interface{}{map[string]interface{}{"tier": "web", "app": "nginx"}}, want: []interface{}{"app", "tier"}},
+		{name: "join", args: []interface{}{[]interface{}{"a", 1}, ", "}, want: "a, 1"},
+		{name: "coalesce", args: []interface{}{nil, "fallback", "other"}, want: "fallback"},
+		{name: "coalesce", args: []interface{}{nil}, want: nil},
+		{name: "now", args: []interface{}{}, want: "2024-05-10T12:00:00Z"},
+		{name: "age", args: []interface{}{"2024-05-07T08:00:00Z"}, want: "3d4h"},
+		{name: "age", args: []interface{}{"yesterday"}, wantErr: "invalid timestamp"},
+		{name: "ago", args: []interface{}{"36h"}, want: "2024-05-09T00:00:00Z"},
+		{name: "duration", args: []interface{}{"1h30m"}, want: int64(5400)},
+		{name: "duration", args: []interface{}{"3d4h"}, want: int64(273600)},
+		{name: "duration", args: []interface{}{"1.5s"}, want: 1.5},
+		{name: "duration", args: []interface{}{"1 hour"}, wantErr: "invalid duration"},
+		{name: "millicores", args: []interface{}{"1.5"}, want: 1500},
+		{name: "millicores", args: []interface{}{"250m"}, want: 250},
+		{name: "bytes", args: []interface{}{"512Mi"}, want: int64(512 << 20)},
+		{name: "type", args: []interface{}{map[string]interface{}{"type": "ROUTE", "start": "Ingress/web", "end": "Service/web"}}, want: "ROUTE"},
+		{name: "type", args: []interface{}{mockResource("Pod", "web", nil)}, wantErr: "expected a relationship, got pod/web"},
+		{name: "length", args: []interface{}{map[string]interface{}{"nodes": []interface{}{"a", "b"}, "relationships": []interface{}{"r"}}}, want: 1},
+		{name: "nodes", args: []interface{}{map[string]interface{}{"nodes": []interface{}{"a", "b"}, "relationships": []interface{}{"r"}}}, want: []interface{}{"a", "b"}},
+		{name: "length", args: []interface{}{"web"}, wantErr: "expected a path, got web"},
+		{name: "trim", args: []interface{}{"a", "b"}, wantErr: "trim expects 1 arguments, got 2"},
+		{name: "coalesce", args: []interface{}{}, wantErr: "coalesce expects at least 1 arguments, got 0"},
+		{name: "nope", args: []interface{}{}, wantErr: "unknown function nope"},
+	}
+
+	for _, tt := range tests {
+		got, err := callFunction(tt.name, tt.args)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s(%v) error = %v, want error containing %q", tt.name, tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s(%v) error = %v", tt.name, tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s(%v) = %#v, want %#v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	RegisterFunction("Double", Function{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Call: func(args []interface{}) (interface{}, error) {
+			n, err := intArgument(args[0])
+			return n * 2, err
+		},
+	})
+	defer func() {
+		functionsLock.Lock()
+		delete(functions, "double")
+		functionsLock.Unlock()
+	}()
+
+	ast, err := ParseQuery("MATCH (d:Deployment) WHERE double(d.spec.replicas) > 4 RETURN double(d.spec.replicas) AS doubled")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	executor, err := NewQueryExecutor(&mockProvider{resources: map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}}),
+			mockResource("Deployment", "api", map[string]interface{}{"spec": map[string]interface{}{"replicas": 1}}),
+		},
+	}})
+	if err != nil {
+		t.Fatalf("NewQueryExecutor() error = %v", err)
+	}
+	result, err := executor.Execute(ast, "default")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []interface{}{map[string]interface{}{"name": "web", "doubled": 6}}
+	if !reflect.DeepEqual(result.Data["d"], want) {
+		t.Errorf("got %v, want %v", result.Data["d"], want)
+	}
+}
+
+func TestExecuteFunctions(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"pods": {
+			mockResource("Pod", "Web-1", map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+				"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "web"},
+					map[string]interface{}{"name": "proxy"},
+				}},
+			}),
+			mockResource("Pod", "API-1", map[string]interface{}{
+				"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "api"},
+				}},
+			}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		want     interface{}
+	}{
+		{
+			name:     "computed return items",
+			query:    `MATCH (p:Pod) RETURN toLower(p.metadata.name) AS id, size(p.spec.containers), coalesce(p.metadata.labels.app, "none") AS app`,
+			variable: "p",
+			want: []interface{}{
+				map[string]interface{}{"name": "Web-1", "id": "web-1", "size(p.spec.containers)": 2, "app": "web"},
+				map[string]interface{}{"name": "API-1", "id": "api-1", "size(p.spec.containers)": 1, "app": "none"},
+			},
+		},
+		{
+			name:     "function in WHERE",
+			query:    `MATCH (p:Pod) WHERE size(p.spec.containers) > 1 RETURN p.metadata.name`,
+			variable: "p",
+			want: []interface{}{
+				map[string]interface{}{"name": "Web-1", "metadata": map[string]interface{}{"name": "Web-1"}},
+			},
+		},
+		{
+			name:     "order by the alias of a computed item",
+			query:    `MATCH (p:Pod) RETURN toLower(p.metadata.name) AS lowered ORDER BY lowered`,
+			variable: "p",
+			want: []interface{}{
+				map[string]interface{}{"name": "API-1", "lowered": "api-1"},
+				map[string]interface{}{"name": "Web-1", "lowered": "web-1"},
+			},
+		},
+		{
+			name:     "group by a computed item",
+			query:    `MATCH (p:Pod) RETURN size(p.spec.containers) AS containers, COUNT{p.metadata.name} AS pods`,
+			variable: "aggregate",
+			want: []interface{}{
+				map[string]interface{}{"containers": 2, "pods": 1},
+				map[string]interface{}{"containers": 1, "pods": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data[tt.variable], tt.want) {
+				t.Errorf("got %v, want %v", result.Data[tt.variable], tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,14 @@ package core
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AvitalTamir/jsonpath"
 	"github.com/avitaltamir/cyphernetes/pkg/provider"
@@ -33,9 +34,16 @@ type Graph struct {
 	Edges []Edge
 }
 
+// QueryResult holds the results of a query. Data holds the items returned for each variable,
+// and Rows the same items combined into one row per match of the returned variables.
+// Deletions describes the resources deleted by a DETACH DELETE clause, and Warnings what was
+// deprecated in a query that still executed.
 type QueryResult struct {
-	Data  map[string]interface{}
-	Graph Graph
+	Data      map[string]interface{}
+	Graph     Graph
+	Rows      []map[string]interface{} `json:",omitempty"`
+	Deletions *DeletionPlan            `json:",omitempty"`
+	Warnings  []string                 `json:",omitempty"`
 }
 
 var resultCache = make(map[string]interface{})
@@ -54,8 +62,18 @@ var (
 	AllNamespaces bool
 	CleanOutput   bool
 	NoColor       bool
+	Cascade       string
 )
 
+// ExecuteOptions are options that only apply to a single execution of a query
+type ExecuteOptions struct {
+	// Rows makes the query report its results as rows in QueryResult.Rows too
+	Rows bool
+	// ConfirmDeletion, when set, is used instead of the package's ConfirmDeletion to confirm the
+	// plans of DETACH DELETE clauses
+	ConfirmDeletion func(plan *DeletionPlan) bool
+}
+
 // Add the apiRequest type definition
 type apiRequest struct{}
 
@@ -72,13 +90,26 @@ func NewQueryExecutor(p provider.Provider) (*QueryExecutor, error) {
 }
 
 func (q *QueryExecutor) Execute(ast *Expression, namespace string) (QueryResult, error) {
+	return q.ExecuteWithOptions(ast, namespace, ExecuteOptions{})
+}
+
+// ExecuteWithOptions executes a query like Execute does, with options for this execution only
+func (q *QueryExecutor) ExecuteWithOptions(ast *Expression, namespace string, opts ExecuteOptions) (QueryResult, error) {
 	if len(ast.Contexts) > 0 {
-		return ExecuteMultiContextQuery(ast, namespace)
+		return executeMultiContextQuery(ast, namespace, opts)
 	}
-	return q.ExecuteSingleQuery(ast, namespace)
+	return q.executeSingleQuery(ast, namespace, opts)
 }
 
 func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (QueryResult, error) {
+	return q.executeSingleQuery(ast, namespace, ExecuteOptions{})
+}
+
+func (q *QueryExecutor) executeSingleQuery(ast *Expression, namespace string, opts ExecuteOptions) (QueryResult, error) {
+	if len(ast.Unions) > 0 {
+		return q.executeUnion(ast, namespace, opts)
+	}
+
 	if AllNamespaces {
 		Namespace = ""
 		AllNamespaces = false // to reset value
@@ -94,74 +125,79 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 		},
 	}
 
+	// clear the result cache and result map once done, even if the query fails or is cancelled
+	defer func() {
+		resultCache = make(map[string]interface{})
+		resultMap = make(map[string]interface{})
+		hopResourceCache = make(map[string][]map[string]interface{})
+	}()
+
+	// The nodes and relationships in scope, for WITH clauses
+	var scopeNodes []*NodePattern
+	var scopeRelationships []*Relationship
+	var scopePaths []*PathPattern
+	var scopeUnwinds []*UnwindClause
+	hasResults := true
+
 	// Iterate over the clauses in the AST.
 	for _, clause := range ast.Clauses {
 		switch c := clause.(type) {
 		case *MatchClause:
-			var filteringOccurred bool
-			filteredResults := make(map[string][]map[string]interface{})
-
-			for i := 0; i < len(c.Relationships)*2; i++ {
-				filteringOccurred = false
-				for _, rel := range c.Relationships {
-					filtered, err := q.processRelationship(rel, c, results, filteredResults)
-					if err != nil {
-						return *results, err
-					}
-					filteringOccurred = filteringOccurred || filtered
-				}
-				if !filteringOccurred {
-					break
-				}
-				// Update resultMap with filtered results for the next pass
-				for k, v := range filteredResults {
-					resultMap[k] = v
+			scopeNodes = append(scopeNodes, c.Nodes...)
+			scopeRelationships = append(scopeRelationships, c.Relationships...)
+			scopePaths = append(scopePaths, c.Paths...)
+
+			// Nothing matches once a WITH clause has filtered out all results
+			if !hasResults {
+				for _, node := range c.Nodes {
+					resultMap[node.ResourceProperties.Name] = []map[string]interface{}{}
 				}
+				continue
 			}
 
-			// Process nodes
-			err := q.processNodes(c, results)
+			q.warnReversedRelationships(c.Relationships, results)
+			if err := q.processMatchClause(c, results); err != nil {
+				return *results, err
+			}
+
+		case *WithClause:
+			var err error
+			hasResults, err = q.executeWithClause(c, scopeNodes, scopeRelationships, results)
 			if err != nil {
 				return *results, err
 			}
+			// Unwound elements aren't carried over
+			scopeUnwinds = nil
 
-		case *SetClause:
-			for _, kvp := range c.KeyValuePairs {
-				resultMapKey := strings.Split(kvp.Key, ".")[0]
-				path := []string{}
-				parts := strings.Split(kvp.Key, ".")
-				for i := 1; i < len(parts); i++ {
-					if i > 1 && strings.HasSuffix(parts[i-1], "\\") {
-						// Combine this part with the previous one, removing the backslash
-						path[len(path)-1] = path[len(path)-1][:len(path[len(path)-1])-1] + "." + strings.ReplaceAll(parts[i], "/", "~1")
-					} else {
-						path = append(path, strings.ReplaceAll(parts[i], "/", "~1"))
-					}
-				}
+		case *UnwindClause:
+			if err := q.executeUnwindClause(c, scopeNodes, scopeRelationships, scopeUnwinds, results); err != nil {
+				return *results, err
+			}
+			scopeUnwinds = append(scopeUnwinds, c)
 
-				resources := resultMap[resultMapKey].([]map[string]interface{})
-				for _, resource := range resources {
-					// Create a single patch that works with the existing structure
-					patches := createCompatiblePatch(path, kvp.Value)
+		case *SetClause:
+			if err := q.setKeyValuePairs(c.KeyValuePairs, scopeRelationships); err != nil {
+				return *results, err
+			}
 
-					// Marshal the patches to JSON
-					patchJSON, err := json.Marshal(patches)
-					if err != nil {
-						return *results, fmt.Errorf("error marshalling patches: %s", err)
-					}
+		case *MergeClause:
+			if err := q.executeMergeClause(c, scopeRelationships, results); err != nil {
+				return *results, err
+			}
 
-					// Apply the patches to the resource
-					err = q.PatchK8sResource(resource, patchJSON)
-					if err != nil {
-						return *results, fmt.Errorf("error patching resource: %s", err)
-					}
+		case *RemoveClause:
+			if err := q.removeFields(c.JsonPaths); err != nil {
+				return *results, err
+			}
 
-					// Update the resultMap
-					updateResultMap(resource, path, kvp.Value)
+		case *DeleteClause:
+			if c.Detach {
+				if err := q.detachDelete(c.NodeIds, opts.ConfirmDeletion, results); err != nil {
+					return *results, err
 				}
+				break
 			}
 
-		case *DeleteClause:
 			// Execute a Kubernetes delete operation based on the DeleteClause.
 			for _, nodeId := range c.NodeIds {
 				// make sure the identifier is a key in the result map
@@ -223,7 +259,6 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 				// The foreign node is currently only a name reference, we'll need to find the matching node in the result map
 				foreignNode.ResourceProperties.Kind = resultMap[foreignNode.ResourceProperties.Name].([]map[string]interface{})[0]["kind"].(string)
 
-				var relType RelationshipType
 				targetGVR, err := q.findGVR(node.ResourceProperties.Kind)
 				if err != nil {
 					return *results, fmt.Errorf("error finding API resource >> %s", err)
@@ -234,22 +269,13 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 					return *results, fmt.Errorf("error finding API resource >> %s", err)
 				}
 
-				for _, resourceRelationship := range relationshipRules {
-					if (strings.EqualFold(targetGVR.Resource, resourceRelationship.KindA) && strings.EqualFold(foreignGVR.Resource, resourceRelationship.KindB)) ||
-						(strings.EqualFold(foreignGVR.Resource, resourceRelationship.KindA) && strings.EqualFold(targetGVR.Resource, resourceRelationship.KindB)) {
-						relType = resourceRelationship.Relationship
-					}
-				}
-
-				if relType == "" {
-					// no relationship type found, error out
-					return *results, fmt.Errorf("relationship type not found between %s and %s", targetGVR.Resource, foreignGVR.Resource)
-				}
-
-				rule, err := findRuleByRelationshipType(relType)
+				// Created resources are related regardless of the direction of the relationship
+				rules, err := findRulesBetweenKinds(targetGVR.Resource, foreignGVR.Resource, None, relationshipTypes(rel))
 				if err != nil {
-					return *results, fmt.Errorf("error determining relationship type >> %s", err)
+					return *results, err
 				}
+				// When several rules relate the kinds, the resource is created by the last one
+				rule := rules[len(rules)-1]
 
 				// Now according to which is the node that needs to be created, we'll construct the spec from the node properties and from the relevant part of the spec that's defined in the relationship
 				// If the node to be created matches KindA in the relationship, then it's spec's nested structure described in the jsonPath in FieldA will have the value of the other node's FieldB
@@ -427,6 +453,11 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 			}
 
 		case *ReturnClause:
+			groupItems := getGroupItems(c)
+
+			// Rows only hold the returned items
+			items := slices.Clone(c.Items)
+
 			nodeIds := []string{}
 			for _, item := range c.Items {
 				// generate a unique list of nodeIds
@@ -436,10 +467,19 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 				}
 			}
 
-			// Add a "name" property to each node
-			for _, nodeId := range nodeIds {
-				metadataNamePath := strings.Join([]string{nodeId, "metadata.name"}, ".")
-				c.Items = append(c.Items, &ReturnItem{JsonPath: metadataNamePath, Alias: "name"})
+			// Add a "name" property to each node, DISTINCT results only hold the returned items
+			if !c.Distinct {
+				for _, nodeId := range nodeIds {
+					// Relationships, paths and unwound elements have no name
+					if slices.ContainsFunc(scopeUnwinds, func(u *UnwindClause) bool { return u.Alias == nodeId }) {
+						continue
+					}
+					if resources, ok := resultMap[nodeId].([]map[string]interface{}); ok && len(resources) > 0 && (isRelationshipEntry(resources[0]) || isPathEntry(resources[0])) {
+						continue
+					}
+					metadataNamePath := strings.Join([]string{nodeId, "metadata.name"}, ".")
+					c.Items = append(c.Items, &ReturnItem{JsonPath: metadataNamePath, Alias: "name"})
+				}
 			}
 
 			for _, item := range c.Items {
@@ -458,7 +498,23 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 				if results.Data[nodeId] == nil {
 					results.Data[nodeId] = []interface{}{}
 				}
-				var aggregateResult interface{}
+				if item.Aggregate != "" {
+					// Grouped aggregations are computed per group once all items are returned
+					if len(groupItems) > 0 {
+						continue
+					}
+
+					aggregateResult, err := aggregateResources(item.Aggregate, item.Distinct, pathStr, resultMap[nodeId].([]map[string]interface{}))
+					if err != nil {
+						return *results, err
+					}
+
+					if results.Data["aggregate"] == nil {
+						results.Data["aggregate"] = make(map[string]interface{})
+					}
+					results.Data["aggregate"].(map[string]interface{})[aggregateKey(item)] = aggregateResult
+					continue
+				}
 
 				for idx, resource := range resultMap[nodeId].([]map[string]interface{}) {
 					// Ensure that the results.Data[nodeId] slice has enough elements to store the current resource.
@@ -469,163 +525,62 @@ func (q *QueryExecutor) ExecuteSingleQuery(ast *Expression, namespace string) (Q
 					}
 					currentMap := results.Data[nodeId].([]interface{})[idx].(map[string]interface{})
 
+					if item.Function != nil {
+						result, err := evaluateFunctionCall(item.Function, nodeId, resource)
+						if err != nil {
+							return *results, err
+						}
+						currentMap[returnItemKey(item)] = result
+						continue
+					}
+
 					result, err := jsonpath.JsonPathLookup(resource, pathStr)
 					if err != nil {
 						logDebug("Path not found:", item.JsonPath)
 						result = nil
 					}
 
-					switch strings.ToUpper(item.Aggregate) {
-					case "COUNT":
-						if aggregateResult == nil {
-							aggregateResult = 0
-						}
-						aggregateResult = aggregateResult.(int) + 1
-					case "SUM":
-						if result != nil {
-							if aggregateResult == nil {
-								aggregateResult = reflect.ValueOf(result).Interface()
-							} else {
-								v1 := reflect.ValueOf(aggregateResult)
-								v2 := reflect.ValueOf(result)
-								v1 = reflect.ValueOf(v1.Interface()).Convert(v1.Type())
-								if v1.Kind() == reflect.Ptr {
-									v1 = v1.Elem()
-								}
-								if v2.Kind() == reflect.Ptr {
-									v2 = v2.Elem()
-								}
-
-								isCPUResource := strings.Contains(pathStr, "resources.limits.cpu") || strings.Contains(pathStr, "resources.requests.cpu")
-								isMemoryResource := strings.Contains(pathStr, "resources.limits.memory") || strings.Contains(pathStr, "resources.requests.memory")
-
-								switch v1.Kind() {
-								case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-									aggregateResult = v1.Int() + v2.Int()
-								case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-									aggregateResult = v1.Uint() + v2.Uint()
-								case reflect.Float32, reflect.Float64:
-									aggregateResult = v1.Float() + v2.Float()
-								case reflect.String:
-									if isCPUResource {
-										v1Cpu, err := convertToMilliCPU(v1.String())
-										if err != nil {
-											return *results, fmt.Errorf("error processing cpu resources value: %v", err)
-										}
-										v2Cpu, err := convertToMilliCPU(v2.String())
-										if err != nil {
-											return *results, fmt.Errorf("error processing cpu resources value: %v", err)
-										}
-
-										aggregateResult = convertMilliCPUToStandard(v1Cpu + v2Cpu)
-									} else if isMemoryResource {
-										v1Mem, err := convertMemoryToBytes(v1.String())
-										if err != nil {
-											return *results, fmt.Errorf("error processing memory resources value: %v", err)
-										}
-										v2Mem, err := convertMemoryToBytes(v2.String())
-										if err != nil {
-											return *results, fmt.Errorf("error processing memory resources value: %v", err)
-										}
-
-										aggregateResult = convertBytesToMemory(v1Mem + v2Mem)
-									}
-								case reflect.Slice:
-									v1Strs, err := convertToStringSlice(v1)
-									if err != nil {
-										return *results, fmt.Errorf("error converting v1 to string slice: %v", err)
-									}
-
-									v2Strs, err := convertToStringSlice(v2)
-									if err != nil {
-										return *results, fmt.Errorf("error converting v2 to string slice: %v", err)
-									}
-
-									if isCPUResource {
-										v1CpuSum, err := sumMilliCPU(v1Strs)
-										if err != nil {
-											return *results, fmt.Errorf("error processing v1 cpu value: %v", err)
-										}
-
-										v2CpuSum, err := sumMilliCPU(v2Strs)
-										if err != nil {
-											return *results, fmt.Errorf("error processing v2 cpu value: %v", err)
-										}
-
-										aggregateResult = []string{convertMilliCPUToStandard(v1CpuSum + v2CpuSum)}
-									} else if isMemoryResource {
-										v1MemSum, err := sumMemoryBytes(v1Strs)
-										if err != nil {
-											return *results, fmt.Errorf("error processing v1 memory value: %v", err)
-										}
-
-										v2MemSum, err := sumMemoryBytes(v2Strs)
-										if err != nil {
-											return *results, fmt.Errorf("error processing v2 memory value: %v", err)
-										}
-
-										aggregateResult = []string{convertBytesToMemory(v1MemSum + v2MemSum)}
-									}
-								default:
-									// Handle unsupported types or error out
-									return *results, fmt.Errorf("unsupported type for SUM: %v", v1.Kind())
-								}
-							}
-						}
-					}
-
-					if item.Aggregate == "" {
-						key := item.Alias
-						if key == "" {
-							if len(pathParts) == 1 {
-								key = pathParts[0]
-							} else if len(pathParts) > 1 {
-								nestedMap := currentMap
-								for i := 0; i < len(pathParts)-1; i++ {
-									if _, exists := nestedMap[pathParts[i]]; !exists {
-										nestedMap[pathParts[i]] = make(map[string]interface{})
-									}
-									nestedMap = nestedMap[pathParts[i]].(map[string]interface{})
+					key := item.Alias
+					if key == "" {
+						if len(pathParts) == 1 {
+							key = pathParts[0]
+						} else if len(pathParts) > 1 {
+							nestedMap := currentMap
+							for i := 0; i < len(pathParts)-1; i++ {
+								if _, exists := nestedMap[pathParts[i]]; !exists {
+									nestedMap[pathParts[i]] = make(map[string]interface{})
 								}
-								nestedMap[pathParts[len(pathParts)-1]] = result
-								continue
-							} else {
-								key = "$"
+								nestedMap = nestedMap[pathParts[i]].(map[string]interface{})
 							}
+							nestedMap[pathParts[len(pathParts)-1]] = result
+							continue
+						} else {
+							key = "$"
 						}
-						currentMap[key] = result
 					}
+					currentMap[key] = result
 				}
-				if item.Aggregate != "" {
-					if results.Data["aggregate"] == nil {
-						results.Data["aggregate"] = make(map[string]interface{})
-					}
-					aggregateMap := results.Data["aggregate"].(map[string]interface{})
-
-					key := item.Alias
-					if key == "" {
-						key = strings.ToLower(item.Aggregate) + ":" + nodeId + "." + strings.Replace(pathStr, "$.", "", 1)
-					}
+			}
 
-					if slice, ok := aggregateResult.([]interface{}); ok && len(slice) == 0 {
-						aggregateResult = nil
-					} else if strSlice, ok := aggregateResult.([]string); ok && len(strSlice) == 1 {
-						aggregateResult = strSlice[0]
-					}
-					aggregateMap[key] = aggregateResult
+			if len(groupItems) > 0 {
+				if err := q.applyGrouping(c, groupItems, scopeNodes, scopeRelationships, scopePaths, scopeUnwinds, results); err != nil {
+					return *results, err
+				}
+				if err := orderGroups(c, groupItems, results); err != nil {
+					return *results, err
 				}
 			}
 
+			if err := q.buildRows(c, items, scopeNodes, scopeRelationships, scopePaths, scopeUnwinds, results, opts.Rows); err != nil {
+				return *results, err
+			}
+
 		default:
 			return *results, fmt.Errorf("unknown clause type: %T", c)
 		}
 	}
 	// build the graph
 	q.buildGraph(results)
-
-	// clear the result cache and result map
-	resultCache = make(map[string]interface{})
-	resultMap = make(map[string]interface{})
 	return *results, nil
 }
 
@@ -633,7 +588,6 @@ func (q *QueryExecutor) processRelationship(rel *Relationship, c *MatchClause, r
 	logDebug(fmt.Sprintf("Processing relationship: %+v\n", rel))
 
 	// Determine relationship type and fetch related resources
-	var relType RelationshipType
 	if rel.LeftNode.ResourceProperties.Kind == "" || rel.RightNode.ResourceProperties.Kind == "" {
 		// error out
 		return false, fmt.Errorf("must specify kind for all nodes in match clause")
@@ -647,63 +601,40 @@ func (q *QueryExecutor) processRelationship(rel *Relationship, c *MatchClause, r
 		return false, fmt.Errorf("error finding API resource >> %s", err)
 	}
 
-	if rightKind.Resource == "namespaces" || leftKind.Resource == "namespaces" {
-		relType = NamespaceHasResource
+	if rel.MaxHops > 0 {
+		return q.processVariableLengthRelationship(rel, c, results, filteredResults, leftKind.Resource, rightKind.Resource)
 	}
 
-	if relType == "" {
-		for _, resourceRelationship := range relationshipRules {
-			if (strings.EqualFold(leftKind.Resource, resourceRelationship.KindA) && strings.EqualFold(rightKind.Resource, resourceRelationship.KindB)) ||
-				(strings.EqualFold(rightKind.Resource, resourceRelationship.KindA) && strings.EqualFold(leftKind.Resource, resourceRelationship.KindB)) {
-				relType = resourceRelationship.Relationship
-			}
-		}
-	}
-
-	if relType == "" {
-		// no relationship type found, error out
-		return false, fmt.Errorf("relationship type not found between %s and %s", leftKind, rightKind)
-	}
-
-	rule, err := findRuleByRelationshipType(relType)
-	if err != nil {
-		return false, fmt.Errorf("error determining relationship type >> %s", err)
+	// A relationship against the direction of the rules between its kinds relates nothing
+	rules, err := findRulesBetweenKinds(leftKind.Resource, rightKind.Resource, rel.Direction, relationshipTypes(rel))
+	if err != nil && !errors.Is(err, errReversedRelationship) {
+		return false, err
 	}
 
 	// Fetch and process related resources
-	for _, node := range c.Nodes {
-		if node.ResourceProperties.Name == rel.LeftNode.ResourceProperties.Name || node.ResourceProperties.Name == rel.RightNode.ResourceProperties.Name {
-			if results.Data[node.ResourceProperties.Name] == nil {
-				err := getNodeResources(node, q, c.ExtraFilters)
-				if err != nil {
-					return false, err
-				}
-			}
-		}
+	if err := q.fetchRelationshipNodes(rel, c, results); err != nil {
+		return false, err
 	}
 
-	var resourcesA, resourcesB []map[string]interface{}
-	var filteredDirection Direction
-
 	resultMapMutex.RLock()
-	if rule.KindA == rightKind.Resource {
-		resourcesA = getResourcesFromMap(filteredResults, rel.RightNode.ResourceProperties.Name)
-		resourcesB = getResourcesFromMap(filteredResults, rel.LeftNode.ResourceProperties.Name)
-		filteredDirection = Left
-	} else if rule.KindA == leftKind.Resource {
-		resourcesA = getResourcesFromMap(filteredResults, rel.LeftNode.ResourceProperties.Name)
-		resourcesB = getResourcesFromMap(filteredResults, rel.RightNode.ResourceProperties.Name)
-		filteredDirection = Right
-	} else {
-		resultMapMutex.RUnlock()
-		return false, fmt.Errorf("relationship rule not found for %s and %s", rel.LeftNode.ResourceProperties.Kind, rel.RightNode.ResourceProperties.Kind)
-	}
+	resourcesLeft := getResourcesFromMap(filteredResults, rel.LeftNode.ResourceProperties.Name)
+	resourcesRight := getResourcesFromMap(filteredResults, rel.RightNode.ResourceProperties.Name)
 	resultMapMutex.RUnlock()
 
-	matchedResources := applyRelationshipRule(resourcesA, resourcesB, rule, filteredDirection)
+	matchedLeft, matchedRight := relateByRules(resourcesLeft, resourcesRight, leftKind.Resource, rightKind.Resource, rel.Direction, rules)
+
+	// Resources on the required side of an optional relationship are kept even when nothing is related to them
+	if rel.LeftNode.Optional != rel.RightNode.Optional {
+		if rel.RightNode.Optional {
+			matchedLeft = resourcesLeft
+		} else {
+			matchedRight = resourcesRight
+		}
+	}
+	matchedResources := map[string]interface{}{"left": matchedLeft, "right": matchedRight}
 
-	filteredA := len(matchedResources["right"].([]map[string]interface{})) < len(resourcesA)
-	filteredB := len(matchedResources["left"].([]map[string]interface{})) < len(resourcesB)
+	filteredA := len(matchedRight) < len(resourcesRight)
+	filteredB := len(matchedLeft) < len(resourcesLeft)
 
 	filteredResults[rel.RightNode.ResourceProperties.Name] = matchedResources["right"].([]map[string]interface{})
 	filteredResults[rel.LeftNode.ResourceProperties.Name] = matchedResources["left"].([]map[string]interface{})
@@ -766,15 +697,17 @@ func (q *QueryExecutor) processRelationship(rel *Relationship, c *MatchClause, r
 	for _, rightResource := range rightResources {
 		for _, leftResource := range leftResources {
 			// Check if these resources actually match according to the criteria
-			for _, criterion := range rule.MatchCriteria {
-				if matchByCriterion(rightResource, leftResource, criterion) || matchByCriterion(leftResource, rightResource, criterion) {
-					rightNodeId := fmt.Sprintf("%s/%s", rightResource["kind"].(string), rightResource["metadata"].(map[string]interface{})["name"].(string))
-					leftNodeId := fmt.Sprintf("%s/%s", leftResource["kind"].(string), leftResource["metadata"].(map[string]interface{})["name"].(string))
-					results.Graph.Edges = append(results.Graph.Edges, Edge{
-						From: rightNodeId,
-						To:   leftNodeId,
-						Type: string(relType),
-					})
+			for _, rule := range rules {
+				for _, criterion := range rule.MatchCriteria {
+					if matchByCriterion(rightResource, leftResource, criterion) || matchByCriterion(leftResource, rightResource, criterion) {
+						rightNodeId := fmt.Sprintf("%s/%s", rightResource["kind"].(string), rightResource["metadata"].(map[string]interface{})["name"].(string))
+						leftNodeId := fmt.Sprintf("%s/%s", leftResource["kind"].(string), leftResource["metadata"].(map[string]interface{})["name"].(string))
+						results.Graph.Edges = append(results.Graph.Edges, Edge{
+							From: rightNodeId,
+							To:   leftNodeId,
+							Type: string(rule.Relationship),
+						})
+					}
 				}
 			}
 		}
@@ -783,6 +716,62 @@ func (q *QueryExecutor) processRelationship(rel *Relationship, c *MatchClause, r
 	return filteredA || filteredB, nil
 }
 
+// processRelationshipVariables binds each named relationship of a match clause, e.g. -[r:ROUTE]->,
+// to the relationships between the resources matched by its nodes
+func (q *QueryExecutor) processRelationshipVariables(c *MatchClause) error {
+	for _, rel := range c.Relationships {
+		if rel.ResourceProperties == nil || rel.ResourceProperties.Name == "" || rel.MaxHops > 0 {
+			continue
+		}
+
+		leftKind, err := q.findGVR(rel.LeftNode.ResourceProperties.Kind)
+		if err != nil {
+			return fmt.Errorf("error finding API resource >> %s", err)
+		}
+		rightKind, err := q.findGVR(rel.RightNode.ResourceProperties.Kind)
+		if err != nil {
+			return fmt.Errorf("error finding API resource >> %s", err)
+		}
+		rules, err := findRulesBetweenKinds(leftKind.Resource, rightKind.Resource, rel.Direction, relationshipTypes(rel))
+		if err != nil && !errors.Is(err, errReversedRelationship) {
+			return err
+		}
+
+		resultMapMutex.Lock()
+		left, _ := resultMap[rel.LeftNode.ResourceProperties.Name].([]map[string]interface{})
+		right, _ := resultMap[rel.RightNode.ResourceProperties.Name].([]map[string]interface{})
+		entries := []map[string]interface{}{}
+		for _, leftResource := range left {
+			for _, rightResource := range right {
+				for _, rule := range rules {
+					if !ruleRelates(rule, leftKind.Resource, rightKind.Resource, rel.Direction, leftResource, rightResource) {
+						continue
+					}
+					entries = append(entries, directedRelationshipEntry(rule, leftResource, rightResource, rel.Direction))
+				}
+			}
+		}
+		resultMap[rel.ResourceProperties.Name] = entries
+		resultMapMutex.Unlock()
+	}
+	return nil
+}
+
+// fetchRelationshipNodes fetches the resources of both nodes of a relationship
+func (q *QueryExecutor) fetchRelationshipNodes(rel *Relationship, c *MatchClause, results *QueryResult) error {
+	for _, node := range c.Nodes {
+		if node.ResourceProperties.Name == rel.LeftNode.ResourceProperties.Name || node.ResourceProperties.Name == rel.RightNode.ResourceProperties.Name {
+			if results.Data[node.ResourceProperties.Name] == nil {
+				err := getNodeResources(node, q, c.ExtraFilters)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func getResourcesFromMap(filteredResults map[string][]map[string]interface{}, key string) []map[string]interface{} {
 	if filtered, ok := filteredResults[key]; ok {
 		return filtered
@@ -803,6 +792,14 @@ func (q *QueryExecutor) processNodes(c *MatchClause, results *QueryResult) error
 			return fmt.Errorf("must specify kind for all nodes in match clause")
 		}
 
+		// Nodes carried over by a WITH clause are already in the graph
+		if node.Bound {
+			if err := getNodeResources(node, q, c.ExtraFilters); err != nil {
+				return fmt.Errorf("error getting node resources >> %s", err)
+			}
+			continue
+		}
+
 		// check if the node has already been fetched
 		cacheKey, err := q.resourcePropertyName(node)
 		if err != nil {
@@ -926,6 +923,8 @@ func getTargetK8sResourceName(resourceTemplate map[string]interface{}, resourceN
 	return name
 }
 
+// resourcePropertyName returns the key a node's resources are cached under. Nodes of the same kind
+// are cached separately, as each has its own properties and filters.
 func (q *QueryExecutor) resourcePropertyName(n *NodePattern) (string, error) {
 	var ns string
 
@@ -935,7 +934,7 @@ func (q *QueryExecutor) resourcePropertyName(n *NodePattern) (string, error) {
 	}
 
 	if n.ResourceProperties.Properties == nil {
-		return fmt.Sprintf("%s_%s", Namespace, gvr.Resource), nil
+		return fmt.Sprintf("%s_%s_%s", Namespace, gvr.Resource, n.ResourceProperties.Name), nil
 	}
 
 	for _, prop := range n.ResourceProperties.Properties.PropertyList {
@@ -949,7 +948,7 @@ func (q *QueryExecutor) resourcePropertyName(n *NodePattern) (string, error) {
 		ns = Namespace
 	}
 
-	return fmt.Sprintf("%s_%s", ns, gvr.Resource), nil
+	return fmt.Sprintf("%s_%s_%s", ns, gvr.Resource, n.ResourceProperties.Name), nil
 }
 
 func convertToComparableTypes(result, filterValue interface{}) (interface{}, interface{}, error) {
@@ -958,9 +957,20 @@ func convertToComparableTypes(result, filterValue interface{}) (interface{}, int
 		return result, nil, nil
 	}
 
-	// If both are already the same type, return them as is
+	// Timestamps and Kubernetes quantities are compared by value
+	if resultTime, filterTime, ok := timestampsToFloat64(result, filterValue); ok {
+		return resultTime, filterTime, nil
+	}
+	if resultQuantity, filterQuantity, ok := quantitiesToFloat64(result, filterValue); ok {
+		return resultQuantity, filterQuantity, nil
+	}
+
+	// If both are already the same type, return them as is. Integers are compared as floats
+	// like other numbers, function calls may yield them.
 	if reflect.TypeOf(result) == reflect.TypeOf(filterValue) {
-		return result, filterValue, nil
+		if _, isInt := toInt64(result); !isInt {
+			return result, filterValue, nil
+		}
 	}
 
 	// Try to convert both to float64 for numeric comparisons
@@ -975,6 +985,24 @@ func convertToComparableTypes(result, filterValue interface{}) (interface{}, int
 	return fmt.Sprintf("%v", result), fmt.Sprintf("%v", filterValue), nil
 }
 
+// timestampsToFloat64 converts two RFC3339 timestamps to nanoseconds since the epoch
+func timestampsToFloat64(a, b interface{}) (float64, float64, bool) {
+	aStr, aIsStr := a.(string)
+	bStr, bIsStr := b.(string)
+	if !aIsStr || !bIsStr {
+		return 0, 0, false
+	}
+	aTime, err := time.Parse(time.RFC3339, aStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	bTime, err := time.Parse(time.RFC3339, bStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	return float64(aTime.UnixNano()), float64(bTime.UnixNano()), true
+}
+
 func toFloat64(v interface{}) (float64, error) {
 	switch v := v.(type) {
 	case float64:
@@ -994,28 +1022,131 @@ func toFloat64(v interface{}) (float64, error) {
 	}
 }
 
+// setKeyValuePairs patches the resources of the nodes referenced by the keys of a SET clause.
+// Values are evaluated per resource, relationships are those the referenced nodes were matched by.
+func (q *QueryExecutor) setKeyValuePairs(pairs []*KeyValuePair, relationships []*Relationship) error {
+	for _, kvp := range pairs {
+		resultMapKey, path := splitKeyPath(kvp.Key)
+		resources := resultMap[resultMapKey].([]map[string]interface{})
+		for _, resource := range resources {
+			value, err := q.evaluateValue(kvp.Value, resultMapKey, resource, relationships)
+			if err != nil {
+				return fmt.Errorf("error evaluating value of %s: %s", kvp.Key, err)
+			}
+
+			// Create a single patch that works with the existing structure
+			patches := createCompatiblePatch(path, value)
+
+			// Marshal the patches to JSON
+			patchJSON, err := json.Marshal(patches)
+			if err != nil {
+				return fmt.Errorf("error marshalling patches: %s", err)
+			}
+
+			// Apply the patches to the resource
+			err = q.PatchK8sResource(resource, patchJSON)
+			if err != nil {
+				return fmt.Errorf("error patching resource: %s", err)
+			}
+
+			// Update the resultMap
+			updateResultMap(resource, path, value)
+		}
+	}
+	return nil
+}
+
+// splitKeyPath splits the key of a SET item into the node it refers to and the path
+// of the field to set, taking escaped dots into account
+func splitKeyPath(key string) (string, []string) {
+	path := []string{}
+	parts := strings.Split(key, ".")
+	for i := 1; i < len(parts); i++ {
+		if i > 1 && strings.HasSuffix(parts[i-1], "\\") {
+			// Combine this part with the previous one, removing the backslash
+			path[len(path)-1] = path[len(path)-1][:len(path[len(path)-1])-1] + "." + parts[i]
+		} else {
+			path = append(path, parts[i])
+		}
+	}
+	return parts[0], path
+}
+
+// jsonPointer converts a field path to a JSON pointer, escaping the path segments and
+// turning array indices into segments of their own
+func jsonPointer(path []string) string {
+	var pointer strings.Builder
+	for _, part := range path {
+		part, indices := splitIndices(part)
+		part = strings.ReplaceAll(part, "~", "~0")
+		pointer.WriteString("/" + strings.ReplaceAll(part, "/", "~1"))
+		for _, index := range indices {
+			pointer.WriteString("/" + index)
+		}
+	}
+	return pointer.String()
+}
+
+// splitIndices splits the array indices off a path segment such as containers[0]
+func splitIndices(part string) (string, []string) {
+	var indices []string
+	for strings.HasSuffix(part, "]") && strings.Contains(part, "[") {
+		start := strings.LastIndex(part, "[")
+		indices = append([]string{part[start+1 : len(part)-1]}, indices...)
+		part = part[:start]
+	}
+	return part, indices
+}
+
 func createCompatiblePatch(path []string, value interface{}) []interface{} {
 	// Create a JSON Patch operation
 	patch := map[string]interface{}{
 		"op":    "replace",
-		"path":  "/" + strings.Join(path, "/"),
+		"path":  jsonPointer(path),
 		"value": value,
 	}
 
 	return []interface{}{patch}
 }
 
+// updateResultMap sets the field at the given path of a resource the way its patch does. Array
+// elements such as containers[0] are set in place, missing ones are left alone as patching them fails.
 func updateResultMap(resource map[string]interface{}, path []string, value interface{}) {
 	current := resource
-	for i, key := range path {
-		if i == len(path)-1 {
-			current[key] = value
-			return
+	for i, part := range path {
+		key, indices := splitIndices(part)
+		if len(indices) == 0 {
+			if i == len(path)-1 {
+				current[key] = value
+				return
+			}
+			if _, ok := current[key]; !ok {
+				current[key] = make(map[string]interface{})
+			}
+			current = current[key].(map[string]interface{})
+			continue
 		}
-		if _, ok := current[key]; !ok {
-			current[key] = make(map[string]interface{})
+
+		items, _ := current[key].([]interface{})
+		for j, index := range indices {
+			n, err := strconv.Atoi(index)
+			if err != nil || n < 0 || n >= len(items) {
+				return
+			}
+			switch {
+			case i == len(path)-1 && j == len(indices)-1:
+				items[n] = value
+				return
+			case j < len(indices)-1:
+				items, _ = items[n].([]interface{})
+			default:
+				element, ok := items[n].(map[string]interface{})
+				if !ok {
+					return
+				}
+				current = element
+			}
 		}
-		current = current[key].(map[string]interface{})
 	}
 }
 
@@ -1361,6 +1492,10 @@ func sumMemoryBytes(memStrs []string) (int64, error) {
 // }
 
 func ExecuteMultiContextQuery(ast *Expression, namespace string) (QueryResult, error) {
+	return executeMultiContextQuery(ast, namespace, ExecuteOptions{})
+}
+
+func executeMultiContextQuery(ast *Expression, namespace string, opts ExecuteOptions) (QueryResult, error) {
 	if len(ast.Contexts) == 0 {
 		return QueryResult{}, fmt.Errorf("no contexts provided for multi-context query")
 	}
@@ -1384,8 +1519,8 @@ func ExecuteMultiContextQuery(ast *Expression, namespace string) (QueryResult, e
 		// Create a modified AST with prefixed variables
 		modifiedAst := prefixVariables(ast, context)
 
-		// Use ExecuteSingleQuery instead of Execute
-		result, err := executor.ExecuteSingleQuery(modifiedAst, namespace)
+		// Execute the query in this context only, rather than in all of its contexts again
+		result, err := executor.executeSingleQuery(modifiedAst, namespace, opts)
 		if err != nil {
 			return combinedResults, fmt.Errorf("error executing query in context %s: %v", context, err)
 		}
@@ -1396,6 +1531,8 @@ func ExecuteMultiContextQuery(ast *Expression, namespace string) (QueryResult, e
 		}
 		combinedResults.Graph.Nodes = append(combinedResults.Graph.Nodes, result.Graph.Nodes...)
 		combinedResults.Graph.Edges = append(combinedResults.Graph.Edges, result.Graph.Edges...)
+		combinedResults.Rows = append(combinedResults.Rows, result.Rows...)
+		combinedResults.Warnings = append(combinedResults.Warnings, result.Warnings...)
 	}
 
 	return combinedResults, nil
@@ -1462,7 +1599,23 @@ func (q *QueryExecutor) Provider() provider.Provider {
 	return q.provider
 }
 
-func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValuePair) (err error) {
+// selectorValue formats the value of a node property for the label or field selector resources
+// are fetched by. Values are matched as a whole: strings holding selector syntax such as , = or !
+// would add terms of their own, and lists and maps can't be selected by, so they're rejected.
+func selectorValue(key string, value interface{}) (string, error) {
+	switch value.(type) {
+	case string, int, int64, float64, bool:
+	default:
+		return "", fmt.Errorf("can't select resources by %s %v: expected a string, number or boolean", key, value)
+	}
+	formatted := fmt.Sprintf("%v", value)
+	if strings.ContainsAny(formatted, ",=!()\\ \t\n") {
+		return "", fmt.Errorf("can't select resources by %s %q: selector values can't contain commas, operators or whitespace", key, formatted)
+	}
+	return formatted, nil
+}
+
+func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*Filter) (err error) {
 	namespace := Namespace
 
 	// Create a copy of ResourceProperties
@@ -1477,7 +1630,10 @@ func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValue
 	if resourcePropertiesCopy.Properties != nil && len(resourcePropertiesCopy.Properties.PropertyList) > 0 {
 		for i, prop := range resourcePropertiesCopy.Properties.PropertyList {
 			if prop.Key == "namespace" || prop.Key == "metadata.namespace" {
-				namespace = prop.Value.(string)
+				namespace, err = selectorValue(prop.Key, prop.Value)
+				if err != nil {
+					return err
+				}
 				// Remove the namespace slice from the properties
 				resourcePropertiesCopy.Properties.PropertyList = append(resourcePropertiesCopy.Properties.PropertyList[:i], resourcePropertiesCopy.Properties.PropertyList[i+1:]...)
 			}
@@ -1491,12 +1647,16 @@ func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValue
 
 	if resourcePropertiesCopy.Properties != nil {
 		for _, prop := range resourcePropertiesCopy.Properties.PropertyList {
+			value, err := selectorValue(prop.Key, prop.Value)
+			if err != nil {
+				return err
+			}
 			if prop.Key == "name" || prop.Key == "metadata.name" || prop.Key == `"name"` || prop.Key == `"metadata.name"` {
-				fieldSelector += fmt.Sprintf("metadata.name=%s,", prop.Value)
+				fieldSelector += fmt.Sprintf("metadata.name=%s,", value)
 				hasNameSelector = true
 			} else {
 				hasLabelSelector = true
-				labelSelector += fmt.Sprintf("%s=%s,", prop.Key, prop.Value)
+				labelSelector += fmt.Sprintf("%s=%s,", prop.Key, value)
 			}
 		}
 		fieldSelector = strings.TrimSuffix(fieldSelector, ",")
@@ -1512,123 +1672,47 @@ func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValue
 	if err != nil {
 		return fmt.Errorf("error getting resource property name: %v", err)
 	}
-	if resultCache[cacheKey] == nil {
-		// Get resources using the provider
-		resources, err := q.provider.GetK8sResources(n.ResourceProperties.Kind, fieldSelector, labelSelector, namespace)
-		if err != nil {
-			return fmt.Errorf("error getting resources: %v", err)
+	if resultCache[cacheKey] == nil || n.Bound {
+		var resourceList []map[string]interface{}
+		if n.Bound {
+			// Nodes carried over by a WITH clause are filtered from their current resources
+			resourceList, _ = resultMap[n.ResourceProperties.Name].([]map[string]interface{})
+		} else {
+			// Get resources using the provider
+			resources, err := q.provider.GetK8sResources(n.ResourceProperties.Kind, fieldSelector, labelSelector, namespace)
+			if err != nil {
+				return fmt.Errorf("error getting resources: %v", err)
+			}
+			resourceList = resources.([]map[string]interface{})
 		}
 
 		// Apply extra filters from WHERE clause
-		resourceList := resources.([]map[string]interface{})
 		var filtered []map[string]interface{}
 
+		// Pattern predicates are evaluated for all resources at once
+		patternMatches := make(map[*Filter]map[string]bool)
+		for _, filter := range extraFilters {
+			if err := q.matchPatterns(resourceList, filter, n, patternMatches); err != nil {
+				return err
+			}
+		}
+
 		// Process each resource
 		for _, resource := range resourceList {
 			keep := true
 			// Apply extra filters
 			for _, filter := range extraFilters {
-				// Extract node name from filter key
-				var resultMapKey string
-				dotIndex := strings.Index(filter.Key, ".")
-				if dotIndex != -1 {
-					resultMapKey = filter.Key[:dotIndex]
-				} else {
-					resultMapKey = filter.Key
+				nodeNames := getFilterNodeNames(filter)
+				if !slices.Contains(nodeNames, n.ResourceProperties.Name) {
+					continue
 				}
-
-				// Handle escaped dots
-				for strings.HasSuffix(resultMapKey, "\\") {
-					nextDotIndex := strings.Index(filter.Key[len(resultMapKey)+1:], ".")
-					if nextDotIndex == -1 {
-						resultMapKey = filter.Key
-						break
-					}
-					resultMapKey = filter.Key[:len(resultMapKey)+1+nextDotIndex]
+				if len(nodeNames) > 1 {
+					return fmt.Errorf("filter expressions may only reference a single node, got: %s", strings.Join(nodeNames, ", "))
 				}
 
-				if resultMapKey == n.ResourceProperties.Name {
-					// Transform path
-					path := filter.Key
-					path = strings.Replace(path, resultMapKey+".", "$.", 1)
-
-					// Get value using jsonpath
-					value, err := jsonpath.JsonPathLookup(resource, path)
-					if err != nil {
-						keep = false
-						break
-					}
-
-					// Convert and compare values
-					resourceValue, filterValue, err := convertToComparableTypes(value, filter.Value)
-					if err != nil {
-						keep = false
-						break
-					}
-
-					// Compare based on operator
-					switch filter.Operator {
-					case "EQUALS", "=", "==":
-						if resourceValue != filterValue {
-							keep = false
-						}
-					case "GREATER_THAN", ">":
-						if rv, ok := resourceValue.(float64); ok {
-							if fv, ok := filterValue.(float64); ok {
-								if rv <= fv {
-									keep = false
-								}
-							}
-						}
-					case "LESS_THAN", "<":
-						if rv, ok := resourceValue.(float64); ok {
-							if fv, ok := filterValue.(float64); ok {
-								if rv >= fv {
-									keep = false
-								}
-							}
-						}
-					case "GREATER_THAN_EQUALS", ">=":
-						if rv, ok := resourceValue.(float64); ok {
-							if fv, ok := filterValue.(float64); ok {
-								if rv < fv {
-									keep = false
-								}
-							}
-						}
-					case "LESS_THAN_EQUALS", "<=":
-						if rv, ok := resourceValue.(float64); ok {
-							if fv, ok := filterValue.(float64); ok {
-								if rv > fv {
-									keep = false
-								}
-							}
-						}
-					case "NOT_EQUALS", "!=":
-						if resourceValue == filterValue {
-							keep = false
-						}
-					case "CONTAINS":
-						strA := fmt.Sprintf("%v", resourceValue)
-						strB := fmt.Sprintf("%v", filterValue)
-						if !strings.Contains(strA, strB) {
-							keep = false
-						}
-					case "REGEX_COMPARE":
-						if filterValueStr, ok := filterValue.(string); ok {
-							if resultValueStr, ok := resourceValue.(string); ok {
-								if regex, err := regexp.Compile(filterValueStr); err == nil {
-									if !regex.MatchString(resultValueStr) {
-										keep = false
-									}
-								}
-							}
-						}
-					}
-
-					if !keep {
-						break
-					}
+				if !evaluateFilter(resource, filter, n.ResourceProperties.Name, patternMatches) {
+					keep = false
+					break
 				}
 			}
 
@@ -1638,7 +1722,9 @@ func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValue
 		}
 
 		// Cache the filtered results
-		resultCache[cacheKey] = filtered
+		if !n.Bound {
+			resultCache[cacheKey] = filtered
+		}
 		resultMap[n.ResourceProperties.Name] = filtered
 	} else {
 		resultMap[n.ResourceProperties.Name] = resultCache[cacheKey]
@@ -1647,6 +1733,29 @@ func getNodeResources(n *NodePattern, q *QueryExecutor, extraFilters []*KeyValue
 	return nil
 }
 
+// getFilterNodeName extracts the node name a filter key refers to, taking escaped dots into account
+func getFilterNodeName(key string) string {
+	var resultMapKey string
+	dotIndex := strings.Index(key, ".")
+	if dotIndex != -1 {
+		resultMapKey = key[:dotIndex]
+	} else {
+		resultMapKey = key
+	}
+
+	// Handle escaped dots
+	for strings.HasSuffix(resultMapKey, "\\") {
+		nextDotIndex := strings.Index(key[len(resultMapKey)+1:], ".")
+		if nextDotIndex == -1 {
+			resultMapKey = key
+			break
+		}
+		resultMapKey = key[:len(resultMapKey)+1+nextDotIndex]
+	}
+
+	return resultMapKey
+}
+
 func GetContextQueryExecutor(context string) (*QueryExecutor, error) {
 	executorsLock.RLock()
 	if executor, exists := contextExecutors[context]; exists {
@@ -1726,22 +1835,40 @@ func logDebug(v ...interface{}) {
 // Helper function to prefix variables in the AST
 func prefixVariables(ast *Expression, context string) *Expression {
 	modified := &Expression{
-		Clauses:  make([]Clause, len(ast.Clauses)),
+		Clauses:  prefixClauses(ast.Clauses, context),
 		Contexts: ast.Contexts,
+		UnionAll: ast.UnionAll,
 	}
+	for _, union := range ast.Unions {
+		modified.Unions = append(modified.Unions, &Expression{Clauses: prefixClauses(union.Clauses, context)})
+	}
+
+	return modified
+}
 
-	for i, clause := range ast.Clauses {
+// prefixClauses returns copies of the clauses of a query whose variables are prefixed with the context
+func prefixClauses(clauses []Clause, context string) []Clause {
+	modified := make([]Clause, len(clauses))
+	for i, clause := range clauses {
 		switch c := clause.(type) {
 		case *MatchClause:
-			modified.Clauses[i] = prefixMatchClause(c, context)
+			modified[i] = prefixMatchClause(c, context)
 		case *ReturnClause:
-			modified.Clauses[i] = prefixReturnClause(c, context)
+			modified[i] = prefixReturnClause(c, context)
+		case *WithClause:
+			modified[i] = prefixWithClause(c, context)
+		case *UnwindClause:
+			modified[i] = prefixUnwindClause(c, context)
 		case *SetClause:
-			modified.Clauses[i] = prefixSetClause(c, context)
+			modified[i] = prefixSetClause(c, context)
+		case *RemoveClause:
+			modified[i] = prefixRemoveClause(c, context)
 		case *DeleteClause:
-			modified.Clauses[i] = prefixDeleteClause(c, context)
+			modified[i] = prefixDeleteClause(c, context)
 		case *CreateClause:
-			modified.Clauses[i] = prefixCreateClause(c, context)
+			modified[i] = prefixCreateClause(c, context)
+		case *MergeClause:
+			modified[i] = prefixMergeClause(c, context)
 		}
 	}
 
@@ -1753,7 +1880,7 @@ func prefixMatchClause(c *MatchClause, context string) *MatchClause {
 	modified := &MatchClause{
 		Nodes:         make([]*NodePattern, len(c.Nodes)),
 		Relationships: make([]*Relationship, len(c.Relationships)),
-		ExtraFilters:  make([]*KeyValuePair, len(c.ExtraFilters)),
+		ExtraFilters:  make([]*Filter, len(c.ExtraFilters)),
 	}
 
 	// Prefix node names
@@ -1765,13 +1892,20 @@ func prefixMatchClause(c *MatchClause, context string) *MatchClause {
 				Properties: node.ResourceProperties.Properties,
 				JsonData:   node.ResourceProperties.JsonData,
 			},
+			Optional: node.Optional,
+			Bound:    node.Bound,
 		}
 	}
 
 	// Prefix relationships
+	relationships := make(map[*Relationship]*Relationship)
 	for i, rel := range c.Relationships {
+		relProperties := rel.ResourceProperties
+		if relProperties != nil && relProperties.Name != "" {
+			relProperties = &ResourceProperties{Name: context + "_" + relProperties.Name, Kind: relProperties.Kind, Properties: relProperties.Properties}
+		}
 		modified.Relationships[i] = &Relationship{
-			ResourceProperties: rel.ResourceProperties,
+			ResourceProperties: relProperties,
 			Direction:          rel.Direction,
 			LeftNode: &NodePattern{
 				ResourceProperties: &ResourceProperties{
@@ -1780,6 +1914,8 @@ func prefixMatchClause(c *MatchClause, context string) *MatchClause {
 					Properties: rel.LeftNode.ResourceProperties.Properties,
 					JsonData:   rel.LeftNode.ResourceProperties.JsonData,
 				},
+				Optional: rel.LeftNode.Optional,
+				Bound:    rel.LeftNode.Bound,
 			},
 			RightNode: &NodePattern{
 				ResourceProperties: &ResourceProperties{
@@ -1788,23 +1924,90 @@ func prefixMatchClause(c *MatchClause, context string) *MatchClause {
 					Properties: rel.RightNode.ResourceProperties.Properties,
 					JsonData:   rel.RightNode.ResourceProperties.JsonData,
 				},
+				Optional: rel.RightNode.Optional,
+				Bound:    rel.RightNode.Bound,
 			},
+			MinHops: rel.MinHops,
+			MaxHops: rel.MaxHops,
 		}
+		relationships[rel] = modified.Relationships[i]
+	}
+
+	// Prefix paths
+	for _, path := range c.Paths {
+		prefixed := &PathPattern{Name: context + "_" + path.Name}
+		for _, name := range path.Nodes {
+			prefixed.Nodes = append(prefixed.Nodes, context+"_"+name)
+		}
+		for _, rel := range path.Relationships {
+			prefixed.Relationships = append(prefixed.Relationships, relationships[rel])
+		}
+		modified.Paths = append(modified.Paths, prefixed)
 	}
 
 	// Prefix filter variables
 	for i, filter := range c.ExtraFilters {
-		parts := strings.Split(filter.Key, ".")
+		modified.ExtraFilters[i] = prefixFilter(filter, context)
+	}
+
+	return modified
+}
+
+func prefixFilter(f *Filter, context string) *Filter {
+	modified := &Filter{Type: f.Type}
+
+	if f.KeyValuePair != nil {
+		parts := strings.Split(f.KeyValuePair.Key, ".")
 		if len(parts) > 0 {
 			parts[0] = context + "_" + parts[0]
 		}
-		modified.ExtraFilters[i] = &KeyValuePair{
+		modified.KeyValuePair = &KeyValuePair{
 			Key:      strings.Join(parts, "."),
-			Value:    filter.Value,
-			Operator: filter.Operator,
+			Value:    prefixValue(f.KeyValuePair.Value, context),
+			Operator: f.KeyValuePair.Operator,
+			Function: prefixFunctionCall(f.KeyValuePair.Function, context),
+		}
+	}
+
+	if f.Pattern != nil {
+		modified.Pattern = &NodeRelationshipList{}
+		prefixed := make(map[*NodePattern]*NodePattern)
+		for _, node := range f.Pattern.Nodes {
+			name := node.ResourceProperties.Name
+			if name != "" {
+				name = context + "_" + name
+			}
+			prefixed[node] = &NodePattern{
+				ResourceProperties: &ResourceProperties{
+					Name:       name,
+					Kind:       node.ResourceProperties.Kind,
+					Properties: node.ResourceProperties.Properties,
+				},
+			}
+			modified.Pattern.Nodes = append(modified.Pattern.Nodes, prefixed[node])
+		}
+		for _, rel := range f.Pattern.Relationships {
+			modified.Pattern.Relationships = append(modified.Pattern.Relationships, &Relationship{
+				ResourceProperties: rel.ResourceProperties,
+				Direction:          rel.Direction,
+				LeftNode:           prefixed[rel.LeftNode],
+				RightNode:          prefixed[rel.RightNode],
+				MinHops:            rel.MinHops,
+				MaxHops:            rel.MaxHops,
+			})
 		}
 	}
 
+	if f.Type == AnyFilter {
+		// The list variable is prefixed like a node, so that the prefixed operand refers to it
+		modified.Variable = context + "_" + f.Variable
+		modified.ListPath = context + "_" + f.ListPath
+	}
+
+	for _, operand := range f.Operands {
+		modified.Operands = append(modified.Operands, prefixFilter(operand, context))
+	}
+
 	return modified
 }
 
@@ -1812,7 +2015,10 @@ func prefixMatchClause(c *MatchClause, context string) *MatchClause {
 
 func prefixReturnClause(c *ReturnClause, context string) *ReturnClause {
 	modified := &ReturnClause{
-		Items: make([]*ReturnItem, len(c.Items)),
+		Items:    make([]*ReturnItem, len(c.Items)),
+		Distinct: c.Distinct,
+		Skip:     c.Skip,
+		Limit:    c.Limit,
 	}
 
 	for i, item := range c.Items {
@@ -1826,9 +2032,64 @@ func prefixReturnClause(c *ReturnClause, context string) *ReturnClause {
 			JsonPath:  strings.Join(parts, "."),
 			Alias:     item.Alias,
 			Aggregate: item.Aggregate,
+			Distinct:  item.Distinct,
+			Function:  prefixFunctionCall(item.Function, context),
+		}
+	}
+
+	for _, order := range c.OrderBy {
+		modified.OrderBy = append(modified.OrderBy, &OrderItem{
+			JsonPath:   context + "_" + order.JsonPath,
+			Descending: order.Descending,
+			Function:   prefixFunctionCall(order.Function, context),
+		})
+	}
+
+	return modified
+}
+
+func prefixWithClause(c *WithClause, context string) *WithClause {
+	modified := &WithClause{
+		Items:   make([]*ReturnItem, len(c.Items)),
+		Filters: make([]*Filter, len(c.Filters)),
+	}
+
+	for i, item := range c.Items {
+		parts := strings.Split(item.JsonPath, ".")
+		parts[0] = context + "_" + parts[0]
+
+		// Aliases are referenced by the WITH clause's filters, so they're prefixed as well
+		alias := item.Alias
+		if alias != "" {
+			alias = context + "_" + alias
+		}
+
+		modified.Items[i] = &ReturnItem{
+			JsonPath:  strings.Join(parts, "."),
+			Alias:     alias,
+			Aggregate: item.Aggregate,
+			Distinct:  item.Distinct,
 		}
 	}
 
+	for i, filter := range c.Filters {
+		modified.Filters[i] = prefixFilter(filter, context)
+	}
+
+	return modified
+}
+
+func prefixUnwindClause(c *UnwindClause, context string) *UnwindClause {
+	modified := &UnwindClause{
+		JsonPath: context + "_" + c.JsonPath,
+		Alias:    context + "_" + c.Alias,
+		Filters:  make([]*Filter, len(c.Filters)),
+	}
+
+	for i, filter := range c.Filters {
+		modified.Filters[i] = prefixFilter(filter, context)
+	}
+
 	return modified
 }
 
@@ -1846,7 +2107,7 @@ func prefixSetClause(c *SetClause, context string) *SetClause {
 
 		modified.KeyValuePairs[i] = &KeyValuePair{
 			Key:      strings.Join(parts, "."),
-			Value:    kvp.Value,
+			Value:    prefixValue(kvp.Value, context),
 			Operator: kvp.Operator,
 		}
 	}
@@ -1854,9 +2115,66 @@ func prefixSetClause(c *SetClause, context string) *SetClause {
 	return modified
 }
 
+// prefixValue prefixes the variable names of the field references in a SET value
+func prefixValue(value interface{}, context string) interface{} {
+	switch v := value.(type) {
+	case *FieldReference:
+		return &FieldReference{JsonPath: context + "_" + v.JsonPath}
+	case *ArithmeticExpression:
+		return &ArithmeticExpression{
+			Operator: v.Operator,
+			Left:     prefixValue(v.Left, context),
+			Right:    prefixValue(v.Right, context),
+		}
+	case *FunctionCall:
+		return prefixFunctionCall(v, context)
+	default:
+		return value
+	}
+}
+
+// prefixFunctionCall prefixes the variable names of the field references in a function call's arguments
+func prefixFunctionCall(f *FunctionCall, context string) *FunctionCall {
+	if f == nil {
+		return nil
+	}
+	modified := &FunctionCall{Name: f.Name, Args: make([]interface{}, len(f.Args))}
+	for i, arg := range f.Args {
+		modified.Args[i] = prefixValue(arg, context)
+	}
+	return modified
+}
+
+func prefixRemoveClause(c *RemoveClause, context string) *RemoveClause {
+	modified := &RemoveClause{
+		JsonPaths: make([]string, len(c.JsonPaths)),
+	}
+
+	for i, jsonPath := range c.JsonPaths {
+		modified.JsonPaths[i] = context + "_" + jsonPath
+	}
+
+	return modified
+}
+
+func prefixMergeClause(c *MergeClause, context string) *MergeClause {
+	return &MergeClause{
+		Node: &NodePattern{
+			ResourceProperties: &ResourceProperties{
+				Name:       context + "_" + c.Node.ResourceProperties.Name,
+				Kind:       c.Node.ResourceProperties.Kind,
+				Properties: c.Node.ResourceProperties.Properties,
+			},
+		},
+		OnCreate: prefixSetClause(&SetClause{KeyValuePairs: c.OnCreate}, context).KeyValuePairs,
+		OnMatch:  prefixSetClause(&SetClause{KeyValuePairs: c.OnMatch}, context).KeyValuePairs,
+	}
+}
+
 func prefixDeleteClause(c *DeleteClause, context string) *DeleteClause {
 	modified := &DeleteClause{
 		NodeIds: make([]string, len(c.NodeIds)),
+		Detach:  c.Detach,
 	}
 
 	for i, nodeId := range c.NodeIds {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/AvitalTamir/jsonpath"
@@ -481,3 +482,65 @@ func TestSumMemoryBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestJsonPointer(t *testing.T) {
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{path: []string{"spec", "replicas"}, want: "/spec/replicas"},
+		{path: []string{"metadata", "labels", "app.kubernetes.io/name"}, want: "/metadata/labels/app.kubernetes.io~1name"},
+		{path: []string{"metadata", "annotations", "a~b"}, want: "/metadata/annotations/a~0b"},
+		{path: []string{"spec", "containers[0]", "ports[1]", "name"}, want: "/spec/containers/0/ports/1/name"},
+	}
+
+	for _, tt := range tests {
+		if got := jsonPointer(tt.path); got != tt.want {
+			t.Errorf("jsonPointer(%v) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "string", value: "nginx", want: "nginx"},
+		{name: "number", value: 7, want: "7"},
+		{name: "boolean", value: true, want: "true"},
+		{name: "extra term", value: "web,tier=admin", wantErr: true},
+		{name: "operator", value: "web!", wantErr: true},
+		{name: "set", value: "in (a)", wantErr: true},
+		{name: "list", value: []interface{}{"web"}, wantErr: true},
+		{name: "map", value: map[string]interface{}{"app": "web"}, wantErr: true},
+		{name: "null", value: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectorValue("app", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectorValue(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectorValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	// Parameters are bound as values, they can't add selector terms of their own
+	ast, err := ParseQueryWithParams("MATCH (p:Pod {app: $app}) RETURN p", map[string]interface{}{"app": "web,tier=admin"})
+	if err != nil {
+		t.Fatalf("ParseQueryWithParams() error = %v", err)
+	}
+	executor, err := NewQueryExecutor(&mockProvider{})
+	if err != nil {
+		t.Fatalf("NewQueryExecutor() error = %v", err)
+	}
+	if _, err := executor.Execute(ast, "default"); err == nil || !strings.Contains(err.Error(), "can't select resources by app") {
+		t.Errorf("Execute() error = %v, want a rejected selector value", err)
+	}
+}
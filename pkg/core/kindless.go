@@ -0,0 +1,310 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// processMatchClause matches the nodes and relationships of a match clause.
+// Kind-less nodes are matched with each of the kinds they can be related to in turn, and the
+// resources matched with any of the kinds are combined.
+func (q *QueryExecutor) processMatchClause(c *MatchClause, results *QueryResult) error {
+	variants, err := q.expandKindlessNodes(c)
+	if err != nil {
+		return err
+	}
+	if len(variants) == 1 {
+		return q.processMatchVariant(variants[0], results)
+	}
+
+	var names []string
+	for _, node := range c.Nodes {
+		names = append(names, node.ResourceProperties.Name)
+	}
+	for _, rel := range c.Relationships {
+		if rel.ResourceProperties != nil && rel.ResourceProperties.Name != "" {
+			names = append(names, rel.ResourceProperties.Name)
+		}
+	}
+	for _, path := range c.Paths {
+		names = append(names, path.Name)
+	}
+
+	// Each variant is matched from the resources the clause started with
+	initial := make(map[string]interface{})
+	for _, name := range names {
+		if resources, ok := resultMap[name]; ok {
+			initial[name] = resources
+		}
+	}
+
+	matched := make(map[string][]map[string]interface{})
+	seen := make(map[string]map[string]bool)
+	for _, variant := range variants {
+		for _, name := range names {
+			if resources, ok := initial[name]; ok {
+				resultMap[name] = resources
+			} else {
+				delete(resultMap, name)
+			}
+		}
+
+		if err := q.processMatchVariant(variant, results); err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if seen[name] == nil {
+				seen[name] = make(map[string]bool)
+				matched[name] = []map[string]interface{}{}
+			}
+			resources, _ := resultMap[name].([]map[string]interface{})
+			for _, resource := range resources {
+				key := fmt.Sprintf("%v/%s", resource["kind"], resourceKey(resource))
+				if isRelationshipEntry(resource) {
+					key = fmt.Sprintf("%v/%v/%v", resource["type"], resource["start"], resource["end"])
+				} else if isPathEntry(resource) {
+					key = fmt.Sprintf("%v", resource)
+				}
+				if !seen[name][key] {
+					seen[name][key] = true
+					matched[name] = append(matched[name], resource)
+				}
+			}
+		}
+	}
+
+	for name, resources := range matched {
+		resultMap[name] = resources
+	}
+	return nil
+}
+
+// processMatchVariant matches the relationships of a match clause whose nodes all have a kind,
+// then its nodes, and binds its named relationships and paths
+func (q *QueryExecutor) processMatchVariant(c *MatchClause, results *QueryResult) error {
+	var filteringOccurred bool
+	filteredResults := make(map[string][]map[string]interface{})
+
+	for i := 0; i < len(c.Relationships)*2; i++ {
+		filteringOccurred = false
+		for _, rel := range c.Relationships {
+			filtered, err := q.processRelationship(rel, c, results, filteredResults)
+			if err != nil {
+				return err
+			}
+			filteringOccurred = filteringOccurred || filtered
+		}
+		if !filteringOccurred {
+			break
+		}
+		// Update resultMap with filtered results for the next pass
+		for k, v := range filteredResults {
+			resultMap[k] = v
+		}
+	}
+
+	// Process nodes
+	if err := q.processNodes(c, results); err != nil {
+		return err
+	}
+	if err := q.processRelationshipVariables(c); err != nil {
+		return err
+	}
+	return q.processPathVariables(c)
+}
+
+// expandKindlessNodes returns the variants of a match clause in which every node has a kind.
+// A node that references a node of the clause by name takes its kind, and a kind-less node
+// takes each of the kinds the relationship rules relate to the kinds of its neighbours.
+func (q *QueryExecutor) expandKindlessNodes(c *MatchClause) ([]*MatchClause, error) {
+	kinds := make(map[string]string)
+	complete := true
+	for _, node := range c.Nodes {
+		name, kind := node.ResourceProperties.Name, node.ResourceProperties.Kind
+		if kind == "" {
+			complete = false
+		}
+		if kinds[name] == "" {
+			kinds[name] = kind
+		}
+	}
+	if complete {
+		return []*MatchClause{c}, nil
+	}
+
+	for _, rel := range c.Relationships {
+		if rel.MaxHops > 0 && (kinds[rel.LeftNode.ResourceProperties.Name] == "" || kinds[rel.RightNode.ResourceProperties.Name] == "") {
+			return nil, fmt.Errorf("variable-length relationships must connect nodes with a kind")
+		}
+	}
+
+	assignments, err := q.assignNodeKinds(c, kinds)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		var kindless []string
+		for name, kind := range kinds {
+			if kind == "" {
+				kindless = append(kindless, name)
+			}
+		}
+		slices.Sort(kindless)
+		return nil, fmt.Errorf("no relationship rules relate kind-less nodes %s to the nodes of the pattern", strings.Join(kindless, ", "))
+	}
+
+	variants := make([]*MatchClause, len(assignments))
+	for i, assignment := range assignments {
+		variants[i] = withNodeKinds(c, assignment)
+	}
+	return variants, nil
+}
+
+// assignNodeKinds assigns each kind-less node, related to a node with a kind, every kind it can
+// be related to, and returns the resulting assignments of kinds to all nodes
+func (q *QueryExecutor) assignNodeKinds(c *MatchClause, kinds map[string]string) ([]map[string]string, error) {
+	for _, rel := range c.Relationships {
+		left, right := rel.LeftNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Name
+		for _, node := range []string{left, right} {
+			neighbour := left
+			if node == left {
+				neighbour = right
+			}
+			if kinds[node] != "" || kinds[neighbour] == "" {
+				continue
+			}
+
+			candidates, err := q.candidateKinds(c, node, kinds)
+			if err != nil {
+				return nil, err
+			}
+			var assignments []map[string]string
+			for _, kind := range candidates {
+				next := maps.Clone(kinds)
+				next[node] = kind
+				assigned, err := q.assignNodeKinds(c, next)
+				if err != nil {
+					return nil, err
+				}
+				assignments = append(assignments, assigned...)
+			}
+			return assignments, nil
+		}
+	}
+
+	for _, node := range c.Nodes {
+		if kinds[node.ResourceProperties.Name] == "" {
+			return nil, fmt.Errorf("must specify kind for node %s, or relate it to a node with a kind", node.ResourceProperties.Name)
+		}
+	}
+	return []map[string]string{kinds}, nil
+}
+
+// candidateKinds returns the resource kinds that the relationships of a kind-less node relate to
+// the kinds of all its neighbours that have a kind, and that are available in the cluster
+func (q *QueryExecutor) candidateKinds(c *MatchClause, node string, kinds map[string]string) ([]string, error) {
+	var candidates []string
+	first := true
+	for _, rel := range c.Relationships {
+		var neighbour string
+		switch node {
+		case rel.LeftNode.ResourceProperties.Name:
+			neighbour = rel.RightNode.ResourceProperties.Name
+		case rel.RightNode.ResourceProperties.Name:
+			neighbour = rel.LeftNode.ResourceProperties.Name
+		default:
+			continue
+		}
+		if kinds[neighbour] == "" {
+			continue
+		}
+
+		gvr, err := q.findGVR(kinds[neighbour])
+		if err != nil {
+			return nil, fmt.Errorf("error finding API resource >> %s", err)
+		}
+		related := relatedKinds(gvr.Resource, directionFrom(rel, neighbour), relationshipTypes(rel))
+		if first {
+			candidates, first = related, false
+			continue
+		}
+		candidates = slices.DeleteFunc(candidates, func(kind string) bool {
+			return !slices.Contains(related, kind)
+		})
+	}
+
+	var available []string
+	for _, kind := range candidates {
+		if _, err := q.findGVR(kind); err == nil {
+			available = append(available, kind)
+		}
+	}
+	return available, nil
+}
+
+// relatedKinds returns the sorted resource kinds that the relationship rules of the given types
+// relate to a kind in the given direction
+func relatedKinds(kind string, direction Direction, types []RelationshipType) []string {
+	var related []string
+	for _, rule := range relationshipRules {
+		// The namespace rule relates every kind
+		if rule.KindA == "*" || rule.KindB == "*" || !ruleHasType(rule, types) {
+			continue
+		}
+
+		for _, fromKindA := range []bool{true, false} {
+			ruleKind, neighbour := rule.KindA, rule.KindB
+			if !fromKindA {
+				ruleKind, neighbour = rule.KindB, rule.KindA
+			}
+			if strings.EqualFold(ruleKind, kind) && ruleFollows(rule, fromKindA, direction) && !slices.Contains(related, neighbour) {
+				related = append(related, neighbour)
+			}
+		}
+	}
+	slices.Sort(related)
+	return related
+}
+
+// withNodeKinds returns a copy of a match clause whose kind-less nodes have the given kinds
+func withNodeKinds(c *MatchClause, kinds map[string]string) *MatchClause {
+	copies := make(map[*NodePattern]*NodePattern)
+	withKind := func(node *NodePattern) *NodePattern {
+		if node.ResourceProperties.Kind != "" {
+			return node
+		}
+		if copied, ok := copies[node]; ok {
+			return copied
+		}
+		properties := *node.ResourceProperties
+		properties.Kind = kinds[properties.Name]
+		copied := *node
+		copied.ResourceProperties = &properties
+		copies[node] = &copied
+		return &copied
+	}
+
+	variant := &MatchClause{ExtraFilters: c.ExtraFilters}
+	for _, node := range c.Nodes {
+		variant.Nodes = append(variant.Nodes, withKind(node))
+	}
+	relationships := make(map[*Relationship]*Relationship)
+	for _, rel := range c.Relationships {
+		copied := *rel
+		copied.LeftNode = withKind(rel.LeftNode)
+		copied.RightNode = withKind(rel.RightNode)
+		relationships[rel] = &copied
+		variant.Relationships = append(variant.Relationships, &copied)
+	}
+	for _, path := range c.Paths {
+		copied := &PathPattern{Name: path.Name, Nodes: path.Nodes}
+		for _, rel := range path.Relationships {
+			copied.Relationships = append(copied.Relationships, relationships[rel])
+		}
+		variant.Paths = append(variant.Paths, copied)
+	}
+	return variant
+}
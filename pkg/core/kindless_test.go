@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExecuteKindlessNodes(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "api", matchingLabels(map[string]interface{}{"app": "api"})),
+			mockResource("Deployment", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+		},
+		"replicasets": {
+			mockResource("ReplicaSet", "api-7d9f", ownedBy("api")),
+		},
+		"horizontalpodautoscalers": {
+			mockResource("HorizontalPodAutoscaler", "api", map[string]interface{}{
+				"spec": map[string]interface{}{"scaleTargetRef": map[string]interface{}{"name": "api"}},
+			}),
+		},
+		"services": {
+			mockResource("Service", "api", selecting(map[string]interface{}{"app": "api"})),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		node     string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "kind-less node matches every related kind",
+			query:    `MATCH (d:Deployment {name: "api"})--(x) RETURN x.kind`,
+			node:     "x",
+			expected: []string{"HorizontalPodAutoscaler/api", "ReplicaSet/api-7d9f", "Service/api"},
+		},
+		{
+			name:     "kind-less node follows the direction of its relationship",
+			query:    `MATCH (d:Deployment {name: "api"})<-(x) RETURN x.kind`,
+			node:     "x",
+			expected: []string{"HorizontalPodAutoscaler/api", "Service/api"},
+		},
+		{
+			name:     "kind-less node filters its neighbour",
+			query:    `MATCH (d:Deployment)->(x) RETURN d.kind`,
+			node:     "d",
+			expected: []string{"Deployment/api"},
+		},
+		{
+			name:     "kind-less node with a condition",
+			query:    `MATCH (d:Deployment)->(x) WHERE x.metadata.name STARTS WITH "api-" RETURN x.kind`,
+			node:     "x",
+			expected: []string{"ReplicaSet/api-7d9f"},
+		},
+		{
+			name:     "anonymous node",
+			query:    `MATCH (d:Deployment)<-(:HorizontalPodAutoscaler) RETURN d.kind`,
+			node:     "d",
+			expected: []string{"Deployment/api"},
+		},
+		{
+			name:     "node referenced again by name",
+			query:    `MATCH (s:Service)->(d:Deployment), (d)->(r:ReplicaSet) RETURN d.kind`,
+			node:     "d",
+			expected: []string{"Deployment/api"},
+		},
+		{
+			name:    "kind-less node without relationships",
+			query:   `MATCH (x) RETURN x`,
+			wantErr: "must specify kind for node x",
+		},
+		{
+			name:    "kind-less node of a variable-length relationship",
+			query:   `MATCH (d:Deployment)-[*1..2]->(x) RETURN x`,
+			wantErr: "variable-length relationships must connect nodes with a kind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []string
+			for _, item := range result.Data[tt.node].([]interface{}) {
+				item := item.(map[string]interface{})
+				got = append(got, fmt.Sprintf("%v/%v", item["kind"], item["name"]))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"strings"
 	"text/scanner"
+	"unicode"
 )
 
 type Lexer struct {
@@ -14,6 +15,7 @@ type Lexer struct {
 	}
 	inContexts  bool
 	inNodeLabel bool
+	prev        TokenType
 }
 
 func NewLexer(input string) *Lexer {
@@ -26,6 +28,12 @@ func NewLexer(input string) *Lexer {
 
 // NextToken returns the next token in the input
 func (l *Lexer) NextToken() Token {
+	tok := l.nextToken()
+	l.prev = tok.Type
+	return tok
+}
+
+func (l *Lexer) nextToken() Token {
 	// If we have a buffered token, return it
 	if l.buf.hasNext {
 		l.buf.hasNext = false
@@ -49,7 +57,8 @@ func (l *Lexer) NextToken() Token {
 
 	case scanner.Ident:
 		lit := l.s.TokenText()
-		if !l.inNodeLabel {
+		// Keywords are only recognized outside of node labels and JSONPath segments
+		if !l.inNodeLabel && l.prev != DOT {
 			switch strings.ToUpper(lit) {
 			case "MATCH":
 				return Token{Type: MATCH, Literal: lit}
@@ -67,10 +76,66 @@ func (l *Lexer) NextToken() Token {
 				return Token{Type: IN, Literal: lit}
 			case "AS":
 				return Token{Type: AS, Literal: lit}
+			case "AND":
+				return Token{Type: AND, Literal: lit}
+			case "OR":
+				return Token{Type: OR, Literal: lit}
+			case "NOT":
+				return Token{Type: NOT, Literal: lit}
 			case "COUNT":
 				return Token{Type: COUNT, Literal: lit}
 			case "SUM":
 				return Token{Type: SUM, Literal: lit}
+			case "AVG":
+				return Token{Type: AVG, Literal: lit}
+			case "MIN":
+				return Token{Type: MIN, Literal: lit}
+			case "MAX":
+				return Token{Type: MAX, Literal: lit}
+			case "COLLECT":
+				return Token{Type: COLLECT, Literal: lit}
+			case "ORDER":
+				return Token{Type: ORDER, Literal: lit}
+			case "BY":
+				return Token{Type: BY, Literal: lit}
+			case "ASC":
+				return Token{Type: ASC, Literal: lit}
+			case "DESC":
+				return Token{Type: DESC, Literal: lit}
+			case "SKIP":
+				return Token{Type: SKIP, Literal: lit}
+			case "LIMIT":
+				return Token{Type: LIMIT, Literal: lit}
+			case "OPTIONAL":
+				return Token{Type: OPTIONAL, Literal: lit}
+			case "WITH":
+				return Token{Type: WITH, Literal: lit}
+			case "MERGE":
+				return Token{Type: MERGE, Literal: lit}
+			case "ON":
+				return Token{Type: ON, Literal: lit}
+			case "REMOVE":
+				return Token{Type: REMOVE, Literal: lit}
+			case "DETACH":
+				return Token{Type: DETACH, Literal: lit}
+			case "IS":
+				return Token{Type: IS, Literal: lit}
+			case "EXISTS":
+				return Token{Type: EXISTS, Literal: lit}
+			case "STARTS":
+				return Token{Type: STARTS, Literal: lit}
+			case "ENDS":
+				return Token{Type: ENDS, Literal: lit}
+			case "ANY":
+				return Token{Type: ANY, Literal: lit}
+			case "UNION":
+				return Token{Type: UNION, Literal: lit}
+			case "ALL":
+				return Token{Type: ALL, Literal: lit}
+			case "DISTINCT":
+				return Token{Type: DISTINCT, Literal: lit}
+			case "UNWIND":
+				return Token{Type: UNWIND, Literal: lit}
 			case "CONTAINS":
 				return Token{Type: CONTAINS, Literal: lit}
 			case "TRUE", "FALSE":
@@ -83,6 +148,9 @@ func (l *Lexer) NextToken() Token {
 		}
 		var fullLit strings.Builder
 		fullLit.WriteString(lit)
+		if !l.scanEscapedSegment(&fullLit) {
+			return Token{Type: ILLEGAL, Literal: fullLit.String()}
+		}
 
 		for l.s.Peek() == '.' {
 			l.s.Next() // consume dot
@@ -93,15 +161,36 @@ func (l *Lexer) NextToken() Token {
 				return Token{Type: ILLEGAL, Literal: fullLit.String()}
 			}
 			fullLit.WriteString(l.s.TokenText())
+			if !l.scanEscapedSegment(&fullLit) {
+				return Token{Type: ILLEGAL, Literal: fullLit.String()}
+			}
 		}
 		return Token{Type: IDENT, Literal: fullLit.String()}
 
 	case scanner.Int:
 		return Token{Type: NUMBER, Literal: l.s.TokenText()}
 
+	case scanner.Float:
+		// The scanner reads the lower bound of a hop range such as *1..4 as the float "1."
+		lit := l.s.TokenText()
+		if strings.HasSuffix(lit, ".") && l.s.Peek() == '.' {
+			l.s.Next() // consume the second dot
+			l.buf.tok, l.buf.lit, l.buf.hasNext = DOTDOT, "..", true
+			return Token{Type: NUMBER, Literal: strings.TrimSuffix(lit, ".")}
+		}
+		return Token{Type: NUMBER, Literal: lit}
+
 	case scanner.String:
 		return Token{Type: STRING, Literal: l.s.TokenText()}
 
+	case '$':
+		// Parameter names must follow the $ immediately
+		if ch := l.s.Peek(); !unicode.IsLetter(ch) && ch != '_' {
+			return Token{Type: ILLEGAL, Literal: "$"}
+		}
+		l.s.Scan()
+		return Token{Type: PARAM, Literal: l.s.TokenText()}
+
 	case '[':
 		return Token{Type: LBRACKET, Literal: "["}
 	case ']':
@@ -129,6 +218,10 @@ func (l *Lexer) NextToken() Token {
 	case ',':
 		return Token{Type: COMMA, Literal: ","}
 	case '.':
+		if l.s.Peek() == '.' {
+			l.s.Next() // consume the second dot
+			return Token{Type: DOTDOT, Literal: ".."}
+		}
 		return Token{Type: DOT, Literal: "."}
 
 	case '=':
@@ -193,6 +286,31 @@ func (l *Lexer) NextToken() Token {
 	return Token{Type: ILLEGAL, Literal: l.s.TokenText()}
 }
 
+// scanEscapedSegment completes a path segment containing escaped dots and slashes,
+// such as the label key in metadata.labels.app\.kubernetes\.io/name
+func (l *Lexer) scanEscapedSegment(segment *strings.Builder) bool {
+	for {
+		switch l.s.Peek() {
+		case '\\':
+			l.s.Next()
+			if l.s.Next() != '.' {
+				return false
+			}
+			segment.WriteString(`\.`)
+		case '/':
+			l.s.Next()
+			segment.WriteRune('/')
+		default:
+			return true
+		}
+
+		if l.s.Scan() != scanner.Ident {
+			return false
+		}
+		segment.WriteString(l.s.TokenText())
+	}
+}
+
 // Add Peek method to Lexer
 func (l *Lexer) Peek() rune {
 	return l.s.Peek()
@@ -27,6 +27,16 @@ func TestLexer(t *testing.T) {
 				{Type: EOF, Literal: ""},
 			},
 		},
+		{
+			name:  "boolean keywords",
+			input: "AND or Not",
+			expected: []Token{
+				{Type: AND, Literal: "AND"},
+				{Type: OR, Literal: "or"},
+				{Type: NOT, Literal: "Not"},
+				{Type: EOF, Literal: ""},
+			},
+		},
 		{
 			name:  "identifiers and literals",
 			input: `pod nginx "hello world" 42 true false null`,
@@ -164,6 +174,206 @@ func TestLexer(t *testing.T) {
 				{Type: EOF, Literal: ""},
 			},
 		},
+		{
+			name:  "ordering keywords and keyword path segments",
+			input: "ORDER BY p.limit DESC SKIP 1 LIMIT 2",
+			expected: []Token{
+				{Type: ORDER, Literal: "ORDER"},
+				{Type: BY, Literal: "BY"},
+				{Type: IDENT, Literal: "p"},
+				{Type: DOT, Literal: "."},
+				{Type: IDENT, Literal: "limit"},
+				{Type: DESC, Literal: "DESC"},
+				{Type: SKIP, Literal: "SKIP"},
+				{Type: NUMBER, Literal: "1"},
+				{Type: LIMIT, Literal: "LIMIT"},
+				{Type: NUMBER, Literal: "2"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "with keyword",
+			input: "WITH d, COUNT{p} AS pods",
+			expected: []Token{
+				{Type: WITH, Literal: "WITH"},
+				{Type: IDENT, Literal: "d"},
+				{Type: COMMA, Literal: ","},
+				{Type: COUNT, Literal: "COUNT"},
+				{Type: LBRACE, Literal: "{"},
+				{Type: IDENT, Literal: "p"},
+				{Type: RBRACE, Literal: "}"},
+				{Type: AS, Literal: "AS"},
+				{Type: IDENT, Literal: "pods"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "merge keywords",
+			input: "MERGE ON CREATE SET ON MATCH SET",
+			expected: []Token{
+				{Type: MERGE, Literal: "MERGE"},
+				{Type: ON, Literal: "ON"},
+				{Type: CREATE, Literal: "CREATE"},
+				{Type: SET, Literal: "SET"},
+				{Type: ON, Literal: "ON"},
+				{Type: MATCH, Literal: "MATCH"},
+				{Type: SET, Literal: "SET"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "remove with escaped path segments",
+			input: `REMOVE d.metadata.labels.app\.kubernetes\.io/name`,
+			expected: []Token{
+				{Type: REMOVE, Literal: "REMOVE"},
+				{Type: IDENT, Literal: "d"},
+				{Type: DOT, Literal: "."},
+				{Type: IDENT, Literal: `metadata.labels.app\.kubernetes\.io/name`},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "detach delete",
+			input: "DETACH DELETE d",
+			expected: []Token{
+				{Type: DETACH, Literal: "DETACH"},
+				{Type: DELETE, Literal: "DELETE"},
+				{Type: IDENT, Literal: "d"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "arithmetic",
+			input: "(d.spec.replicas + 1) * 1.5 / 2 - 3",
+			expected: []Token{
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "d"},
+				{Type: DOT, Literal: "."},
+				{Type: IDENT, Literal: "spec.replicas"},
+				{Type: ILLEGAL, Literal: "+"},
+				{Type: NUMBER, Literal: "1"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: ILLEGAL, Literal: "*"},
+				{Type: NUMBER, Literal: "1.5"},
+				{Type: ILLEGAL, Literal: "/"},
+				{Type: NUMBER, Literal: "2"},
+				{Type: ILLEGAL, Literal: "-"},
+				{Type: NUMBER, Literal: "3"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "list and null operators",
+			input: `x IN ["a", 1] STARTS WITH ENDS with is not null EXISTS(x)`,
+			expected: []Token{
+				{Type: IDENT, Literal: "x"},
+				{Type: IN, Literal: "IN"},
+				{Type: LBRACKET, Literal: "["},
+				{Type: STRING, Literal: `"a"`},
+				{Type: COMMA, Literal: ","},
+				{Type: NUMBER, Literal: "1"},
+				{Type: RBRACKET, Literal: "]"},
+				{Type: STARTS, Literal: "STARTS"},
+				{Type: WITH, Literal: "WITH"},
+				{Type: ENDS, Literal: "ENDS"},
+				{Type: WITH, Literal: "with"},
+				{Type: IS, Literal: "is"},
+				{Type: NOT, Literal: "not"},
+				{Type: NULL, Literal: "null"},
+				{Type: EXISTS, Literal: "EXISTS"},
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "x"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "union keywords",
+			input: "RETURN w UNION all MATCH",
+			expected: []Token{
+				{Type: RETURN, Literal: "RETURN"},
+				{Type: IDENT, Literal: "w"},
+				{Type: UNION, Literal: "UNION"},
+				{Type: ALL, Literal: "all"},
+				{Type: MATCH, Literal: "MATCH"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "unwind keyword",
+			input: "unwind p.spec.containers AS c",
+			expected: []Token{
+				{Type: UNWIND, Literal: "unwind"},
+				{Type: IDENT, Literal: "p"},
+				{Type: DOT, Literal: "."},
+				{Type: IDENT, Literal: "spec.containers"},
+				{Type: AS, Literal: "AS"},
+				{Type: IDENT, Literal: "c"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "distinct keyword",
+			input: "RETURN DISTINCT COUNT{distinct p}",
+			expected: []Token{
+				{Type: RETURN, Literal: "RETURN"},
+				{Type: DISTINCT, Literal: "DISTINCT"},
+				{Type: COUNT, Literal: "COUNT"},
+				{Type: LBRACE, Literal: "{"},
+				{Type: DISTINCT, Literal: "distinct"},
+				{Type: IDENT, Literal: "p"},
+				{Type: RBRACE, Literal: "}"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "aggregate keywords",
+			input: "AVG min Max COLLECT",
+			expected: []Token{
+				{Type: AVG, Literal: "AVG"},
+				{Type: MIN, Literal: "min"},
+				{Type: MAX, Literal: "Max"},
+				{Type: COLLECT, Literal: "COLLECT"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "hop range",
+			input: "-[*1..4]-> -[*..3]->",
+			expected: []Token{
+				{Type: REL_BEGINPROPS_NONE, Literal: "-["},
+				{Type: ILLEGAL, Literal: "*"},
+				{Type: NUMBER, Literal: "1"},
+				{Type: DOTDOT, Literal: ".."},
+				{Type: NUMBER, Literal: "4"},
+				{Type: REL_ENDPROPS_RIGHT, Literal: "]->"},
+				{Type: REL_BEGINPROPS_NONE, Literal: "-["},
+				{Type: ILLEGAL, Literal: "*"},
+				{Type: DOTDOT, Literal: ".."},
+				{Type: NUMBER, Literal: "3"},
+				{Type: REL_ENDPROPS_RIGHT, Literal: "]->"},
+				{Type: EOF, Literal: ""},
+			},
+		},
+		{
+			name:  "parameters",
+			input: `(w:$kind {name: $name}) $ x`,
+			expected: []Token{
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "w"},
+				{Type: COLON, Literal: ":"},
+				{Type: PARAM, Literal: "kind"},
+				{Type: LBRACE, Literal: "{"},
+				{Type: IDENT, Literal: "name"},
+				{Type: COLON, Literal: ":"},
+				{Type: PARAM, Literal: "name"},
+				{Type: RBRACE, Literal: "}"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: ILLEGAL, Literal: "$"},
+				{Type: IDENT, Literal: "x"},
+				{Type: EOF, Literal: ""},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// executeMergeClause looks up the resource of a MERGE clause by its name. An existing resource
+// is patched with the ON MATCH items, a missing one is created with the ON CREATE items applied.
+// relationships are those of the preceding match clauses, which values may refer to.
+func (q *QueryExecutor) executeMergeClause(c *MergeClause, relationships []*Relationship, results *QueryResult) error {
+	node := c.Node
+	nodeName := node.ResourceProperties.Name
+	name, namespace, labels, err := mergeIdentity(node)
+	if err != nil {
+		return err
+	}
+
+	resources, err := q.provider.GetK8sResources(node.ResourceProperties.Kind, "metadata.name="+name, "", namespace)
+	if err != nil {
+		return fmt.Errorf("error getting resources: %v", err)
+	}
+	existing, _ := resources.([]map[string]interface{})
+
+	if len(existing) > 0 {
+		resultMap[nodeName] = existing[:1]
+		if err := q.setKeyValuePairs(c.OnMatch, relationships); err != nil {
+			return err
+		}
+	} else {
+		metadata := map[string]interface{}{"name": name}
+		if namespace != "" {
+			metadata["namespace"] = namespace
+		}
+		if len(labels) > 0 {
+			metadata["labels"] = labels
+		}
+		resourceTemplate := map[string]interface{}{"metadata": metadata}
+		for _, kvp := range c.OnCreate {
+			_, path := splitKeyPath(kvp.Key)
+			value, err := q.evaluateValue(kvp.Value, nodeName, resourceTemplate, relationships)
+			if err != nil {
+				return fmt.Errorf("error evaluating value of %s: %s", kvp.Key, err)
+			}
+			updateResultMap(resourceTemplate, path, value)
+		}
+
+		err := q.provider.CreateK8sResource(node.ResourceProperties.Kind, name, namespace, resourceTemplate)
+		if err != nil {
+			return fmt.Errorf("error creating resource >> %v", err)
+		}
+
+		// Read the resource back, in dry-run mode the template stands in for it
+		resources, err := q.provider.GetK8sResources(node.ResourceProperties.Kind, "metadata.name="+name, "", namespace)
+		if err != nil {
+			return fmt.Errorf("error getting resources: %v", err)
+		}
+		if created, _ := resources.([]map[string]interface{}); len(created) > 0 {
+			resourceTemplate = created[0]
+		} else {
+			resourceTemplate["kind"] = node.ResourceProperties.Kind
+		}
+		resultMap[nodeName] = []map[string]interface{}{resourceTemplate}
+	}
+
+	resource := resultMap[nodeName].([]map[string]interface{})[0]
+	graphNode := Node{
+		Id:   nodeName,
+		Kind: resource["kind"].(string),
+		Name: name,
+	}
+	if graphNode.Kind != "Namespace" {
+		graphNode.Namespace = getNamespaceName(resource["metadata"].(map[string]interface{}))
+	}
+	results.Graph.Nodes = append(results.Graph.Nodes, graphNode)
+	return nil
+}
+
+// mergeIdentity returns the name and namespace identifying the resource of a merged node,
+// along with the labels it's created with
+func mergeIdentity(node *NodePattern) (string, string, map[string]interface{}, error) {
+	var name string
+	namespace := Namespace
+	labels := make(map[string]interface{})
+	for _, prop := range node.ResourceProperties.Properties.PropertyList {
+		var err error
+		key := strings.Trim(prop.Key, `"`)
+		switch key {
+		case "name", "metadata.name":
+			name, err = selectorValue(key, prop.Value)
+		case "namespace", "metadata.namespace":
+			namespace, err = selectorValue(key, prop.Value)
+		default:
+			labels[key] = fmt.Sprintf("%v", prop.Value)
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+	return name, namespace, labels, nil
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantCreated []string
+		wantPatched []string
+		want        interface{}
+	}{
+		{
+			name:        "existing resource is patched",
+			query:       `MERGE (d:Deployment {name: "web"}) ON CREATE SET d.spec.replicas = 3 ON MATCH SET d.spec.replicas = 2 RETURN d.spec.replicas`,
+			wantPatched: []string{"Deployment/web"},
+			want:        2,
+		},
+		{
+			name:        "missing resource is created",
+			query:       `MERGE (d:Deployment {name: "api", app: "api"}) ON CREATE SET d.spec.replicas = 3 ON MATCH SET d.spec.replicas = 2 RETURN d.spec.replicas`,
+			wantCreated: []string{"Deployment/api"},
+			want:        3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mockProvider{resources: map[string][]map[string]interface{}{
+				"deployments": {
+					mockResource("Deployment", "web", map[string]interface{}{
+						"spec": map[string]interface{}{"replicas": 1},
+					}),
+				},
+			}}
+			ast, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			executor, err := NewQueryExecutor(provider)
+			if err != nil {
+				t.Fatalf("NewQueryExecutor() error = %v", err)
+			}
+			result, err := executor.Execute(ast, "default")
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(provider.created, tt.wantCreated) {
+				t.Errorf("created %v, want %v", provider.created, tt.wantCreated)
+			}
+			if !reflect.DeepEqual(provider.patched, tt.wantPatched) {
+				t.Errorf("patched %v, want %v", provider.patched, tt.wantPatched)
+			}
+			got := result.Data["d"].([]interface{})[0].(map[string]interface{})["spec"].(map[string]interface{})["replicas"]
+			if got != tt.want {
+				t.Errorf("d.spec.replicas = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/avitaltamir/cyphernetes/pkg/provider"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// mockProvider serves an in-memory set of resources, keyed by their plural resource name
+type mockProvider struct {
+	resources map[string][]map[string]interface{}
+	deleted   []string
+	patched   []string
+	created   []string
+}
+
+var mockGVRs = map[string]schema.GroupVersionResource{
+	"pod":                     {Version: "v1", Resource: "pods"},
+	"service":                 {Version: "v1", Resource: "services"},
+	"endpoints":               {Version: "v1", Resource: "endpoints"},
+	"configmap":               {Version: "v1", Resource: "configmaps"},
+	"namespace":               {Version: "v1", Resource: "namespaces"},
+	"deployment":              {Group: "apps", Version: "v1", Resource: "deployments"},
+	"replicaset":              {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"statefulset":             {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"daemonset":               {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"job":                     {Group: "batch", Version: "v1", Resource: "jobs"},
+	"cronjob":                 {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"ingress":                 {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"poddisruptionbudget":     {Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"},
+	"networkpolicy":           {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	"horizontalpodautoscaler": {Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+}
+
+func (m *mockProvider) FindGVR(kind string) (schema.GroupVersionResource, error) {
+	kind = strings.ToLower(kind)
+	for singular, gvr := range mockGVRs {
+		if kind == singular || kind == gvr.Resource {
+			return gvr, nil
+		}
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("GVR not found for kind: %s", kind)
+}
+
+func (m *mockProvider) GetK8sResources(kind, fieldSelector, labelSelector, namespace string) (interface{}, error) {
+	gvr, err := m.FindGVR(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []map[string]interface{}{}
+	for _, resource := range m.resources[gvr.Resource] {
+		metadata := resource["metadata"].(map[string]interface{})
+		if name, ok := strings.CutPrefix(fieldSelector, "metadata.name="); ok && metadata["name"] != name {
+			continue
+		}
+		if labelSelector != "" {
+			labels, _ := metadata["labels"].(map[string]interface{})
+			matches := true
+			for _, selector := range strings.Split(labelSelector, ",") {
+				key, value, _ := strings.Cut(selector, "=")
+				if labels[key] != value {
+					matches = false
+				}
+			}
+			if !matches {
+				continue
+			}
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+func (m *mockProvider) DeleteK8sResources(kind, name, namespace string) error {
+	m.deleted = append(m.deleted, kind+"/"+name)
+	return nil
+}
+
+func (m *mockProvider) CreateK8sResource(kind, name, namespace string, body interface{}) error {
+	m.created = append(m.created, kind+"/"+name)
+	return nil
+}
+
+func (m *mockProvider) PatchK8sResource(kind, name, namespace string, body interface{}) error {
+	m.patched = append(m.patched, kind+"/"+name)
+	return nil
+}
+
+func (m *mockProvider) GetOpenAPIResourceSpecs() (map[string][]string, error) {
+	return map[string][]string{}, nil
+}
+
+func (m *mockProvider) CreateProviderForContext(context string) (provider.Provider, error) {
+	return m, nil
+}
+
+// executeMockQuery parses and executes a query against the given resources
+func executeMockQuery(query string, resources map[string][]map[string]interface{}) (QueryResult, error) {
+	return executeMockQueryWithOptions(query, resources, ExecuteOptions{})
+}
+
+// executeMockQueryWithRows executes a query against mock resources, reporting its rows too
+func executeMockQueryWithRows(query string, resources map[string][]map[string]interface{}) (QueryResult, error) {
+	return executeMockQueryWithOptions(query, resources, ExecuteOptions{Rows: true})
+}
+
+func executeMockQueryWithOptions(query string, resources map[string][]map[string]interface{}, opts ExecuteOptions) (QueryResult, error) {
+	ast, err := ParseQuery(query)
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	executor, err := NewQueryExecutor(&mockProvider{resources: resources})
+	if err != nil {
+		return QueryResult{}, err
+	}
+
+	// Start from a clean slate, a failed query may leave results behind
+	resultCache = make(map[string]interface{})
+	resultMap = make(map[string]interface{})
+	hopResourceCache = make(map[string][]map[string]interface{})
+	return executor.ExecuteWithOptions(ast, "default", opts)
+}
+
+// mockResource returns a resource of the given kind and name in the default namespace, with the
+// given fields merged into it in order
+func mockResource(kind, name string, fields ...map[string]interface{}) map[string]interface{} {
+	resource := map[string]interface{}{
+		"kind": kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+	}
+	for _, f := range fields {
+		mergeFields(resource, f)
+	}
+	return resource
+}
+
+// mergeFields merges fields into a resource, merging nested maps and replacing other values.
+// Maps are copied, so that fields shared by several resources aren't merged into.
+func mergeFields(resource, fields map[string]interface{}) {
+	for key, value := range fields {
+		nested, isMap := value.(map[string]interface{})
+		if !isMap {
+			resource[key] = value
+			continue
+		}
+		existing, hasMap := resource[key].(map[string]interface{})
+		if !hasMap {
+			existing = make(map[string]interface{})
+			resource[key] = existing
+		}
+		mergeFields(existing, nested)
+	}
+}
+
+// ownedBy returns the fields of a resource owned by the named resource
+func ownedBy(owner string) map[string]interface{} {
+	return map[string]interface{}{"metadata": map[string]interface{}{
+		"ownerReferences": []interface{}{map[string]interface{}{"name": owner}},
+	}}
+}
+
+// labeled returns the fields of a resource with the given labels
+func labeled(labels map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}}
+}
+
+// inNamespace returns the fields of a resource in the given namespace
+func inNamespace(namespace string) map[string]interface{} {
+	return map[string]interface{}{"metadata": map[string]interface{}{"namespace": namespace}}
+}
+
+// selecting returns the fields of a service selecting the pods with the given labels
+func selecting(labels map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"spec": map[string]interface{}{"selector": labels}}
+}
+
+// matchingLabels returns the fields of a resource whose label selector matches the given labels
+func matchingLabels(labels map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": labels}}}
+}
+
+// routingTo returns the fields of an ingress routing to the named services
+func routingTo(services ...string) map[string]interface{} {
+	var paths []interface{}
+	for _, service := range services {
+		paths = append(paths, map[string]interface{}{
+			"backend": map[string]interface{}{"service": map[string]interface{}{"name": service}},
+		})
+	}
+	return map[string]interface{}{"spec": map[string]interface{}{
+		"rules": []interface{}{map[string]interface{}{"http": map[string]interface{}{"paths": paths}}},
+	}}
+}
+
+// onNode returns the fields of a pod scheduled on the named node
+func onNode(node string) map[string]interface{} {
+	return map[string]interface{}{"spec": map[string]interface{}{"nodeName": node}}
+}
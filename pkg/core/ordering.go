@@ -0,0 +1,298 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AvitalTamir/jsonpath"
+)
+
+// orderRows sorts the rows of a return clause, along with the bindings they were computed from,
+// according to its ORDER BY part, drops duplicate rows when it returns DISTINCT results, then
+// pages them according to its SKIP and LIMIT parts
+func orderRows(c *ReturnClause, bindings []rowBinding, rows []map[string]interface{}) ([]rowBinding, []map[string]interface{}, error) {
+	for _, order := range c.OrderBy {
+		nodeId := strings.Split(order.JsonPath, ".")[0]
+		if resultMap[nodeId] == nil {
+			return nil, nil, fmt.Errorf("node identifier %s not found in order by clause", nodeId)
+		}
+		if len(bindings) > 0 {
+			if _, ok := bindings[0][nodeId]; !ok {
+				return nil, nil, fmt.Errorf("can't order by %s: %s is neither returned nor related to a returned variable", order.JsonPath, nodeId)
+			}
+		}
+	}
+
+	indices := make([]int, len(bindings))
+	for i := range indices {
+		indices[i] = i
+	}
+	if len(c.OrderBy) > 0 {
+		values := make([][]interface{}, len(bindings))
+		for i, binding := range bindings {
+			values[i] = make([]interface{}, len(c.OrderBy))
+			for k, order := range c.OrderBy {
+				value, err := rowValue(binding, order.JsonPath, order.Function)
+				if err != nil {
+					return nil, nil, err
+				}
+				values[i][k] = value
+			}
+		}
+		sort.SliceStable(indices, func(a, b int) bool {
+			for k, order := range c.OrderBy {
+				cmp := compareOrderValues(values[indices[a]][k], values[indices[b]][k])
+				if cmp == 0 {
+					continue
+				}
+				if order.Descending {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+			return false
+		})
+	}
+
+	if c.Distinct {
+		seen := make(map[string]bool)
+		distinct := []int{}
+		for _, idx := range indices {
+			key, err := json.Marshal(rows[idx])
+			if err != nil {
+				return nil, nil, fmt.Errorf("error comparing rows: %v", err)
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				distinct = append(distinct, idx)
+			}
+		}
+		indices = distinct
+	}
+
+	indices = pageItems(indices, c.Skip, c.Limit)
+	orderedBindings := make([]rowBinding, len(indices))
+	orderedRows := make([]map[string]interface{}, len(indices))
+	for i, idx := range indices {
+		orderedBindings[i] = bindings[idx]
+		orderedRows[i] = rows[idx]
+	}
+	return orderedBindings, orderedRows, nil
+}
+
+// applyOrdering narrows the returned items of every variable down to those of the resources bound
+// in the ordered rows of a return clause, in the order of the rows, so that ORDER BY, SKIP and
+// LIMIT apply to whole rows rather than to each variable on its own. Aggregations are computed
+// before ordering and are not affected.
+func applyOrdering(c *ReturnClause, bindings []rowBinding, results *QueryResult) error {
+	for nodeId, data := range results.Data {
+		if nodeId == "aggregate" {
+			continue
+		}
+		items, ok := data.([]interface{})
+		if !ok {
+			continue
+		}
+		resources, _ := resultMap[nodeId].([]map[string]interface{})
+		if len(resources) != len(items) {
+			return fmt.Errorf("can't order the results of %s: got %d items for %d resources", nodeId, len(items), len(resources))
+		}
+
+		indices := make(map[string]int)
+		for i, resource := range resources {
+			if _, ok := indices[bindingKey(resource)]; !ok {
+				indices[bindingKey(resource)] = i
+			}
+		}
+
+		ordered := []interface{}{}
+		added := make(map[int]bool)
+		for _, binding := range bindings {
+			resource := binding[nodeId]
+			if resource == nil {
+				continue
+			}
+			idx, ok := indices[bindingKey(resource)]
+			if !ok || added[idx] {
+				continue
+			}
+			added[idx] = true
+			ordered = append(ordered, items[idx])
+		}
+		if c.Distinct {
+			ordered = distinctRows(ordered)
+		}
+		results.Data[nodeId] = ordered
+	}
+
+	return nil
+}
+
+// orderGroups sorts and pages the rows of grouped aggregations according to the ORDER BY, SKIP
+// and LIMIT parts of a return clause. Rows are sorted by their grouping keys, referred to by path
+// or alias, or by their aggregations, referred to by alias.
+func orderGroups(c *ReturnClause, groupItems []*ReturnItem, results *QueryResult) error {
+	rows, _ := results.Data["aggregate"].([]interface{})
+
+	keys := make([]string, len(c.OrderBy))
+	for k, order := range c.OrderBy {
+		key, err := groupOrderKey(c, groupItems, order)
+		if err != nil {
+			return err
+		}
+		keys[k] = key
+	}
+
+	sort.SliceStable(rows, func(a, b int) bool {
+		for k, order := range c.OrderBy {
+			cmp := compareOrderValues(rows[a].(map[string]interface{})[keys[k]], rows[b].(map[string]interface{})[keys[k]])
+			if cmp == 0 {
+				continue
+			}
+			if order.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+
+	results.Data["aggregate"] = pageItems(rows, c.Skip, c.Limit)
+	return nil
+}
+
+// groupOrderKey returns the key of the grouped rows' values a sort key refers to
+func groupOrderKey(c *ReturnClause, groupItems []*ReturnItem, order *OrderItem) (string, error) {
+	for _, item := range groupItems {
+		if item.JsonPath == order.JsonPath && sameFunctionCall(item.Function, order.Function) {
+			return returnItemKey(item), nil
+		}
+	}
+	for _, item := range c.Items {
+		if item.Aggregate != "" && item.Alias != "" && item.Alias == order.JsonPath {
+			return aggregateKey(item), nil
+		}
+	}
+	return "", fmt.Errorf("can't order grouped results by %s: expected a returned grouping key or the alias of an aggregation", order.JsonPath)
+}
+
+// sameFunctionCall reports whether two function calls, either of which may be nil, call the same
+// function with the same arguments. Copies of a query, such as those run in each context, hold
+// copies of its calls.
+func sameFunctionCall(a, b *FunctionCall) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// isOrdered reports whether a return clause orders, pages or drops duplicate results
+func isOrdered(c *ReturnClause) bool {
+	return len(c.OrderBy) > 0 || c.Skip > 0 || c.Limit != nil || c.Distinct
+}
+
+// bindingKey identifies what a row binds a variable to. Resources and unwound elements are bound
+// as they are, relationships and paths are described anew for every row.
+func bindingKey(value map[string]interface{}) string {
+	if isRelationshipEntry(value) || isPathEntry(value) {
+		key, _ := json.Marshal(value)
+		return string(key)
+	}
+	return fmt.Sprintf("%p", value)
+}
+
+// pageItems drops the first skip items and keeps at most limit of the remaining ones.
+// A nil limit means no limit.
+func pageItems[T any](items []T, skip int, limit *int) []T {
+	if skip >= len(items) {
+		return []T{}
+	}
+	items = items[skip:]
+	if limit != nil && *limit < len(items) {
+		items = items[:*limit]
+	}
+	return items
+}
+
+// lookupReturnPath looks up a return path such as "p.metadata.name" in a resource,
+// missing paths yield nil
+func lookupReturnPath(resource map[string]interface{}, path string) interface{} {
+	value, err := jsonpath.JsonPathLookup(resource, toJsonPath(path))
+	if err != nil {
+		logDebug("Path not found:", path)
+		return nil
+	}
+	return value
+}
+
+// returnItemValue returns the value of a return path or, for computed items, of its function call
+func returnItemValue(resource map[string]interface{}, path string, function *FunctionCall) (interface{}, error) {
+	if function != nil {
+		return evaluateFunctionCall(function, strings.Split(path, ".")[0], resource)
+	}
+	return lookupReturnPath(resource, path), nil
+}
+
+// compareOrderValues compares two values for sorting and returns -1, 0 or 1.
+// Missing values sort last. Numbers, RFC3339 timestamps and Kubernetes quantities
+// are compared by value, everything else is compared by its string representation.
+func compareOrderValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	if aFloat, err := toFloat64(a); err == nil {
+		if bFloat, err := toFloat64(b); err == nil {
+			return compareFloats(aFloat, bFloat)
+		}
+	}
+
+	if aBool, ok := a.(bool); ok {
+		if bBool, ok := b.(bool); ok {
+			switch {
+			case aBool == bBool:
+				return 0
+			case !aBool:
+				return -1
+			default:
+				return 1
+			}
+		}
+	}
+
+	if aQuantity, bQuantity, ok := quantitiesToFloat64(a, b); ok {
+		return compareFloats(aQuantity, bQuantity)
+	}
+
+	aStr, aIsStr := a.(string)
+	bStr, bIsStr := b.(string)
+	if aIsStr && bIsStr {
+		if aTime, err := time.Parse(time.RFC3339, aStr); err == nil {
+			if bTime, err := time.Parse(time.RFC3339, bStr); err == nil {
+				return aTime.Compare(bTime)
+			}
+		}
+		return strings.Compare(aStr, bStr)
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareOrderValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        interface{}
+		b        interface{}
+		expected int
+	}{
+		{"numbers", int64(2), float64(10), -1},
+		{"equal numbers", int64(3), 3, 0},
+		{"strings", "nginx", "api", 1},
+		{"timestamps", "2024-05-01T10:00:00Z", "2024-04-30T23:00:00+02:00", 1},
+		{"cpu quantities", "500m", "2", -1},
+		{"memory quantities", "1Gi", "512Mi", 1},
+		{"booleans", false, true, -1},
+		{"nil sorts last", nil, "a", 1},
+		{"both nil", nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareOrderValues(tt.a, tt.b); got != tt.expected {
+				t.Errorf("compareOrderValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteOrdering(t *testing.T) {
+	pod := func(name, app string, restarts int64) map[string]interface{} {
+		return mockResource("Pod", name, labeled(map[string]interface{}{"app": app}), map[string]interface{}{
+			"status": map[string]interface{}{"restartCount": restarts},
+		})
+	}
+	service := func(app string) map[string]interface{} {
+		return mockResource("Service", app, selecting(map[string]interface{}{"app": app}))
+	}
+	resources := map[string][]map[string]interface{}{
+		"pods":     {pod("a", "web", 3), pod("b", "web", 0), pod("c", "api", 3), pod("d", "api", 12)},
+		"services": {service("web"), service("api")},
+	}
+	// names returns the names of the returned resources of a variable
+	names := func(data interface{}) []string {
+		names := []string{}
+		items, _ := data.([]interface{})
+		for _, item := range items {
+			names = append(names, item.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		want     map[string][]string
+		wantRows []map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "ascending",
+			query:    `MATCH (p:Pod) RETURN p.status.restartCount AS restarts ORDER BY p.status.restartCount`,
+			want:     map[string][]string{"p": {"b", "a", "c", "d"}},
+			wantRows: []map[string]interface{}{{"restarts": int64(0)}, {"restarts": int64(3)}, {"restarts": int64(3)}, {"restarts": int64(12)}},
+		},
+		{
+			name:  "descending with tie breaker",
+			query: `MATCH (p:Pod) RETURN p.metadata.name AS name ORDER BY p.status.restartCount DESC, name DESC`,
+			want:  map[string][]string{"p": {"d", "c", "a", "b"}},
+			wantRows: []map[string]interface{}{
+				{"name": "d"}, {"name": "c"}, {"name": "a"}, {"name": "b"},
+			},
+		},
+		{
+			name:     "skip and limit",
+			query:    `MATCH (p:Pod) RETURN p.metadata.name AS name ORDER BY name SKIP 1 LIMIT 2`,
+			want:     map[string][]string{"p": {"b", "c"}},
+			wantRows: []map[string]interface{}{{"name": "b"}, {"name": "c"}},
+		},
+		{
+			name:     "skip past the end",
+			query:    `MATCH (p:Pod) RETURN p.metadata.name AS name SKIP 10`,
+			want:     map[string][]string{"p": {}},
+			wantRows: []map[string]interface{}{},
+		},
+		{
+			name:     "limit 0",
+			query:    `MATCH (p:Pod) RETURN p.metadata.name AS name LIMIT 0`,
+			want:     map[string][]string{"p": {}},
+			wantRows: []map[string]interface{}{},
+		},
+		{
+			name:  "rows of several variables are ordered and paged together",
+			query: `MATCH (s:Service)->(p:Pod) RETURN s.metadata.name AS service, p.metadata.name AS pod ORDER BY service DESC, pod SKIP 1`,
+			want:  map[string][]string{"s": {"web", "api"}, "p": {"b", "c", "d"}},
+			wantRows: []map[string]interface{}{
+				{"service": "web", "pod": "b"},
+				{"service": "api", "pod": "c"},
+				{"service": "api", "pod": "d"},
+			},
+		},
+		{
+			name:  "ordered by a related variable that isn't returned",
+			query: `MATCH (s:Service)->(p:Pod) RETURN p.metadata.name AS pod ORDER BY s.metadata.name, p.status.restartCount DESC LIMIT 3`,
+			want:  map[string][]string{"p": {"d", "c", "a"}},
+			wantRows: []map[string]interface{}{
+				{"pod": "d"}, {"pod": "c"}, {"pod": "a"},
+			},
+		},
+		{
+			name:    "ordered by an unrelated variable",
+			query:   `MATCH (s:Service), (p:Pod) RETURN p.metadata.name AS pod ORDER BY s.metadata.name`,
+			wantErr: "can't order by s.metadata.name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("executeMockQuery() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			for variable, want := range tt.want {
+				if got := names(result.Data[variable]); !reflect.DeepEqual(got, want) {
+					t.Errorf("got %s %v, want %v", variable, got, want)
+				}
+			}
+			if !reflect.DeepEqual(result.Rows, tt.wantRows) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestGroupOrderKey(t *testing.T) {
+	ast, err := ParseQuery(`MATCH (p:Pod) RETURN toUpper(p.spec.nodeName) AS node, COUNT{p.metadata.name} AS pods ORDER BY node`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	returnClause := ast.Clauses[len(ast.Clauses)-1].(*ReturnClause)
+
+	// Queries run in each context order by copies of the returned function calls
+	for _, c := range []*ReturnClause{returnClause, prefixReturnClause(returnClause, "staging")} {
+		key, err := groupOrderKey(c, getGroupItems(c), c.OrderBy[0])
+		if err != nil {
+			t.Fatalf("groupOrderKey() error = %v", err)
+		}
+		if want := returnItemKey(c.Items[0]); key != want {
+			t.Errorf("groupOrderKey() = %q, want %q", key, want)
+		}
+	}
+
+	orderByOtherCall := &OrderItem{JsonPath: returnClause.OrderBy[0].JsonPath, Function: &FunctionCall{Name: "toLower", Args: returnClause.Items[0].Function.Args}}
+	if _, err := groupOrderKey(returnClause, getGroupItems(returnClause), orderByOtherCall); err == nil {
+		t.Errorf("groupOrderKey() ordering by another call of the same field, want error")
+	}
+}
+
+func TestExecuteDistinct(t *testing.T) {
+	pod := func(name, node string, images ...string) map[string]interface{} {
+		var containers []interface{}
+		for _, image := range images {
+			containers = append(containers, map[string]interface{}{"image": image})
+		}
+		return mockResource("Pod", name, onNode(node), map[string]interface{}{
+			"spec": map[string]interface{}{"containers": containers},
+		})
+	}
+	resources := map[string][]map[string]interface{}{
+		"pods": {
+			pod("web-a", "node-a", "nginx:1.25", "envoy:1.30"),
+			pod("web-b", "node-b", "nginx:1.25", "envoy:1.30"),
+			pod("api-a", "node-a", "api:2.0"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		want     interface{}
+		wantRows []map[string]interface{}
+	}{
+		{
+			name:     "return distinct drops duplicate results",
+			query:    `MATCH (p:Pod) RETURN DISTINCT p.spec.nodeName AS node`,
+			variable: "p",
+			want: []interface{}{
+				map[string]interface{}{"node": "node-a"},
+				map[string]interface{}{"node": "node-b"},
+			},
+			wantRows: []map[string]interface{}{{"node": "node-a"}, {"node": "node-b"}},
+		},
+		{
+			name:     "duplicates are dropped before paging",
+			query:    `MATCH (p:Pod) RETURN DISTINCT p.spec.nodeName AS node ORDER BY node DESC LIMIT 2`,
+			variable: "p",
+			want: []interface{}{
+				map[string]interface{}{"node": "node-b"},
+				map[string]interface{}{"node": "node-a"},
+			},
+			wantRows: []map[string]interface{}{{"node": "node-b"}, {"node": "node-a"}},
+		},
+		{
+			name:     "count distinct values",
+			query:    `MATCH (p:Pod) RETURN COUNT{DISTINCT p.spec.containers[*].image} AS images, COUNT{p.spec.containers[*].image} AS containers`,
+			variable: "aggregate",
+			want:     map[string]interface{}{"images": 3, "containers": 5},
+			wantRows: []map[string]interface{}{{"images": 3, "containers": 5}},
+		},
+		{
+			name:     "collect distinct values per group",
+			query:    `MATCH (p:Pod) RETURN p.spec.nodeName AS node, COLLECT{DISTINCT p.spec.containers[*].image}`,
+			variable: "aggregate",
+			want: []interface{}{
+				map[string]interface{}{"node": "node-a", "collect:distinct p.spec.containers[*].image": []interface{}{"nginx:1.25", "envoy:1.30", "api:2.0"}},
+				map[string]interface{}{"node": "node-b", "collect:distinct p.spec.containers[*].image": []interface{}{"nginx:1.25", "envoy:1.30"}},
+			},
+			wantRows: []map[string]interface{}{
+				{"node": "node-a", "collect:distinct p.spec.containers[*].image": []interface{}{"nginx:1.25", "envoy:1.30", "api:2.0"}},
+				{"node": "node-b", "collect:distinct p.spec.containers[*].image": []interface{}{"nginx:1.25", "envoy:1.30"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data[tt.variable], tt.want) {
+				t.Errorf("got %s %v, want %v", tt.variable, result.Data[tt.variable], tt.want)
+			}
+			if !reflect.DeepEqual(result.Rows, tt.wantRows) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.wantRows)
+			}
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -11,6 +14,12 @@ type Parser struct {
 	lexer   *Lexer
 	current Token
 	pos     int
+	// peeked holds tokens read ahead of the current token
+	peeked []Token
+	// params holds the values bound to the query's $parameters
+	params map[string]interface{}
+	// anonymousNodes counts the nodes without a variable, which are given generated names
+	anonymousNodes int
 }
 
 func NewRecursiveParser(input string) *Parser {
@@ -26,7 +35,6 @@ func (p *Parser) Parse() (*Expression, error) {
 	debugLog("Starting parse with token: %v", p.current)
 
 	var contexts []string
-	var clauses []Clause
 
 	// Check for IN clause
 	if p.current.Type == IN {
@@ -40,9 +48,72 @@ func (p *Parser) Parse() (*Expression, error) {
 		p.lexer.SetParsingContexts(false)
 	}
 
-	// Parse first clause (must be MATCH or CREATE)
-	if p.current.Type != MATCH && p.current.Type != CREATE {
-		return nil, fmt.Errorf("expected MATCH or CREATE, got \"%v\"", p.current.Literal)
+	clauses, err := p.parseClauses()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the queries combined with the first one by UNION
+	var unions []*Expression
+	var unionAll bool
+	for p.current.Type == UNION {
+		p.advance()
+		all := p.current.Type == ALL
+		if all {
+			p.advance()
+		}
+		if len(unions) > 0 && all != unionAll {
+			return nil, fmt.Errorf("can't combine UNION and UNION ALL in one query")
+		}
+		unionAll = all
+
+		unionClauses, err := p.parseClauses()
+		if err != nil {
+			return nil, fmt.Errorf("parsing UNION query: %w", err)
+		}
+		unions = append(unions, &Expression{Clauses: unionClauses})
+	}
+
+	// Check for invalid tokens first
+	if p.current.Type == LESS_THAN {
+		debugLog("Found invalid token '<' before EOF")
+		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+	}
+
+	// Then check for EOF
+	debugLog("Checking for EOF, current token: %v", p.current)
+	if p.current.Type != EOF {
+		if p.current.Type == ILLEGAL && strings.HasPrefix(p.current.Literal, "<") {
+			return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+		}
+		return nil, fmt.Errorf("unexpected token after expression: \"%v\"", p.current.Literal)
+	}
+
+	// Check for incomplete expressions last
+	if err := checkComplete(clauses); err != nil {
+		return nil, err
+	}
+	if len(unions) > 0 {
+		if err := checkUnion(clauses, unions); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Expression{
+		Contexts: contexts,
+		Clauses:  clauses,
+		Unions:   unions,
+		UnionAll: unionAll,
+	}, nil
+}
+
+// parseClauses parses the clauses of a single query, up to the end of the input or a UNION
+func (p *Parser) parseClauses() ([]Clause, error) {
+	var clauses []Clause
+
+	// Parse first clause (must be MATCH, CREATE or MERGE)
+	if p.current.Type != MATCH && p.current.Type != CREATE && p.current.Type != MERGE {
+		return nil, fmt.Errorf("expected MATCH, CREATE or MERGE, got \"%v\"", p.current.Literal)
 	}
 
 	firstClause, err := p.parseFirstClause()
@@ -54,99 +125,205 @@ func (p *Parser) Parse() (*Expression, error) {
 	}
 	clauses = append(clauses, firstClause)
 
-	// Handle WHERE clause if present
-	if p.current.Type == WHERE {
-		if matchClause, ok := firstClause.(*MatchClause); ok {
-			p.advance()
-			filters, err := p.parseKeyValuePairs()
+	// Parse the following clauses, each of which must be valid after the one before it
+	for p.current.Type != EOF {
+		last := clauses[len(clauses)-1]
+		_, afterMatch := last.(*MatchClause)
+		_, afterWith := last.(*WithClause)
+		_, afterMerge := last.(*MergeClause)
+
+		switch p.current.Type {
+		case WHERE:
+			return nil, fmt.Errorf("WHERE clause can only follow MATCH")
+
+		case OPTIONAL:
+			if !afterMatch {
+				return nil, fmt.Errorf("OPTIONAL MATCH can only follow MATCH")
+			}
+			if err := p.parseOptionalMatchClause(last.(*MatchClause)); err != nil {
+				return nil, fmt.Errorf("parsing OPTIONAL MATCH clause: %w", err)
+			}
+			continue
+
+		case MATCH:
+			if !afterWith {
+				return nil, fmt.Errorf("MATCH can only start a query or follow WITH")
+			}
+			matchClause, err := p.parseMatchClause()
 			if err != nil {
-				return nil, fmt.Errorf("parsing WHERE clause: %w", err)
+				return nil, fmt.Errorf("parsing MATCH clause: %w", err)
 			}
-			matchClause.ExtraFilters = filters
-		} else {
-			return nil, fmt.Errorf("WHERE clause can only follow MATCH")
-		}
-	}
+			bindWithNodes(matchClause, availableNodes(clauses))
+			clauses = append(clauses, matchClause)
+			continue
 
-	// Parse optional second and third clauses according to valid combinations
-	switch p.current.Type {
-	case SET:
-		setClause, err := p.parseSetClause()
-		if err != nil {
-			return nil, err
-		}
-		clauses = append(clauses, setClause)
+		case UNWIND:
+			if _, afterUnwind := last.(*UnwindClause); !afterMatch && !afterWith && !afterUnwind {
+				return nil, fmt.Errorf("UNWIND can only follow MATCH, WITH or UNWIND")
+			}
+			unwindClause, err := p.parseUnwindClause(clauses)
+			if err != nil {
+				return nil, fmt.Errorf("parsing UNWIND clause: %w", err)
+			}
+			clauses = append(clauses, unwindClause)
+			continue
+
+		case WITH:
+			if !afterMatch && !afterWith {
+				return nil, fmt.Errorf("WITH can only follow MATCH")
+			}
+			withClause, err := p.parseWithClause(clauses)
+			if err != nil {
+				return nil, fmt.Errorf("parsing WITH clause: %w", err)
+			}
+			clauses = append(clauses, withClause)
+			continue
+
+		case MERGE:
+			if !afterMatch && !afterWith && !afterMerge {
+				return nil, fmt.Errorf("MERGE can only start a query or follow MATCH, WITH or MERGE")
+			}
+			mergeClause, err := p.parseMergeClause()
+			if err != nil {
+				return nil, fmt.Errorf("parsing MERGE clause: %w", err)
+			}
+			if _, ok := availableNodes(clauses)[mergeClause.Node.ResourceProperties.Name]; ok {
+				return nil, fmt.Errorf("node %s is already bound by a preceding clause", mergeClause.Node.ResourceProperties.Name)
+			}
+			clauses = append(clauses, mergeClause)
+			continue
 
-		// After SET, only RETURN is valid
-		if p.current.Type == RETURN {
-			returnClause, err := p.parseReturnClause()
+		case SET:
+			if !afterMatch && !afterWith && !afterMerge && !(isCreateClause(last) && len(clauses) == 1) {
+				break
+			}
+			setClause, err := p.parseSetClause()
 			if err != nil {
 				return nil, err
 			}
-			clauses = append(clauses, returnClause)
-		}
+			clauses = append(clauses, setClause)
+			continue
 
-	case DELETE:
-		if _, ok := firstClause.(*MatchClause); !ok {
-			return nil, fmt.Errorf("DELETE can only follow MATCH")
-		}
-		deleteClause, err := p.parseDeleteClause()
-		if err != nil {
-			return nil, err
-		}
-		clauses = append(clauses, deleteClause)
+		case REMOVE:
+			if !afterMatch && !afterWith && !afterMerge {
+				return nil, fmt.Errorf("REMOVE can only follow MATCH, WITH or MERGE")
+			}
+			removeClause, err := p.parseRemoveClause()
+			if err != nil {
+				return nil, fmt.Errorf("parsing REMOVE clause: %w", err)
+			}
+			clauses = append(clauses, removeClause)
+			continue
 
-	case CREATE:
-		if _, ok := firstClause.(*MatchClause); !ok {
-			return nil, fmt.Errorf("CREATE can only follow MATCH in this position")
-		}
-		createClause, err := p.parseCreateClause()
-		if err != nil {
-			return nil, err
-		}
-		clauses = append(clauses, createClause)
+		case DELETE, DETACH:
+			if !afterMatch && !afterWith && !afterMerge {
+				if len(clauses) == 1 {
+					return nil, fmt.Errorf("DELETE can only follow MATCH")
+				}
+				break
+			}
+			deleteClause, err := p.parseDeleteClause()
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, deleteClause)
+			continue
 
-		// After CREATE, only RETURN is valid
-		if p.current.Type == RETURN {
-			returnClause, err := p.parseReturnClause()
+		case CREATE:
+			if !afterMatch && !afterWith {
+				if len(clauses) == 1 {
+					return nil, fmt.Errorf("CREATE can only follow MATCH in this position")
+				}
+				break
+			}
+			createClause, err := p.parseCreateClause()
 			if err != nil {
 				return nil, err
 			}
-			clauses = append(clauses, returnClause)
-		}
+			clauses = append(clauses, createClause)
+			continue
 
-	case RETURN:
-		returnClause, err := p.parseReturnClause()
-		if err != nil {
-			return nil, err
+		case RETURN:
+			switch last.(type) {
+			case *DeleteClause, *ReturnClause:
+			default:
+				returnClause, err := p.parseReturnClause()
+				if err != nil {
+					return nil, err
+				}
+				if err := checkWithAliases(returnClause, clauses); err != nil {
+					return nil, err
+				}
+				clauses = append(clauses, returnClause)
+				continue
+			}
 		}
-		clauses = append(clauses, returnClause)
+		break
 	}
 
-	// Check for invalid tokens first
-	if p.current.Type == '<' {
-		debugLog("Found invalid token '<' before EOF")
-		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+	return clauses, nil
+}
+
+// checkComplete returns an error if the clauses of a query don't make up a complete query
+func checkComplete(clauses []Clause) error {
+	_, isMerge := clauses[0].(*MergeClause)
+	if len(clauses) < 2 && !isCreateClause(clauses[0]) && !isMerge {
+		return fmt.Errorf("incomplete expression")
+	}
+	switch clauses[len(clauses)-1].(type) {
+	case *WithClause:
+		return fmt.Errorf("incomplete expression: WITH must be followed by another clause")
+	case *UnwindClause:
+		return fmt.Errorf("incomplete expression: UNWIND must be followed by another clause")
 	}
+	return nil
+}
 
-	// Then check for EOF
-	debugLog("Checking for EOF, current token: %v", p.current)
-	if p.current.Type != EOF {
-		if p.current.Type == ILLEGAL && strings.HasPrefix(p.current.Literal, "<") {
-			return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+// checkUnion returns an error unless all the queries combined by UNION end with a RETURN clause
+// returning the same columns, so that their results can be combined. Queries may return them under
+// variables of their own, as long as each of them returns the columns of one of the first query's.
+func checkUnion(clauses []Clause, unions []*Expression) error {
+	var first *ReturnClause
+	var columns []string
+	for i, query := range append([]*Expression{{Clauses: clauses}}, unions...) {
+		if err := checkComplete(query.Clauses); err != nil {
+			return err
+		}
+		returnClause, ok := query.Clauses[len(query.Clauses)-1].(*ReturnClause)
+		if !ok {
+			return fmt.Errorf("all queries combined by UNION must end with RETURN")
+		}
+		if getGroupItems(returnClause) == nil {
+			for _, item := range returnClause.Items {
+				if item.Aggregate != "" {
+					return fmt.Errorf("queries combined by UNION can't return aggregations without grouping them")
+				}
+			}
 		}
-		return nil, fmt.Errorf("unexpected token after expression: \"%v\"", p.current.Literal)
-	}
 
-	// Check for incomplete expression last
-	if len(clauses) < 2 && !isCreateClause(clauses[0]) {
-		return nil, fmt.Errorf("incomplete expression")
+		queryColumns := returnColumns(returnClause)
+		if i == 0 {
+			first, columns = returnClause, queryColumns
+			continue
+		}
+		if !slices.Equal(columns, queryColumns) {
+			return fmt.Errorf("all queries combined by UNION must return the same columns, got %s and %s", strings.Join(columns, ", "), strings.Join(queryColumns, ", "))
+		}
+		if _, _, err := unionMapping(first, returnClause); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return &Expression{
-		Contexts: contexts,
-		Clauses:  clauses,
-	}, nil
+// returnColumns returns the sorted columns of a return clause
+func returnColumns(c *ReturnClause) []string {
+	var columns []string
+	for _, item := range c.Items {
+		columns = append(columns, unionColumn(item))
+	}
+	slices.Sort(columns)
+	return columns
 }
 
 func isCreateClause(c Clause) bool {
@@ -154,19 +331,21 @@ func isCreateClause(c Clause) bool {
 	return ok
 }
 
-// parseFirstClause parses either a MATCH or CREATE clause
+// parseFirstClause parses either a MATCH, CREATE or MERGE clause
 func (p *Parser) parseFirstClause() (Clause, error) {
 	switch p.current.Type {
 	case MATCH:
 		return p.parseMatchClause()
 	case CREATE:
 		return p.parseCreateClause()
+	case MERGE:
+		return p.parseMergeClause()
 	default:
-		return nil, fmt.Errorf("expected MATCH or CREATE, got \"%v\"", p.current.Literal)
+		return nil, fmt.Errorf("expected MATCH, CREATE or MERGE, got \"%v\"", p.current.Literal)
 	}
 }
 
-// parseMatchClause parses: MATCH NodeRelationshipList (WHERE KeyValuePairs)?
+// parseMatchClause parses: MATCH NodeRelationshipList (WHERE Filters)?
 func (p *Parser) parseMatchClause() (*MatchClause, error) {
 	if p.current.Type != MATCH {
 		return nil, fmt.Errorf("expected MATCH, got \"%v\"", p.current.Literal)
@@ -178,10 +357,10 @@ func (p *Parser) parseMatchClause() (*MatchClause, error) {
 		return nil, err
 	}
 
-	var filters []*KeyValuePair
+	var filters []*Filter
 	if p.current.Type == WHERE {
 		p.advance()
-		filters, err = p.parseKeyValuePairs()
+		filters, err = p.parseFilters()
 		if err != nil {
 			return nil, err
 		}
@@ -191,105 +370,531 @@ func (p *Parser) parseMatchClause() (*MatchClause, error) {
 		Nodes:         nodeRels.Nodes,
 		Relationships: nodeRels.Relationships,
 		ExtraFilters:  filters,
+		Paths:         nodeRels.Paths,
 	}, nil
 }
 
-// parseCreateClause parses: CREATE NodeRelationshipList
-func (p *Parser) parseCreateClause() (*CreateClause, error) {
-	if p.current.Type != CREATE {
-		return nil, fmt.Errorf("expected CREATE, got \"%v\"", p.current.Literal)
+// parseOptionalMatchClause parses: OPTIONAL MATCH NodeRelationshipList (WHERE Filters)?
+// The optional pattern is merged into the preceding match clause. It must be connected to
+// at least one of the match clause's nodes, and the nodes it introduces are marked optional.
+func (p *Parser) parseOptionalMatchClause(matchClause *MatchClause) error {
+	if p.current.Type != OPTIONAL {
+		return fmt.Errorf("expected OPTIONAL, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+	if p.current.Type != MATCH {
+		return fmt.Errorf("expected MATCH after OPTIONAL, got \"%v\"", p.current.Literal)
 	}
 	p.advance()
 
 	nodeRels, err := p.parseNodeRelationshipList()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if len(nodeRels.Paths) > 0 {
+		return fmt.Errorf("paths can't be bound in OPTIONAL MATCH")
 	}
 
-	return &CreateClause{
-		Nodes:         nodeRels.Nodes,
-		Relationships: nodeRels.Relationships,
-	}, nil
-}
+	boundNodes := make(map[string]*NodePattern)
+	for _, node := range matchClause.Nodes {
+		boundNodes[node.ResourceProperties.Name] = node
+	}
 
-// parseNodeRelationshipList parses node patterns and relationships
-func (p *Parser) parseNodeRelationshipList() (*NodeRelationshipList, error) {
-	var nodes []*NodePattern
-	var relationships []*Relationship
+	referencesBoundNode := false
+	optionalNodes := []string{}
+	resolve := func(node *NodePattern) (*NodePattern, error) {
+		if bound, ok := boundNodes[node.ResourceProperties.Name]; ok {
+			if !bound.Optional {
+				referencesBoundNode = true
+			}
+			return bound, nil
+		}
+		if node.ResourceProperties.Kind == "" {
+			return nil, fmt.Errorf("must specify kind for node %s", node.ResourceProperties.Name)
+		}
+		node.Optional = true
+		boundNodes[node.ResourceProperties.Name] = node
+		matchClause.Nodes = append(matchClause.Nodes, node)
+		optionalNodes = append(optionalNodes, node.ResourceProperties.Name)
+		return node, nil
+	}
 
-	debugLog("Parsing node relationship list, current token: %v", p.current.Literal)
+	for _, node := range nodeRels.Nodes {
+		if _, err := resolve(node); err != nil {
+			return err
+		}
+	}
+	for _, rel := range nodeRels.Relationships {
+		if rel.LeftNode, err = resolve(rel.LeftNode); err != nil {
+			return err
+		}
+		if rel.RightNode, err = resolve(rel.RightNode); err != nil {
+			return err
+		}
+		matchClause.Relationships = append(matchClause.Relationships, rel)
+	}
 
-	// Parse first node
-	node, err := p.parseNodePattern()
-	if err != nil {
-		return nil, err
+	if !referencesBoundNode {
+		return fmt.Errorf("OPTIONAL MATCH must reference a node from the preceding MATCH")
 	}
-	nodes = append(nodes, node)
 
-	// Check for invalid relationship tokens before entering the loop
-	if p.current.Type == '<' || (p.current.Type == '<' && p.lexer.Peek() == '<') {
-		debugLog("Found invalid relationship token: \"%v\"", p.current.Literal)
-		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+	if p.current.Type == WHERE {
+		p.advance()
+		filters, err := p.parseFilters()
+		if err != nil {
+			return err
+		}
+		// Conditions on the preceding match's nodes would drop them, defeating the optional match
+		for _, filter := range filters {
+			for _, nodeName := range getFilterNodeNames(filter) {
+				if !slices.Contains(optionalNodes, nodeName) {
+					return fmt.Errorf("OPTIONAL MATCH WHERE clause may only reference nodes introduced by the OPTIONAL MATCH, got %s", nodeName)
+				}
+			}
+		}
+		matchClause.ExtraFilters = append(matchClause.ExtraFilters, filters...)
 	}
 
-	// Parse subsequent relationships and nodes
-	for {
-		debugLog("In relationship loop, current token: %v", p.current.Literal)
+	return nil
+}
 
-		if isRelationshipStart(p.current.Type) {
-			rel, rightNode, err := p.parseRelationshipAndNode()
-			if err != nil {
-				return nil, err
-			}
-			rel.LeftNode = nodes[len(nodes)-1]
-			rel.RightNode = rightNode
-			relationships = append(relationships, rel)
-			nodes = append(nodes, rightNode)
-			continue
+// parseWithClause parses: WITH WithItems (WHERE Filters)?
+// Items are node variables bound by the preceding clauses, or aggregations with an alias.
+// The WHERE clause may reference the carried variables and aliases.
+func (p *Parser) parseWithClause(clauses []Clause) (*WithClause, error) {
+	if p.current.Type != WITH {
+		return nil, fmt.Errorf("expected WITH, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	items, err := p.parseReturnItems()
+	if err != nil {
+		return nil, err
+	}
+
+	boundNodes := availableNodes(clauses)
+	var names []string
+	for _, item := range items {
+		nodeName := strings.Split(item.JsonPath, ".")[0]
+		node, ok := boundNodes[nodeName]
+		if !ok {
+			return nil, fmt.Errorf("node %s is not bound by a preceding clause", nodeName)
+		}
+		if node.ResourceProperties.Kind == "" {
+			return nil, fmt.Errorf("WITH can't carry kind-less node %s", nodeName)
 		}
 
-		if p.current.Type == COMMA {
-			p.advance()
-			node, err := p.parseNodePattern()
-			if err != nil {
-				return nil, err
+		if item.Aggregate == "" {
+			if item.Alias != "" || item.JsonPath != nodeName || item.Function != nil {
+				projection := item.JsonPath
+				if item.Function != nil {
+					projection = item.Function.String()
+				}
+				if item.Alias != "" {
+					projection += " AS " + item.Alias
+				}
+				return nil, fmt.Errorf("WITH can't project %s, it only carries node variables and aggregations: carry %s and use its fields in WHERE or RETURN instead", projection, nodeName)
 			}
-			nodes = append(nodes, node)
+			names = append(names, nodeName)
 			continue
 		}
 
-		break
+		if item.Alias == "" {
+			return nil, fmt.Errorf("aggregations in WITH must have an alias")
+		}
+		if _, ok := boundNodes[item.Alias]; ok || slices.Contains(names, item.Alias) {
+			return nil, fmt.Errorf("alias %s is already in use", item.Alias)
+		}
+		names = append(names, item.Alias)
 	}
 
-	return &NodeRelationshipList{
-		Nodes:         nodes,
-		Relationships: relationships,
-	}, nil
+	var filters []*Filter
+	if p.current.Type == WHERE {
+		p.advance()
+		filters, err = p.parseFilters()
+		if err != nil {
+			return nil, err
+		}
+		for _, filter := range filters {
+			filterNames := getFilterNodeNames(filter)
+			if len(filterNames) > 1 {
+				return nil, fmt.Errorf("filter expressions may only reference a single node, got: %s", strings.Join(filterNames, ", "))
+			}
+			if len(filterNames) == 0 {
+				return nil, fmt.Errorf("WITH WHERE conditions must reference a variable or alias it carries")
+			}
+			if !slices.Contains(names, filterNames[0]) {
+				return nil, fmt.Errorf("WITH WHERE clause may only reference the variables and aliases it carries, got %s", filterNames[0])
+			}
+		}
+	}
+
+	return &WithClause{Items: items, Filters: filters}, nil
 }
 
-// parseNodePattern parses: LPAREN ResourceProperties RPAREN | LPAREN IDENT RPAREN
-func (p *Parser) parseNodePattern() (*NodePattern, error) {
-	debugLog("Parsing node pattern, current token: \"%v\"", p.current.Literal)
-	if p.current.Type != LPAREN {
-		return nil, fmt.Errorf("expected (, got \"%v\"", p.current.Literal)
+// parseUnwindClause parses: UNWIND ReturnPath AS IDENT (WHERE Filters)?
+// The path must lead into a node bound by the preceding clauses, or into an element bound by a
+// preceding UNWIND clause. The WHERE clause may only reference the unwound elements.
+func (p *Parser) parseUnwindClause(clauses []Clause) (*UnwindClause, error) {
+	if p.current.Type != UNWIND {
+		return nil, fmt.Errorf("expected UNWIND, got \"%v\"", p.current.Literal)
 	}
 	p.advance()
 
+	path, err := p.parseReturnPath()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(path, ".") {
+		return nil, fmt.Errorf("expected a path to a list, got %s", path)
+	}
+
+	if p.current.Type != AS {
+		return nil, fmt.Errorf("expected AS, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 	if p.current.Type != IDENT {
-		return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+		return nil, fmt.Errorf("expected identifier after AS, got \"%v\"", p.current.Literal)
 	}
-	name := p.current.Literal
+	alias := p.current.Literal
 	p.advance()
 
-	var resourceProps *ResourceProperties
-	if p.current.Type == COLON {
+	boundNodes := availableNodes(clauses)
+	unwound := availableElements(clauses)
+	variable := strings.Split(path, ".")[0]
+	if _, ok := boundNodes[variable]; !ok && !slices.Contains(unwound, variable) {
+		return nil, fmt.Errorf("%s is not bound by a preceding clause", variable)
+	}
+	if _, ok := boundNodes[alias]; ok || slices.Contains(unwound, alias) {
+		return nil, fmt.Errorf("variable %s is already bound", alias)
+	}
+
+	var filters []*Filter
+	if p.current.Type == WHERE {
 		p.advance()
-		var err error
-		resourceProps, err = p.parseResourceProperties(name)
+		filters, err = p.parseFilters()
 		if err != nil {
 			return nil, err
 		}
-		if p.current.Type != RPAREN {
+		for _, filter := range filters {
+			for _, name := range getFilterNodeNames(filter) {
+				if name != alias {
+					return nil, fmt.Errorf("UNWIND WHERE clause may only reference %s, got %s", alias, name)
+				}
+			}
+		}
+	}
+
+	return &UnwindClause{JsonPath: path, Alias: alias, Filters: filters}, nil
+}
+
+// checkWithAliases returns an error if a RETURN clause references the alias of an aggregation
+// computed by the last WITH clause, as those only hold values within WITH and its WHERE clause
+func checkWithAliases(returnClause *ReturnClause, clauses []Clause) error {
+	var with *WithClause
+	for _, clause := range clauses {
+		if c, ok := clause.(*WithClause); ok {
+			with = c
+		}
+	}
+	if with == nil {
+		return nil
+	}
+	for _, item := range returnClause.Items {
+		name := strings.Split(item.JsonPath, ".")[0]
+		for _, withItem := range with.Items {
+			if withItem.Aggregate != "" && withItem.Alias == name {
+				return fmt.Errorf("RETURN can't reference %s, the alias of an aggregation in WITH: aliases of WITH aggregations can only be used in WITH's WHERE clause", name)
+			}
+		}
+	}
+	return nil
+}
+
+// availableElements returns the variables bound by the UNWIND clauses since the last WITH clause
+func availableElements(clauses []Clause) []string {
+	var names []string
+	for _, clause := range clauses {
+		switch c := clause.(type) {
+		case *WithClause:
+			names = nil
+		case *UnwindClause:
+			names = append(names, c.Alias)
+		}
+	}
+	return names
+}
+
+// availableNodes returns the nodes bound at the end of the given clauses: the nodes
+// matched or merged since the last WITH clause, along with the nodes that clause carries.
+func availableNodes(clauses []Clause) map[string]*NodePattern {
+	nodes := make(map[string]*NodePattern)
+	for _, clause := range clauses {
+		switch c := clause.(type) {
+		case *MatchClause:
+			for _, node := range c.Nodes {
+				// A node referenced again by name has no kind of its own
+				if bound, ok := nodes[node.ResourceProperties.Name]; !ok || bound.ResourceProperties.Kind == "" {
+					nodes[node.ResourceProperties.Name] = node
+				}
+			}
+		case *WithClause:
+			carried := make(map[string]*NodePattern)
+			for _, item := range c.Items {
+				if item.Aggregate == "" {
+					carried[item.JsonPath] = nodes[item.JsonPath]
+				}
+			}
+			nodes = carried
+		case *MergeClause:
+			nodes[c.Node.ResourceProperties.Name] = c.Node
+		}
+	}
+	return nodes
+}
+
+// bindWithNodes marks the nodes of a match clause that refer to nodes carried by a
+// preceding WITH clause, and fills in their kind.
+func bindWithNodes(matchClause *MatchClause, boundNodes map[string]*NodePattern) {
+	bind := func(node *NodePattern) {
+		bound, ok := boundNodes[node.ResourceProperties.Name]
+		if !ok {
+			return
+		}
+		node.Bound = true
+		if node.ResourceProperties.Kind == "" {
+			node.ResourceProperties.Kind = bound.ResourceProperties.Kind
+		}
+	}
+
+	for _, node := range matchClause.Nodes {
+		bind(node)
+	}
+	for _, rel := range matchClause.Relationships {
+		bind(rel.LeftNode)
+		bind(rel.RightNode)
+	}
+}
+
+// parseCreateClause parses: CREATE NodeRelationshipList
+func (p *Parser) parseCreateClause() (*CreateClause, error) {
+	if p.current.Type != CREATE {
+		return nil, fmt.Errorf("expected CREATE, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	nodeRels, err := p.parseNodeRelationshipList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range nodeRels.Relationships {
+		if rel.MaxHops > 0 {
+			return nil, fmt.Errorf("variable-length relationships are not supported in CREATE")
+		}
+	}
+	if len(nodeRels.Paths) > 0 {
+		return nil, fmt.Errorf("paths can't be bound in CREATE")
+	}
+
+	return &CreateClause{
+		Nodes:         nodeRels.Nodes,
+		Relationships: nodeRels.Relationships,
+	}, nil
+}
+
+// parseMergeClause parses: MERGE NodePattern (ON (CREATE | MATCH) SET KeyValuePairs)*
+func (p *Parser) parseMergeClause() (*MergeClause, error) {
+	if p.current.Type != MERGE {
+		return nil, fmt.Errorf("expected MERGE, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	node, err := p.parseNodePattern()
+	if err != nil {
+		return nil, err
+	}
+	if node.ResourceProperties.Kind == "" {
+		return nil, fmt.Errorf("must specify kind for the node in MERGE")
+	}
+	if !hasNameProperty(node.ResourceProperties.Properties) {
+		return nil, fmt.Errorf("the node in MERGE must be identified by its name")
+	}
+
+	mergeClause := &MergeClause{Node: node}
+	for p.current.Type == ON {
+		p.advance()
+		action := p.current
+		if action.Type != CREATE && action.Type != MATCH {
+			return nil, fmt.Errorf("expected CREATE or MATCH after ON, got \"%v\"", action.Literal)
+		}
+		p.advance()
+
+		setClause, err := p.parseSetClause()
+		if err != nil {
+			return nil, err
+		}
+		for _, kvp := range setClause.KeyValuePairs {
+			if nodeName := getFilterNodeName(kvp.Key); nodeName != node.ResourceProperties.Name {
+				return nil, fmt.Errorf("ON %s SET may only reference the merged node %s, got %s", strings.ToUpper(action.Literal), node.ResourceProperties.Name, nodeName)
+			}
+		}
+
+		if action.Type == CREATE {
+			mergeClause.OnCreate = append(mergeClause.OnCreate, setClause.KeyValuePairs...)
+		} else {
+			mergeClause.OnMatch = append(mergeClause.OnMatch, setClause.KeyValuePairs...)
+		}
+	}
+
+	return mergeClause, nil
+}
+
+// hasNameProperty reports whether node properties select a resource by name
+func hasNameProperty(properties *Properties) bool {
+	if properties == nil {
+		return false
+	}
+	for _, prop := range properties.PropertyList {
+		switch strings.Trim(prop.Key, `"`) {
+		case "name", "metadata.name":
+			return true
+		}
+	}
+	return false
+}
+
+// parseNodeRelationshipList parses node patterns and relationships
+func (p *Parser) parseNodeRelationshipList() (*NodeRelationshipList, error) {
+	var nodes []*NodePattern
+	var relationships []*Relationship
+
+	var paths []*PathPattern
+
+	debugLog("Parsing node relationship list, current token: %v", p.current.Literal)
+
+	// A path variable binds the pattern that follows it, up to the next comma
+	var path *PathPattern
+	parsePathVariable := func() {
+		path = nil
+		if p.current.Type == IDENT && p.peek(1).Type == EQUALS {
+			path = &PathPattern{Name: p.current.Literal}
+			paths = append(paths, path)
+			p.advance()
+			p.advance()
+		}
+	}
+
+	// Parse first node
+	parsePathVariable()
+	node, err := p.parseNodePattern()
+	if err != nil {
+		return nil, err
+	}
+	nodes = append(nodes, node)
+	if path != nil {
+		path.Nodes = append(path.Nodes, node.ResourceProperties.Name)
+	}
+
+	// Check for invalid relationship tokens before entering the loop
+	if p.current.Type == LESS_THAN {
+		debugLog("Found invalid relationship token: \"%v\"", p.current.Literal)
+		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+	}
+
+	// Parse subsequent relationships and nodes
+	for {
+		debugLog("In relationship loop, current token: %v", p.current.Literal)
+
+		if isRelationshipStart(p.current.Type) {
+			rel, rightNode, err := p.parseRelationshipAndNode()
+			if err != nil {
+				return nil, err
+			}
+			rel.LeftNode = nodes[len(nodes)-1]
+			rel.RightNode = rightNode
+			relationships = append(relationships, rel)
+			nodes = append(nodes, rightNode)
+			if path != nil {
+				path.Nodes = append(path.Nodes, rightNode.ResourceProperties.Name)
+				path.Relationships = append(path.Relationships, rel)
+			}
+			continue
+		}
+
+		if p.current.Type == COMMA {
+			p.advance()
+			parsePathVariable()
+			node, err := p.parseNodePattern()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			if path != nil {
+				path.Nodes = append(path.Nodes, node.ResourceProperties.Name)
+			}
+			continue
+		}
+
+		break
+	}
+
+	// Path variables can't share their name with another variable
+	for i, path := range paths {
+		for _, node := range nodes {
+			if node.ResourceProperties.Name == path.Name {
+				return nil, fmt.Errorf("path variable %s is already bound to a node", path.Name)
+			}
+		}
+		for _, rel := range relationships {
+			if rel.ResourceProperties != nil && rel.ResourceProperties.Name == path.Name {
+				return nil, fmt.Errorf("path variable %s is already bound to a relationship", path.Name)
+			}
+		}
+		for _, other := range paths[:i] {
+			if other.Name == path.Name {
+				return nil, fmt.Errorf("path variable %s is bound more than once", path.Name)
+			}
+		}
+	}
+
+	return &NodeRelationshipList{
+		Nodes:         nodes,
+		Relationships: relationships,
+		Paths:         paths,
+	}, nil
+}
+
+// parseNodePattern parses: LPAREN ResourceProperties RPAREN | LPAREN IDENT RPAREN
+func (p *Parser) parseNodePattern() (*NodePattern, error) {
+	debugLog("Parsing node pattern, current token: \"%v\"", p.current.Literal)
+	if p.current.Type != LPAREN {
+		return nil, fmt.Errorf("expected (, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	var name string
+	switch p.current.Type {
+	case IDENT:
+		name = p.current.Literal
+		p.advance()
+	case COLON, RPAREN:
+		// Anonymous nodes are matched like any other node, but can't be referenced
+		p.anonymousNodes++
+		name = fmt.Sprintf("_anon%d", p.anonymousNodes)
+	default:
+		return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	}
+
+	var resourceProps *ResourceProperties
+	if p.current.Type == COLON {
+		p.advance()
+		var err error
+		resourceProps, err = p.parseResourceProperties(name)
+		if err != nil {
+			return nil, err
+		}
+		if p.current.Type != RPAREN {
 			return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
 		}
 		p.advance()
@@ -303,7 +908,7 @@ func (p *Parser) parseNodePattern() (*NodePattern, error) {
 
 	// Check for invalid relationship tokens immediately after closing parenthesis
 	debugLog("After node pattern, checking next token: \"%v\"", p.current.Literal)
-	if p.current.Type == '<' {
+	if p.current.Type == LESS_THAN {
 		debugLog("Found invalid relationship token after node pattern")
 		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
 	}
@@ -313,11 +918,22 @@ func (p *Parser) parseNodePattern() (*NodePattern, error) {
 
 // parseResourceProperties parses the properties of a node or relationship
 func (p *Parser) parseResourceProperties(name string) (*ResourceProperties, error) {
-	if p.current.Type != IDENT {
+	var kind string
+	switch p.current.Type {
+	case IDENT:
+		kind = p.current.Literal
+		p.advance()
+	case PARAM:
+		value, err := p.parseParam()
+		if err != nil {
+			return nil, err
+		}
+		if kind, _ = value.(string); kind == "" {
+			return nil, fmt.Errorf("parameter used as kind must be a non-empty string, got %v", value)
+		}
+	default:
 		return nil, fmt.Errorf("expected kind identifier, got \"%v\"", p.current.Literal)
 	}
-	kind := p.current.Literal
-	p.advance()
 
 	var properties *Properties
 	var jsonData string
@@ -353,6 +969,16 @@ func (p *Parser) parseResourceProperties(name string) (*ResourceProperties, erro
 					jsonBuilder.WriteString("]")
 				case NUMBER:
 					jsonBuilder.WriteString(p.current.Literal)
+				case PARAM:
+					value, ok := p.params[p.current.Literal]
+					if !ok {
+						return nil, fmt.Errorf("missing value for parameter $%s", p.current.Literal)
+					}
+					encoded, err := json.Marshal(value)
+					if err != nil {
+						return nil, fmt.Errorf("encoding parameter $%s: %w", p.current.Literal, err)
+					}
+					jsonBuilder.Write(encoded)
 				default:
 					return nil, fmt.Errorf("unexpected token in JSON: \"%v\"", p.current.Literal)
 				}
@@ -397,14 +1023,44 @@ func isRelationshipStart(t TokenType) bool {
 
 // Helper method to advance the lexer
 func (p *Parser) advance() {
-	p.current = p.lexer.NextToken()
+	if len(p.peeked) > 0 {
+		p.current = p.peeked[0]
+		p.peeked = p.peeked[1:]
+	} else {
+		p.current = p.lexer.NextToken()
+	}
 	p.pos++
 }
 
+// peek returns the n-th token after the current token without consuming it
+func (p *Parser) peek(n int) Token {
+	for len(p.peeked) < n {
+		p.peeked = append(p.peeked, p.lexer.NextToken())
+	}
+	return p.peeked[n-1]
+}
+
 // parseRelationshipAndNode parses a relationship token followed by a node pattern
 func (p *Parser) parseRelationshipAndNode() (*Relationship, *NodePattern, error) {
+	rel, err := p.parseRelationship()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Parse the right node
+	rightNode, err := p.parseNodePattern()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rel, rightNode, nil
+}
+
+// parseRelationship parses a relationship token, including its properties and hop range if present
+func (p *Parser) parseRelationship() (*Relationship, error) {
 	var direction Direction
 	var resourceProps *ResourceProperties
+	var minHops, maxHops int
 
 	// Determine relationship direction and properties based on token type
 	switch p.current.Type {
@@ -420,95 +1076,497 @@ func (p *Parser) parseRelationshipAndNode() (*Relationship, *NodePattern, error)
 		direction = Left
 		p.advance()
 		var err error
-		resourceProps, err = p.parseRelationshipProperties()
+		resourceProps, minHops, maxHops, err = p.parseRelationshipDetails()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		if p.current.Type != REL_ENDPROPS_NONE {
-			return nil, nil, fmt.Errorf("expected relationship end token, got \"%v\"", p.current.Literal)
+			return nil, fmt.Errorf("expected relationship end token, got \"%v\"", p.current.Literal)
 		}
 	case REL_BEGINPROPS_NONE:
-		direction = Right
 		p.advance()
 		var err error
-		resourceProps, err = p.parseRelationshipProperties()
+		resourceProps, minHops, maxHops, err = p.parseRelationshipDetails()
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		if p.current.Type != REL_ENDPROPS_RIGHT {
-			return nil, nil, fmt.Errorf("expected relationship end token, got \"%v\"", p.current.Literal)
+		switch p.current.Type {
+		case REL_ENDPROPS_RIGHT:
+			direction = Right
+		case REL_ENDPROPS_NONE:
+			direction = None
+		default:
+			return nil, fmt.Errorf("expected relationship end token, got \"%v\"", p.current.Literal)
 		}
 	default:
-		return nil, nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
+		return nil, fmt.Errorf("unexpected relationship token: \"%v\"", p.current.Literal)
 	}
 	p.advance()
 
-	// Parse the right node
-	rightNode, err := p.parseNodePattern()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	rel := &Relationship{
+	return &Relationship{
 		ResourceProperties: resourceProps,
 		Direction:          direction,
-	}
-
-	return rel, rightNode, nil
+		MinHops:            minHops,
+		MaxHops:            maxHops,
+	}, nil
 }
 
-// parseSetClause parses: SET KeyValuePairs
-func (p *Parser) parseSetClause() (*SetClause, error) {
-	if p.current.Type != SET {
-		return nil, fmt.Errorf("expected SET, got \"%v\"", p.current.Literal)
+// parseRelationshipDetails parses the inside of a relationship's brackets: RelationshipProperties? HopRange?
+func (p *Parser) parseRelationshipDetails() (*ResourceProperties, int, int, error) {
+	var resourceProps *ResourceProperties
+	if !p.isWildcard() {
+		var err error
+		resourceProps, err = p.parseRelationshipProperties()
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	if !p.isWildcard() {
+		return resourceProps, 0, 0, nil
 	}
 	p.advance()
 
-	pairs, err := p.parseKeyValuePairs()
+	minHops, maxHops, err := p.parseHopRange()
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
-
-	return &SetClause{KeyValuePairs: pairs}, nil
+	return resourceProps, minHops, maxHops, nil
 }
 
-// parseDeleteClause parses: DELETE NodeIds
-func (p *Parser) parseDeleteClause() (*DeleteClause, error) {
-	if p.current.Type != DELETE {
-		return nil, fmt.Errorf("expected DELETE, got \"%v\"", p.current.Literal)
-	}
-	p.advance()
+// parseHopRange parses the hop range following the * of a variable-length relationship:
+// (NUMBER)? (DOTDOT (NUMBER)?)?
+// A missing lower bound defaults to 1 and a missing upper bound to defaultMaxHops.
+func (p *Parser) parseHopRange() (int, int, error) {
+	minHops, maxHops := 1, defaultMaxHops
 
-	var nodeIds []string
-	for {
-		if p.current.Type != IDENT {
-			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	if p.current.Type == NUMBER {
+		hops, err := p.parseCount("*")
+		if err != nil {
+			return 0, 0, err
 		}
-		nodeIds = append(nodeIds, p.current.Literal)
-		p.advance()
+		minHops, maxHops = hops, hops
+	}
 
-		if p.current.Type != COMMA {
+	if p.current.Type == DOTDOT {
+		p.advance()
+		maxHops = max(defaultMaxHops, minHops)
+		if p.current.Type == NUMBER {
+			hops, err := p.parseCount("..")
+			if err != nil {
+				return 0, 0, err
+			}
+			maxHops = hops
+		}
+	}
+
+	if minHops < 1 {
+		return 0, 0, fmt.Errorf("variable-length relationships must have at least 1 hop, got %d", minHops)
+	}
+	if maxHops < minHops {
+		return 0, 0, fmt.Errorf("invalid hop range: maximum %d is less than minimum %d", maxHops, minHops)
+	}
+	return minHops, maxHops, nil
+}
+
+func (p *Parser) isWildcard() bool {
+	return p.current.Type == ILLEGAL && p.current.Literal == "*"
+}
+
+// parseSetClause parses: SET SetItem (COMMA SetItem)*
+func (p *Parser) parseSetClause() (*SetClause, error) {
+	if p.current.Type != SET {
+		return nil, fmt.Errorf("expected SET, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	var pairs []*KeyValuePair
+	for {
+		pair, err := p.parseSetItem()
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+
+		if p.current.Type != COMMA {
 			break
 		}
 		p.advance()
 	}
 
-	return &DeleteClause{NodeIds: nodeIds}, nil
+	return &SetClause{KeyValuePairs: pairs}, nil
+}
+
+// parseSetItem parses: JsonPath EQUALS ValueExpression
+func (p *Parser) parseSetItem() (*KeyValuePair, error) {
+	key, err := p.parseKeyPath()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(key, "[*]") {
+		return nil, fmt.Errorf("wildcards are not supported in SET, got %s", key)
+	}
+
+	if p.current.Type != EQUALS {
+		return nil, fmt.Errorf("expected = after %s, got \"%v\"", key, p.current.Literal)
+	}
+	p.advance()
+
+	value, err := p.parseValueExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyValuePair{
+		Key:      key,
+		Value:    value,
+		Operator: "EQUALS",
+	}, nil
+}
+
+// parseValueExpression parses: Term (('+' | '-') Term)*
+func (p *Parser) parseValueExpression() (interface{}, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isArithmeticOperator("+", "-") {
+		operator := p.current.Literal
+		p.advance()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithmeticExpression{Operator: operator, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseTerm parses: Factor (('*' | '/') Factor)*
+func (p *Parser) parseTerm() (interface{}, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isArithmeticOperator("*", "/") {
+		operator := p.current.Literal
+		p.advance()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &ArithmeticExpression{Operator: operator, Left: left, Right: right}
+	}
+	return left, nil
 }
 
-// parseReturnClause parses: RETURN ReturnItems
+// parseFactor parses: LPAREN ValueExpression RPAREN | '-'? NUMBER | FieldReference | Value
+func (p *Parser) parseFactor() (interface{}, error) {
+	switch {
+	case p.current.Type == LPAREN:
+		p.advance()
+		value, err := p.parseValueExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.Type != RPAREN {
+			return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+		return value, nil
+
+	case p.isNumberStart():
+		return p.parseNumber()
+
+	case p.current.Type == IDENT && p.peek(1).Type == LPAREN:
+		return p.parseFunctionCall()
+
+	case p.current.Type == IDENT:
+		jsonPath, err := p.parseReturnPath()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(jsonPath, ".") {
+			return nil, fmt.Errorf("expected a field reference, got %s", jsonPath)
+		}
+		if strings.Contains(jsonPath, "[*]") {
+			return nil, fmt.Errorf("wildcards are not supported in field references, got %s", jsonPath)
+		}
+		return &FieldReference{JsonPath: jsonPath}, nil
+
+	default:
+		return p.parseValue()
+	}
+}
+
+// isNumberStart reports whether the current token starts a number: '-'? NUMBER
+func (p *Parser) isNumberStart() bool {
+	return p.current.Type == NUMBER || (p.isArithmeticOperator("-") && p.peek(1).Type == NUMBER)
+}
+
+// parseNumber parses: '-'? NUMBER as an int, or as a float64 if it has a fraction
+func (p *Parser) parseNumber() (interface{}, error) {
+	sign := ""
+	if p.isArithmeticOperator("-") {
+		sign = "-"
+		p.advance()
+	}
+	literal := sign + p.current.Literal
+	p.advance()
+	if i, err := strconv.Atoi(literal); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", literal)
+	}
+	return f, nil
+}
+
+// parseFunctionCall parses: IDENT LPAREN (FunctionArgument (COMMA FunctionArgument)*)? RPAREN
+func (p *Parser) parseFunctionCall() (*FunctionCall, error) {
+	name := p.current.Literal
+	fn, ok := lookupFunction(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	p.advance()
+	if p.current.Type != LPAREN {
+		return nil, fmt.Errorf("expected ( after %s, got \"%v\"", name, p.current.Literal)
+	}
+	p.advance()
+
+	call := &FunctionCall{Name: name, Args: []interface{}{}}
+	for p.current.Type != RPAREN {
+		arg, err := p.parseFunctionArgument()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+	if p.current.Type != RPAREN {
+		return nil, fmt.Errorf("expected ) after arguments of %s, got \"%v\"", name, p.current.Literal)
+	}
+	p.advance()
+
+	if err := checkArity(name, fn, len(call.Args)); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// parseFunctionArgument parses: FunctionCall | ReturnPath | '-'? NUMBER | Value
+func (p *Parser) parseFunctionArgument() (interface{}, error) {
+	switch {
+	case p.current.Type == IDENT && p.peek(1).Type == LPAREN:
+		return p.parseFunctionCall()
+	case p.current.Type == IDENT:
+		jsonPath, err := p.parseReturnPath()
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(jsonPath, "[*]") {
+			return nil, fmt.Errorf("wildcards are not supported in field references, got %s", jsonPath)
+		}
+		return &FieldReference{JsonPath: jsonPath}, nil
+	case p.isNumberStart():
+		return p.parseNumber()
+	default:
+		return p.parseValue()
+	}
+}
+
+// parseFunctionItem parses a function call that's a RETURN item or the subject of a WHERE
+// condition, and returns it along with the single node it refers to
+func (p *Parser) parseFunctionItem(clause string) (*FunctionCall, string, error) {
+	call, err := p.parseFunctionCall()
+	if err != nil {
+		return nil, "", err
+	}
+	nodeIds := functionNodeIds(call)
+	if len(nodeIds) != 1 {
+		return nil, "", fmt.Errorf("function calls in %s must reference exactly one node, got %s", clause, call)
+	}
+	return call, nodeIds[0], nil
+}
+
+// isArithmeticOperator reports whether the current token is one of the given arithmetic
+// operators. Like wildcards, arithmetic operators are lexed as ILLEGAL tokens.
+func (p *Parser) isArithmeticOperator(operators ...string) bool {
+	return p.current.Type == ILLEGAL && slices.Contains(operators, p.current.Literal)
+}
+
+// parseRemoveClause parses: REMOVE JsonPath (COMMA JsonPath)*
+func (p *Parser) parseRemoveClause() (*RemoveClause, error) {
+	if p.current.Type != REMOVE {
+		return nil, fmt.Errorf("expected REMOVE, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	var jsonPaths []string
+	for {
+		jsonPath, err := p.parseReturnPath()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(jsonPath, ".") {
+			return nil, fmt.Errorf("REMOVE items must be paths to fields, got %s", jsonPath)
+		}
+		if strings.Contains(jsonPath, "[*]") {
+			return nil, fmt.Errorf("wildcards are not supported in REMOVE, got %s", jsonPath)
+		}
+		jsonPaths = append(jsonPaths, jsonPath)
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return &RemoveClause{JsonPaths: jsonPaths}, nil
+}
+
+// parseDeleteClause parses: DETACH? DELETE NodeIds
+func (p *Parser) parseDeleteClause() (*DeleteClause, error) {
+	detach := p.current.Type == DETACH
+	if detach {
+		p.advance()
+	}
+	if p.current.Type != DELETE {
+		return nil, fmt.Errorf("expected DELETE, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	var nodeIds []string
+	for {
+		if p.current.Type != IDENT {
+			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+		}
+		nodeIds = append(nodeIds, p.current.Literal)
+		p.advance()
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return &DeleteClause{NodeIds: nodeIds, Detach: detach}, nil
+}
+
+// parseReturnClause parses: RETURN DISTINCT? ReturnItems
 func (p *Parser) parseReturnClause() (*ReturnClause, error) {
 	if p.current.Type != RETURN {
 		return nil, fmt.Errorf("expected RETURN, got \"%v\"", p.current.Literal)
 	}
 	p.advance()
 
+	distinct := p.current.Type == DISTINCT
+	if distinct {
+		p.advance()
+	}
+
 	items, err := p.parseReturnItems()
 	if err != nil {
 		return nil, err
 	}
+	returnClause := &ReturnClause{Items: items, Distinct: distinct}
+
+	if p.current.Type == ORDER {
+		p.advance()
+		if p.current.Type != BY {
+			return nil, fmt.Errorf("expected BY after ORDER, got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+
+		orderBy, err := p.parseOrderItems()
+		if err != nil {
+			return nil, err
+		}
+		// Sort keys may refer to a return item by its alias
+		for _, order := range orderBy {
+			if strings.Contains(order.JsonPath, ".") {
+				continue
+			}
+			for _, item := range items {
+				if item.Alias == order.JsonPath && item.Aggregate == "" {
+					order.JsonPath = item.JsonPath
+					order.Function = item.Function
+					break
+				}
+			}
+		}
+		returnClause.OrderBy = orderBy
+	}
+
+	if p.current.Type == SKIP {
+		p.advance()
+		skip, err := p.parseCount("SKIP")
+		if err != nil {
+			return nil, err
+		}
+		returnClause.Skip = skip
+	}
+
+	if p.current.Type == LIMIT {
+		p.advance()
+		limit, err := p.parseCount("LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		returnClause.Limit = &limit
+	}
+
+	return returnClause, nil
+}
+
+// parseOrderItems parses: ReturnPath (ASC | DESC)? (COMMA ReturnPath (ASC | DESC)?)*
+func (p *Parser) parseOrderItems() ([]*OrderItem, error) {
+	var items []*OrderItem
+
+	for {
+		path, err := p.parseReturnPath()
+		if err != nil {
+			return nil, err
+		}
+		item := &OrderItem{JsonPath: path}
+
+		switch p.current.Type {
+		case ASC:
+			p.advance()
+		case DESC:
+			item.Descending = true
+			p.advance()
+		}
+		items = append(items, item)
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
 
-	return &ReturnClause{Items: items}, nil
+	return items, nil
+}
+
+// parseCount parses the non-negative integer following SKIP or LIMIT
+func (p *Parser) parseCount(keyword string) (int, error) {
+	if p.current.Type != NUMBER {
+		return 0, fmt.Errorf("expected number after %s, got \"%v\"", keyword, p.current.Literal)
+	}
+	count, err := strconv.Atoi(p.current.Literal)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %v", keyword, err)
+	}
+	p.advance()
+	return count, nil
 }
 
 // parseReturnItems parses a list of return items
@@ -518,8 +1576,15 @@ func (p *Parser) parseReturnItems() ([]*ReturnItem, error) {
 	for {
 		var item ReturnItem
 
-		// Check for aggregation functions
-		if p.current.Type == COUNT || p.current.Type == SUM {
+		// Check for function calls
+		if p.current.Type == IDENT && p.peek(1).Type == LPAREN {
+			call, nodeId, err := p.parseFunctionItem("RETURN")
+			if err != nil {
+				return nil, err
+			}
+			item.Function = call
+			item.JsonPath = nodeId
+		} else if isAggregateToken(p.current.Type) {
 			item.Aggregate = strings.ToUpper(p.current.Literal)
 			p.advance()
 
@@ -527,230 +1592,639 @@ func (p *Parser) parseReturnItems() ([]*ReturnItem, error) {
 				return nil, fmt.Errorf("expected {, got \"%v\"", p.current.Literal)
 			}
 			p.advance()
+
+			if p.current.Type == DISTINCT {
+				item.Distinct = true
+				p.advance()
+			}
+		}
+
+		if item.Function == nil {
+			path, err := p.parseReturnPath()
+			if err != nil {
+				return nil, err
+			}
+			item.JsonPath = path
+		}
+
+		// Handle closing brace for aggregation
+		if item.Aggregate != "" {
+			if p.current.Type != RBRACE {
+				return nil, fmt.Errorf("expected }, got \"%v\"", p.current.Literal)
+			}
+			p.advance()
+		}
+
+		// Check for AS alias
+		if p.current.Type == AS {
+			p.advance()
+			if p.current.Type != IDENT {
+				return nil, fmt.Errorf("expected identifier after AS, got \"%v\"", p.current.Literal)
+			}
+			item.Alias = p.current.Literal
+			p.advance()
+		}
+
+		items = append(items, &item)
+
+		if p.current.Type != COMMA {
+			break
 		}
+		p.advance()
+	}
+
+	return items, nil
+}
+
+func isAggregateToken(t TokenType) bool {
+	switch t {
+	case COUNT, SUM, AVG, MIN, MAX, COLLECT:
+		return true
+	}
+	return false
+}
+
+// parseReturnPath parses a node reference optionally followed by a JSONPath: IDENT (DOT IDENT (LBRACKET (NUMBER | *) RBRACKET)?)*
+func (p *Parser) parseReturnPath() (string, error) {
+	if p.current.Type != IDENT {
+		return "", fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	}
+	nodeRef := p.current.Literal
+	p.advance()
+
+	// Handle full node reference or path
+	if p.current.Type != DOT {
+		return nodeRef, nil
+	}
+	p.advance()
 
-		// Parse node reference
+	var path strings.Builder
+	path.WriteString(nodeRef)
+	path.WriteString(".")
+
+	for {
 		if p.current.Type != IDENT {
-			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+			return "", fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
 		}
-		nodeRef := p.current.Literal
+		path.WriteString(p.current.Literal)
 		p.advance()
 
-		// Handle full node reference or path
-		if p.current.Type == DOT {
+		// Handle array indices and dots
+		if p.current.Type == LBRACKET {
 			p.advance()
-			var path strings.Builder
-			path.WriteString(nodeRef)
-			path.WriteString(".")
+			path.WriteString("[")
 
-			for {
-				if p.current.Type != IDENT {
-					return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
-				}
+			// Handle wildcard [*]
+			if p.current.Type == ILLEGAL && p.current.Literal == "*" {
+				path.WriteString("*")
+				p.advance()
+			} else if p.current.Type != NUMBER {
+				return "", fmt.Errorf("expected number or * in array index, got \"%v\"", p.current.Literal)
+			} else {
 				path.WriteString(p.current.Literal)
 				p.advance()
+			}
 
-				// Handle array indices and dots
-				if p.current.Type == LBRACKET {
-					p.advance()
-					path.WriteString("[")
-
-					// Handle wildcard [*]
-					if p.current.Type == ILLEGAL && p.current.Literal == "*" {
-						path.WriteString("*")
-						p.advance()
-					} else if p.current.Type != NUMBER {
-						return nil, fmt.Errorf("expected number or * in array index, got \"%v\"", p.current.Literal)
-					} else {
-						path.WriteString(p.current.Literal)
-						p.advance()
-					}
+			if p.current.Type != RBRACKET {
+				return "", fmt.Errorf("expected closing bracket, got \"%v\"", p.current.Literal)
+			}
+			path.WriteString("]")
+			p.advance()
+		}
 
-					if p.current.Type != RBRACKET {
-						return nil, fmt.Errorf("expected closing bracket, got \"%v\"", p.current.Literal)
-					}
-					path.WriteString("]")
-					p.advance()
-				}
+		if p.current.Type != DOT {
+			break
+		}
+		p.advance()
+		path.WriteString(".")
+	}
 
-				if p.current.Type != DOT {
-					break
-				}
-				p.advance()
-				path.WriteString(".")
+	return path.String(), nil
+}
+
+// parseContexts parses a list of context identifiers
+func (p *Parser) parseContexts() ([]string, error) {
+	var contexts []string
+	var currentContext strings.Builder
+
+	for {
+		if p.current.Type != IDENT {
+			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+		}
+
+		// Start building the context name
+		currentContext.WriteString(p.current.Literal)
+		p.advance()
+
+		// Handle dashed names specifically in contexts
+		for p.current.Type == IDENT && p.current.Literal == "-" {
+			currentContext.WriteString("-")
+			p.advance()
+
+			if p.current.Type != IDENT {
+				return nil, fmt.Errorf("expected identifier after dash, got \"%v\"", p.current.Literal)
 			}
-			item.JsonPath = path.String()
-		} else {
-			item.JsonPath = nodeRef
+			currentContext.WriteString(p.current.Literal)
+			p.advance()
 		}
 
-		// Handle closing brace for aggregation
-		if item.Aggregate != "" {
-			if p.current.Type != RBRACE {
-				return nil, fmt.Errorf("expected }, got \"%v\"", p.current.Literal)
+		contexts = append(contexts, currentContext.String())
+		currentContext.Reset()
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return contexts, nil
+}
+
+// parseProperties parses a list of key-value properties
+func (p *Parser) parseProperties() (*Properties, error) {
+	var propertyList []*Property
+
+	for {
+		if p.current.Type != IDENT && p.current.Type != STRING {
+			return nil, fmt.Errorf("expected property key, got \"%v\"", p.current.Literal)
+		}
+		key := p.current.Literal
+		p.advance()
+
+		if p.current.Type != COLON {
+			return nil, fmt.Errorf("expected :, got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		propertyList = append(propertyList, &Property{
+			Key:   key,
+			Value: value,
+		})
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return &Properties{PropertyList: propertyList}, nil
+}
+
+// parseKeyValuePairs parses a list of key-value pairs with operators
+func (p *Parser) parseKeyValuePairs() ([]*KeyValuePair, error) {
+	var pairs []*KeyValuePair
+
+	for {
+		pair, err := p.parseKeyValuePair()
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return pairs, nil
+}
+
+// parseKeyValuePair parses a single JSONPath, operator and value
+func (p *Parser) parseKeyValuePair() (*KeyValuePair, error) {
+	key, err := p.parseKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	operator, value, err := p.parseComparison(getFilterNodeName(key))
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyValuePair{
+		Key:      key,
+		Value:    value,
+		Operator: operator,
+	}, nil
+}
+
+// parseKeyPath parses the JSONPath of a key-value pair. Array indices may be a wildcard [*].
+func (p *Parser) parseKeyPath() (string, error) {
+	if p.current.Type != IDENT {
+		return "", fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	}
+	var path strings.Builder
+	path.WriteString(p.current.Literal)
+	p.advance()
+
+	for {
+		if p.current.Type == DOT {
+			p.advance()
+			path.WriteString(".")
+			if p.current.Type != IDENT {
+				return "", fmt.Errorf("expected identifier after dot, got \"%v\"", p.current.Literal)
 			}
+			path.WriteString(p.current.Literal)
 			p.advance()
+		} else if p.current.Type == LBRACKET {
+			p.advance()
+			path.WriteString("[")
+			if p.current.Type != NUMBER && (p.current.Type != ILLEGAL || p.current.Literal != "*") {
+				return "", fmt.Errorf("expected number or * in array index, got \"%v\"", p.current.Literal)
+			}
+			path.WriteString(p.current.Literal)
+			p.advance()
+			if p.current.Type != RBRACKET {
+				return "", fmt.Errorf("expected closing bracket, got \"%v\"", p.current.Literal)
+			}
+			path.WriteString("]")
+			p.advance()
+			if p.current.Type == DOT {
+				continue
+			}
+		} else {
+			break
+		}
+	}
+
+	return path.String(), nil
+}
+
+// parseFilters parses: FilterExpression (COMMA FilterExpression)*
+// The returned filters are implicitly ANDed, top-level AND expressions are flattened into the list.
+func (p *Parser) parseFilters() ([]*Filter, error) {
+	var filters []*Filter
+
+	for {
+		filter, err := p.parseOrFilter()
+		if err != nil {
+			return nil, err
+		}
+		if filter.Type == AndFilter {
+			filters = append(filters, filter.Operands...)
+		} else {
+			filters = append(filters, filter)
+		}
+
+		if p.current.Type != COMMA {
+			break
+		}
+		p.advance()
+	}
+
+	return filters, nil
+}
+
+// parseOrFilter parses: AndFilter (OR AndFilter)*
+func (p *Parser) parseOrFilter() (*Filter, error) {
+	left, err := p.parseAndFilter()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.Type != OR {
+		return left, nil
+	}
+
+	operands := []*Filter{left}
+	for p.current.Type == OR {
+		p.advance()
+		right, err := p.parseAndFilter()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+
+	return &Filter{Type: OrFilter, Operands: operands}, nil
+}
+
+// parseAndFilter parses: NotFilter (AND NotFilter)*
+func (p *Parser) parseAndFilter() (*Filter, error) {
+	left, err := p.parseNotFilter()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.Type != AND {
+		return left, nil
+	}
+
+	operands := []*Filter{left}
+	for p.current.Type == AND {
+		p.advance()
+		right, err := p.parseNotFilter()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+
+	return &Filter{Type: AndFilter, Operands: operands}, nil
+}
+
+// parseNotFilter parses: NOT NotFilter | LPAREN Filters RPAREN | KeyValuePair
+func (p *Parser) parseNotFilter() (*Filter, error) {
+	switch p.current.Type {
+	case NOT:
+		p.advance()
+		operand, err := p.parseNotFilter()
+		if err != nil {
+			return nil, err
+		}
+		return &Filter{Type: NotFilter, Operands: []*Filter{operand}}, nil
+
+	case EXISTS:
+		return p.parseExistsFilter()
+
+	case ANY:
+		return p.parseAnyFilter()
+
+	case LPAREN:
+		if p.isPatternStart() {
+			pattern, err := p.parsePatternPredicate()
+			if err != nil {
+				return nil, err
+			}
+			return &Filter{Type: PatternFilter, Pattern: pattern}, nil
+		}
+
+		p.advance()
+		filters, err := p.parseFilters()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.Type != RPAREN {
+			return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+		if len(filters) == 1 {
+			return filters[0], nil
 		}
+		return &Filter{Type: AndFilter, Operands: filters}, nil
 
-		// Check for AS alias
-		if p.current.Type == AS {
-			p.advance()
-			if p.current.Type != IDENT {
-				return nil, fmt.Errorf("expected identifier after AS, got \"%v\"", p.current.Literal)
+	default:
+		if p.current.Type == IDENT && p.peek(1).Type == LPAREN {
+			pair, err := p.parseFunctionCondition()
+			if err != nil {
+				return nil, err
 			}
-			item.Alias = p.current.Literal
-			p.advance()
+			return &Filter{Type: KeyValuePairFilter, KeyValuePair: pair}, nil
 		}
 
-		items = append(items, &item)
-
-		if p.current.Type != COMMA {
-			break
+		pair, err := p.parseKeyValuePair()
+		if err != nil {
+			return nil, err
 		}
-		p.advance()
+		return &Filter{Type: KeyValuePairFilter, KeyValuePair: pair}, nil
 	}
+}
 
-	return items, nil
+// parseComparison parses the operator and value of a WHERE condition on a node:
+// IS NOT? NULL | IN List | Operator ConditionValue
+func (p *Parser) parseComparison(nodeId string) (string, interface{}, error) {
+	operator, err := p.parseOperator()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var value interface{}
+	switch operator {
+	case "IS_NULL", "IS_NOT_NULL":
+		return operator, nil, nil
+	case "IN":
+		value, err = p.parseList()
+	default:
+		value, err = p.parseConditionValue(nodeId)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return operator, value, nil
 }
 
-// parseContexts parses a list of context identifiers
-func (p *Parser) parseContexts() ([]string, error) {
-	var contexts []string
-	var currentContext strings.Builder
+// parseExistsFilter parses: EXISTS LPAREN (PatternPredicate | KeyPath) RPAREN
+// A field exists when it's set to a value other than NULL.
+func (p *Parser) parseExistsFilter() (*Filter, error) {
+	p.advance()
+	if p.current.Type != LPAREN {
+		return nil, fmt.Errorf("expected ( after EXISTS, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 
-	for {
-		if p.current.Type != IDENT {
-			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	var filter *Filter
+	if p.isPatternStart() {
+		pattern, err := p.parsePatternPredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter = &Filter{Type: PatternFilter, Pattern: pattern}
+	} else {
+		key, err := p.parseKeyPath()
+		if err != nil {
+			return nil, err
 		}
+		filter = &Filter{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: key, Operator: "IS_NOT_NULL"}}
+	}
 
-		// Start building the context name
-		currentContext.WriteString(p.current.Literal)
-		p.advance()
+	if p.current.Type != RPAREN {
+		return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 
-		// Handle dashed names specifically in contexts
-		for p.current.Type == IDENT && p.current.Literal == "-" {
-			currentContext.WriteString("-")
-			p.advance()
+	return filter, nil
+}
 
-			if p.current.Type != IDENT {
-				return nil, fmt.Errorf("expected identifier after dash, got \"%v\"", p.current.Literal)
-			}
-			currentContext.WriteString(p.current.Literal)
-			p.advance()
-		}
+// parseAnyFilter parses: ANY LPAREN IDENT IN KeyPath WHERE Filters RPAREN
+// The conditions refer to the elements of the list by the variable, and may not reference any other node.
+func (p *Parser) parseAnyFilter() (*Filter, error) {
+	p.advance()
+	if p.current.Type != LPAREN {
+		return nil, fmt.Errorf("expected ( after ANY, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 
-		contexts = append(contexts, currentContext.String())
-		currentContext.Reset()
+	if p.current.Type != IDENT {
+		return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+	}
+	variable := p.current.Literal
+	p.advance()
 
-		if p.current.Type != COMMA {
-			break
-		}
-		p.advance()
+	if p.current.Type != IN {
+		return nil, fmt.Errorf("expected IN, got \"%v\"", p.current.Literal)
 	}
+	p.advance()
 
-	return contexts, nil
-}
+	listPath, err := p.parseKeyPath()
+	if err != nil {
+		return nil, err
+	}
 
-// parseProperties parses a list of key-value properties
-func (p *Parser) parseProperties() (*Properties, error) {
-	var propertyList []*Property
+	if p.current.Type != WHERE {
+		return nil, fmt.Errorf("expected WHERE, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 
-	for {
-		if p.current.Type != IDENT && p.current.Type != STRING {
-			return nil, fmt.Errorf("expected property key, got \"%v\"", p.current.Literal)
+	filters, err := p.parseFilters()
+	if err != nil {
+		return nil, err
+	}
+	condition := &Filter{Type: AndFilter, Operands: filters}
+	if len(filters) == 1 {
+		condition = filters[0]
+	}
+	for _, name := range getFilterNodeNames(condition) {
+		if name != variable {
+			return nil, fmt.Errorf("ANY conditions may only reference %s, got %s", variable, name)
 		}
-		key := p.current.Literal
-		p.advance()
+	}
 
-		if p.current.Type != COLON {
-			return nil, fmt.Errorf("expected :, got \"%v\"", p.current.Literal)
-		}
-		p.advance()
+	if p.current.Type != RPAREN {
+		return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
+	}
+	p.advance()
 
-		value, err := p.parseValue()
-		if err != nil {
-			return nil, err
-		}
+	return &Filter{Type: AnyFilter, Variable: variable, ListPath: listPath, Operands: []*Filter{condition}}, nil
+}
 
-		propertyList = append(propertyList, &Property{
-			Key:   key,
-			Value: value,
-		})
+// parseConditionValue parses the value a WHERE condition compares with: FunctionCall | Number | Value.
+// Function calls are evaluated for each resource, so they may only reference the node of the condition.
+func (p *Parser) parseConditionValue(nodeId string) (interface{}, error) {
+	if p.isNumberStart() {
+		return p.parseNumber()
+	}
+	if p.current.Type != IDENT || p.peek(1).Type != LPAREN {
+		return p.parseValue()
+	}
 
-		if p.current.Type != COMMA {
-			break
+	call, err := p.parseFunctionCall()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range functionNodeIds(call) {
+		if id != nodeId {
+			return nil, fmt.Errorf("function calls in WHERE may only reference the node of their condition %s, got %s", nodeId, call)
 		}
-		p.advance()
 	}
-
-	return &Properties{PropertyList: propertyList}, nil
+	return call, nil
 }
 
-// parseKeyValuePairs parses a list of key-value pairs with operators
-func (p *Parser) parseKeyValuePairs() ([]*KeyValuePair, error) {
-	var pairs []*KeyValuePair
+// parseFunctionCondition parses: FunctionCall Operator Value
+func (p *Parser) parseFunctionCondition() (*KeyValuePair, error) {
+	call, nodeId, err := p.parseFunctionItem("WHERE")
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		if p.current.Type != IDENT {
-			return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
-		}
-		var path strings.Builder
-		path.WriteString(p.current.Literal)
-		p.advance()
+	operator, value, err := p.parseComparison(nodeId)
+	if err != nil {
+		return nil, err
+	}
 
-		for {
-			if p.current.Type == DOT {
-				p.advance()
-				path.WriteString(".")
-				if p.current.Type != IDENT {
-					return nil, fmt.Errorf("expected identifier after dot, got \"%v\"", p.current.Literal)
-				}
-				path.WriteString(p.current.Literal)
-				p.advance()
-			} else if p.current.Type == LBRACKET {
-				p.advance()
-				path.WriteString("[")
-				if p.current.Type != NUMBER {
-					return nil, fmt.Errorf("expected number in array index, got \"%v\"", p.current.Literal)
-				}
-				path.WriteString(p.current.Literal)
-				p.advance()
-				if p.current.Type != RBRACKET {
-					return nil, fmt.Errorf("expected closing bracket, got \"%v\"", p.current.Literal)
-				}
-				path.WriteString("]")
-				p.advance()
-				if p.current.Type == DOT {
-					continue
-				}
-			} else {
-				break
-			}
-		}
+	return &KeyValuePair{
+		Key:      nodeId,
+		Value:    value,
+		Operator: operator,
+		Function: call,
+	}, nil
+}
+
+// isPatternStart reports whether the parenthesis at the current token opens a node pattern,
+// i.e. (name), (name:Kind) or (:Kind), rather than a group of conditions
+func (p *Parser) isPatternStart() bool {
+	if p.current.Type != LPAREN {
+		return false
+	}
+	switch p.peek(1).Type {
+	case COLON:
+		return true
+	case IDENT:
+		next := p.peek(2).Type
+		return next == RPAREN || next == COLON
+	}
+	return false
+}
+
+// parsePatternPredicate parses a relationship pattern used as a condition:
+// PatternNode (Relationship PatternNode)+
+// Exactly one node of the pattern must be named, referring to a node of the MATCH clause,
+// the other nodes must be anonymous.
+func (p *Parser) parsePatternPredicate() (*NodeRelationshipList, error) {
+	node, err := p.parsePatternNode()
+	if err != nil {
+		return nil, err
+	}
+	pattern := &NodeRelationshipList{Nodes: []*NodePattern{node}}
 
-		operator, err := p.parseOperator()
+	for isRelationshipStart(p.current.Type) {
+		rel, err := p.parseRelationship()
 		if err != nil {
 			return nil, err
 		}
-
-		value, err := p.parseValue()
+		node, err := p.parsePatternNode()
 		if err != nil {
 			return nil, err
 		}
+		rel.LeftNode = pattern.Nodes[len(pattern.Nodes)-1]
+		rel.RightNode = node
+		pattern.Nodes = append(pattern.Nodes, node)
+		pattern.Relationships = append(pattern.Relationships, rel)
+	}
 
-		pairs = append(pairs, &KeyValuePair{
-			Key:      path.String(),
-			Value:    value,
-			Operator: operator,
-		})
+	if len(pattern.Relationships) == 0 {
+		return nil, fmt.Errorf("expected relationship in pattern, got \"%v\"", p.current.Literal)
+	}
 
-		if p.current.Type != COMMA {
-			break
+	named := 0
+	for _, node := range pattern.Nodes {
+		if node.ResourceProperties.Name != "" {
+			named++
+		} else if node.ResourceProperties.Kind == "" {
+			return nil, fmt.Errorf("anonymous nodes in patterns must specify a kind")
 		}
+	}
+	if named != 1 {
+		return nil, fmt.Errorf("patterns must reference exactly one node variable, got %d", named)
+	}
+
+	return pattern, nil
+}
+
+// parsePatternNode parses a node of a pattern predicate: LPAREN IDENT? (COLON ResourceProperties)? RPAREN
+func (p *Parser) parsePatternNode() (*NodePattern, error) {
+	if p.current.Type != LPAREN {
+		return nil, fmt.Errorf("expected (, got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	var name string
+	if p.current.Type == IDENT {
+		name = p.current.Literal
 		p.advance()
 	}
 
-	return pairs, nil
+	resourceProps := &ResourceProperties{Name: name}
+	if p.current.Type == COLON {
+		p.advance()
+		var err error
+		resourceProps, err = p.parseResourceProperties(name)
+		if err != nil {
+			return nil, err
+		}
+	} else if name == "" {
+		return nil, fmt.Errorf("expected identifier or :, got \"%v\"", p.current.Literal)
+	}
+
+	if p.current.Type != RPAREN {
+		return nil, fmt.Errorf("expected ), got \"%v\"", p.current.Literal)
+	}
+	p.advance()
+
+	return &NodePattern{ResourceProperties: resourceProps}, nil
 }
 
 // parseOperator parses comparison operators
@@ -780,6 +2254,32 @@ func (p *Parser) parseOperator() (string, error) {
 	case REGEX_COMPARE:
 		p.advance()
 		return "REGEX_COMPARE", nil
+	case IN:
+		p.advance()
+		return "IN", nil
+	case STARTS, ENDS:
+		operator := "STARTS_WITH"
+		if p.current.Type == ENDS {
+			operator = "ENDS_WITH"
+		}
+		p.advance()
+		if p.current.Type != WITH {
+			return "", fmt.Errorf("expected WITH, got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+		return operator, nil
+	case IS:
+		p.advance()
+		operator := "IS_NULL"
+		if p.current.Type == NOT {
+			operator = "IS_NOT_NULL"
+			p.advance()
+		}
+		if p.current.Type != NULL {
+			return "", fmt.Errorf("expected NULL, got \"%v\"", p.current.Literal)
+		}
+		p.advance()
+		return operator, nil
 	default:
 		return "", fmt.Errorf("expected operator, got \"%v\"", p.current.Literal)
 	}
@@ -812,29 +2312,118 @@ func (p *Parser) parseValue() (interface{}, error) {
 	case NULL:
 		p.advance()
 		return nil, nil
+	case PARAM:
+		return p.parseParam()
 	default:
 		return nil, fmt.Errorf("expected value, got \"%v\"", p.current.Literal)
 	}
 }
 
-// parseRelationshipProperties parses the properties of a relationship
-func (p *Parser) parseRelationshipProperties() (*ResourceProperties, error) {
-	if p.current.Type != IDENT {
-		return nil, fmt.Errorf("expected identifier, got \"%v\"", p.current.Literal)
+// parseList parses the list of values of an IN condition: LBRACKET (ListItem (COMMA ListItem)*)? RBRACKET,
+// or a parameter bound to a list
+func (p *Parser) parseList() ([]interface{}, error) {
+	if p.current.Type == PARAM {
+		value, err := p.parseParam()
+		if err != nil {
+			return nil, err
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a list, got %v", value)
+		}
+		return list, nil
+	}
+
+	if p.current.Type != LBRACKET {
+		return nil, fmt.Errorf("expected [, got \"%v\"", p.current.Literal)
 	}
-	name := p.current.Literal
 	p.advance()
 
-	if p.current.Type != COLON {
-		return nil, fmt.Errorf("expected :, got \"%v\"", p.current.Literal)
+	list := []interface{}{}
+	for p.current.Type != RBRACKET {
+		if len(list) > 0 {
+			if p.current.Type != COMMA {
+				return nil, fmt.Errorf("expected , or ], got \"%v\"", p.current.Literal)
+			}
+			p.advance()
+		}
+
+		var item interface{}
+		var err error
+		if p.isNumberStart() {
+			item, err = p.parseNumber()
+		} else {
+			item, err = p.parseValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
 	}
 	p.advance()
 
-	if p.current.Type != IDENT {
-		return nil, fmt.Errorf("expected kind identifier, got \"%v\"", p.current.Literal)
+	return list, nil
+}
+
+// parseParam returns the value bound to a $parameter
+func (p *Parser) parseParam() (interface{}, error) {
+	value, ok := p.params[p.current.Literal]
+	if !ok {
+		return nil, fmt.Errorf("missing value for parameter $%s", p.current.Literal)
 	}
-	kind := p.current.Literal
 	p.advance()
+	return normalizeParamValue(value), nil
+}
+
+// normalizeParamValue converts whole numbers decoded from JSON to ints, so that
+// parameters compare the same way as literals written in the query
+func normalizeParamValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v)
+		}
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeParamValue(item)
+		}
+		return normalized
+	}
+	return value
+}
+
+// parseRelationshipProperties parses the properties of a relationship:
+// IDENT? (COLON IDENT ('|' IDENT)*)? Properties?
+// The relationship types are kept in the Kind of its properties, separated by |
+func (p *Parser) parseRelationshipProperties() (*ResourceProperties, error) {
+	if p.current.Type != IDENT && p.current.Type != COLON {
+		return nil, fmt.Errorf("expected identifier or :, got \"%v\"", p.current.Literal)
+	}
+	var name string
+	if p.current.Type == IDENT {
+		name = p.current.Literal
+		p.advance()
+	}
+
+	var types []string
+	if p.current.Type == COLON {
+		p.advance()
+		for {
+			if p.current.Type != IDENT {
+				return nil, fmt.Errorf("expected relationship type, got \"%v\"", p.current.Literal)
+			}
+			types = append(types, p.current.Literal)
+			p.advance()
+
+			// Like wildcards, | is lexed as an ILLEGAL token
+			if p.current.Type != ILLEGAL || p.current.Literal != "|" {
+				break
+			}
+			p.advance()
+		}
+	}
+	kind := strings.Join(types, "|")
 
 	var properties *Properties
 	var jsonData string
@@ -867,7 +2456,14 @@ func (p *Parser) parseRelationshipProperties() (*ResourceProperties, error) {
 
 // ParseQuery is the main entry point for parsing Cyphernetes queries
 func ParseQuery(query string) (*Expression, error) {
+	return ParseQueryWithParams(query, nil)
+}
+
+// ParseQueryWithParams parses a Cyphernetes query, binding its $parameters to the given values.
+// Parameter values are never interpreted as query text.
+func ParseQueryWithParams(query string, params map[string]interface{}) (*Expression, error) {
 	parser := NewRecursiveParser(query)
+	parser.params = params
 	expr, err := parser.Parse()
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
@@ -55,11 +55,14 @@ func TestRecursiveParser(t *testing.T) {
 								},
 							},
 						},
-						ExtraFilters: []*KeyValuePair{
+						ExtraFilters: []*Filter{
 							{
-								Key:      "pod.metadata.name",
-								Value:    "nginx",
-								Operator: "EQUALS",
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "pod.metadata.name",
+									Value:    "nginx",
+									Operator: "EQUALS",
+								},
 							},
 						},
 					},
@@ -258,11 +261,14 @@ func TestRecursiveParser(t *testing.T) {
 								},
 							},
 						},
-						ExtraFilters: []*KeyValuePair{
+						ExtraFilters: []*Filter{
 							{
-								Key:      "pod.spec.containers[0].image",
-								Value:    "nginx",
-								Operator: "EQUALS",
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "pod.spec.containers[0].image",
+									Value:    "nginx",
+									Operator: "EQUALS",
+								},
 							},
 						},
 					},
@@ -476,11 +482,14 @@ func TestRecursiveParser(t *testing.T) {
 								},
 							},
 						},
-						ExtraFilters: []*KeyValuePair{
+						ExtraFilters: []*Filter{
 							{
-								Key:      "d.spec.replicas",
-								Value:    1,
-								Operator: "EQUALS",
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "d.spec.replicas",
+									Value:    1,
+									Operator: "EQUALS",
+								},
 							},
 						},
 					},
@@ -618,10 +627,10 @@ func TestRecursiveParser(t *testing.T) {
 						Nodes: []*NodePattern{
 							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
 						},
-						ExtraFilters: []*KeyValuePair{
-							{Key: "p.status.phase", Value: "Running", Operator: "NOT_EQUALS"},
-							{Key: "p.metadata.name", Value: "^test-.*", Operator: "REGEX_COMPARE"},
-							{Key: "p.spec.containers[0].resources.requests.memory", Value: "Gi", Operator: "CONTAINS"},
+						ExtraFilters: []*Filter{
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.status.phase", Value: "Running", Operator: "NOT_EQUALS"}},
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.metadata.name", Value: "^test-.*", Operator: "REGEX_COMPARE"}},
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.spec.containers[0].resources.requests.memory", Value: "Gi", Operator: "CONTAINS"}},
 						},
 					},
 					&ReturnClause{
@@ -944,109 +953,1428 @@ func TestRecursiveParser(t *testing.T) {
 				},
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseQuery(tt.input)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				// Special handling for JSON data comparison
-				if len(got.Clauses) > 0 && len(tt.want.Clauses) > 0 {
-					if createClause, ok := got.Clauses[0].(*CreateClause); ok {
-						if wantCreateClause, ok := tt.want.Clauses[0].(*CreateClause); ok {
-							if len(createClause.Nodes) > 0 && len(wantCreateClause.Nodes) > 0 {
-								// Normalize the JSON data
-								var gotJSON, wantJSON interface{}
-								if err := json.Unmarshal([]byte(createClause.Nodes[0].ResourceProperties.JsonData), &gotJSON); err == nil {
-									if err := json.Unmarshal([]byte(wantCreateClause.Nodes[0].ResourceProperties.JsonData), &wantJSON); err == nil {
-										if reflect.DeepEqual(gotJSON, wantJSON) {
-											// If the JSON content matches, update the JsonData to match formatting
-											createClause.Nodes[0].ResourceProperties.JsonData = wantCreateClause.Nodes[0].ResourceProperties.JsonData
-										}
-									}
-								}
-							}
-						}
-					}
-				}
-
-				// Now do the final comparison
-				if !reflect.DeepEqual(got, tt.want) {
-					gotJSON, _ := json.MarshalIndent(got, "", "  ")
-					wantJSON, _ := json.MarshalIndent(tt.want, "", "  ")
-					t.Errorf("ParseQuery() mismatch:\nGOT:\n%s\n\nWANT:\n%s", string(gotJSON), string(wantJSON))
-				}
-			}
-		})
-	}
-}
-
-func TestParserErrors(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   string
-		wantErr string
-	}{
-		{
-			name:    "incomplete match query",
-			input:   "MATCH (pod:Pod)",
-			wantErr: "incomplete expression",
-		},
-		{
-			name:    "missing closing parenthesis",
-			input:   "MATCH (pod:Pod RETURN pod",
-			wantErr: "expected )",
-		},
-		{
-			name:    "invalid relationship direction",
-			input:   "MATCH (pod:Pod)<<(svc:Service) RETURN pod",
-			wantErr: "unexpected relationship token",
-		},
 		{
-			name:    "missing kind after colon",
-			input:   "MATCH (pod:) RETURN pod",
-			wantErr: "expected kind identifier",
+			name:  "match with boolean where expression",
+			input: `MATCH (p:Pod) WHERE p.status.phase = "Failed" OR NOT (p.metadata.namespace = "default" AND p.spec.restartPolicy != "Always") RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "p",
+									Kind: "Pod",
+								},
+							},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: OrFilter,
+								Operands: []*Filter{
+									{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.status.phase", Value: "Failed", Operator: "EQUALS"}},
+									{
+										Type: NotFilter,
+										Operands: []*Filter{
+											{
+												Type: AndFilter,
+												Operands: []*Filter{
+													{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.metadata.namespace", Value: "default", Operator: "EQUALS"}},
+													{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.spec.restartPolicy", Value: "Always", Operator: "NOT_EQUALS"}},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid operator in where clause",
-			input:   "MATCH (pod:Pod) WHERE pod.metadata.name ?? 'nginx' RETURN pod",
-			wantErr: "expected operator",
+			name:  "match with where AND binding tighter than OR",
+			input: `MATCH (p:Pod) WHERE p.status.phase = "Failed" AND p.spec.nodeName = "node-1" OR p.status.phase = "Unknown", p.metadata.name =~ "^api" RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "p",
+									Kind: "Pod",
+								},
+							},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: OrFilter,
+								Operands: []*Filter{
+									{
+										Type: AndFilter,
+										Operands: []*Filter{
+											{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.status.phase", Value: "Failed", Operator: "EQUALS"}},
+											{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.spec.nodeName", Value: "node-1", Operator: "EQUALS"}},
+										},
+									},
+									{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.status.phase", Value: "Unknown", Operator: "EQUALS"}},
+								},
+							},
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "p.metadata.name", Value: "^api", Operator: "REGEX_COMPARE"}},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid clause combination",
-			input:   "CREATE (pod:Pod) DELETE pod",
-			wantErr: "DELETE can only follow MATCH",
+			name:  "match with top-level AND flattened",
+			input: `MATCH (d:Deployment) WHERE d.spec.replicas > 2 AND d.metadata.name CONTAINS "api" RETURN d`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "d",
+									Kind: "Deployment",
+								},
+							},
+						},
+						ExtraFilters: []*Filter{
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "d.spec.replicas", Value: 2, Operator: "GREATER_THAN"}},
+							{Type: KeyValuePairFilter, KeyValuePair: &KeyValuePair{Key: "d.metadata.name", Value: "api", Operator: "CONTAINS"}},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "d"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:    "missing return value",
-			input:   "MATCH (pod:Pod) RETURN",
-			wantErr: "expected identifier",
+			name:  "return with order by, skip and limit",
+			input: "MATCH (pod:Pod) RETURN pod.metadata.name AS name, pod.status.startTime ORDER BY pod.status.startTime DESC, name SKIP 5 LIMIT 10",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "pod",
+									Kind: "Pod",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "pod.metadata.name", Alias: "name"},
+							{JsonPath: "pod.status.startTime"},
+						},
+						OrderBy: []*OrderItem{
+							{JsonPath: "pod.status.startTime", Descending: true},
+							{JsonPath: "pod.metadata.name"},
+						},
+						Skip:  5,
+						Limit: intPtr(10),
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid array index",
-			input:   "MATCH (pod:Pod) WHERE pod.spec.containers[a].image = 'nginx' RETURN pod",
-			wantErr: "expected number in array index",
+			name:  "return with keyword as path segment",
+			input: "MATCH (d:Deployment) RETURN d.spec.limit ORDER BY d.spec.limit ASC LIMIT 1",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "d",
+									Kind: "Deployment",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "d.spec.limit"},
+						},
+						OrderBy: []*OrderItem{
+							{JsonPath: "d.spec.limit"},
+						},
+						Limit: intPtr(1),
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid relationship property",
-			input:   `MATCH (d:Deployment)-[r:EXPOSES {invalid}]->(s:Service) RETURN d`,
-			wantErr: "expected :",
+			name:  "return with avg, min, max and collect",
+			input: "MATCH (p:Pod) RETURN AVG{p.status.containerStatuses[0].restartCount} AS avgRestarts, MIN{p.metadata.creationTimestamp}, MAX{p.spec.containers[*].resources.requests.memory} AS maxMem, COLLECT{p.metadata.name} AS names",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "p",
+									Kind: "Pod",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p.status.containerStatuses[0].restartCount", Alias: "avgRestarts", Aggregate: "AVG"},
+							{JsonPath: "p.metadata.creationTimestamp", Aggregate: "MIN"},
+							{JsonPath: "p.spec.containers[*].resources.requests.memory", Alias: "maxMem", Aggregate: "MAX"},
+							{JsonPath: "p.metadata.name", Alias: "names", Aggregate: "COLLECT"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid IN clause",
-			input:   `IN production, MATCH (d:Deployment) RETURN d`,
-			wantErr: "expected identifier",
+			name:  "match with variable-length relationship",
+			input: "MATCH (i:Ingress)-[*1..4]->(p:Pod) RETURN p",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "i",
+									Kind: "Ingress",
+								},
+							},
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "p",
+									Kind: "Pod",
+								},
+							},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								MinHops:   1,
+								MaxHops:   4,
+								LeftNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "i",
+										Kind: "Ingress",
+									},
+								},
+								RightNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "p",
+										Kind: "Pod",
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p"},
+						},
+					},
+				},
+			},
 		},
 		{
-			name:    "invalid array index in SET",
-			input:   `MATCH (d:Deployment) SET d.spec.containers[a].image = "nginx" RETURN d`,
-			wantErr: "expected number in array index",
-		},
-	}
+			name:  "match with named open-ended variable-length relationship",
+			input: "MATCH (d:Deployment)<-[r:OWNS*2..]-(p:Pod) RETURN p",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "d",
+									Kind: "Deployment",
+								},
+							},
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "p",
+									Kind: "Pod",
+								},
+							},
+						},
+						Relationships: []*Relationship{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "r",
+									Kind: "OWNS",
+								},
+								Direction: Left,
+								MinHops:   2,
+								MaxHops:   defaultMaxHops,
+								LeftNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "d",
+										Kind: "Deployment",
+									},
+								},
+								RightNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "p",
+										Kind: "Pod",
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "optional match",
+			input: "MATCH (d:Deployment) OPTIONAL MATCH (d)->(h:HorizontalPodAutoscaler) WHERE h.spec.maxReplicas > 3 RETURN d, h",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "d",
+									Kind: "Deployment",
+								},
+							},
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "h",
+									Kind: "HorizontalPodAutoscaler",
+								},
+								Optional: true,
+							},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "d",
+										Kind: "Deployment",
+									},
+								},
+								RightNode: &NodePattern{
+									ResourceProperties: &ResourceProperties{
+										Name: "h",
+										Kind: "HorizontalPodAutoscaler",
+									},
+									Optional: true,
+								},
+							},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "h.spec.maxReplicas",
+									Value:    3,
+									Operator: "GREATER_THAN",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "d"},
+							{JsonPath: "h"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "negated pattern predicate",
+			input: "MATCH (s:Service) WHERE NOT (s)->(:Endpoints) RETURN s",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{
+								ResourceProperties: &ResourceProperties{
+									Name: "s",
+									Kind: "Service",
+								},
+							},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: NotFilter,
+								Operands: []*Filter{
+									{
+										Type: PatternFilter,
+										Pattern: &NodeRelationshipList{
+											Nodes: []*NodePattern{
+												{ResourceProperties: &ResourceProperties{Name: "s"}},
+												{ResourceProperties: &ResourceProperties{Kind: "Endpoints"}},
+											},
+											Relationships: []*Relationship{
+												{
+													Direction: Right,
+													LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "s"}},
+													RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Kind: "Endpoints"}},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "s"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "with clause",
+			input: "MATCH (ns:Namespace)->(p:Pod) WITH ns, COUNT{p.metadata.name} AS pods WHERE pods > 1 MATCH (ns)->(d:Deployment) RETURN d",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}},
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+							},
+						},
+					},
+					&WithClause{
+						Items: []*ReturnItem{
+							{JsonPath: "ns"},
+							{JsonPath: "p.metadata.name", Alias: "pods", Aggregate: "COUNT"},
+						},
+						Filters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "pods",
+									Value:    1,
+									Operator: "GREATER_THAN",
+								},
+							},
+						},
+					},
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}, Bound: true},
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}, Bound: true},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "d"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "merge clause",
+			input: `MERGE (d:Deployment {name: "web"}) ON CREATE SET d.spec.replicas = 3 ON MATCH SET d.metadata.labels.app = "web" RETURN d`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MergeClause{
+						Node: &NodePattern{
+							ResourceProperties: &ResourceProperties{
+								Name: "d",
+								Kind: "Deployment",
+								Properties: &Properties{
+									PropertyList: []*Property{
+										{Key: "name", Value: "web"},
+									},
+								},
+							},
+						},
+						OnCreate: []*KeyValuePair{
+							{Key: "d.spec.replicas", Value: 3, Operator: "EQUALS"},
+						},
+						OnMatch: []*KeyValuePair{
+							{Key: "d.metadata.labels.app", Value: "web", Operator: "EQUALS"},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "d"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "remove clause",
+			input: `MATCH (d:Deployment) REMOVE d.metadata.labels.app\.kubernetes\.io/name, d.spec.template.spec.nodeSelector`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+						},
+					},
+					&RemoveClause{
+						JsonPaths: []string{
+							`d.metadata.labels.app\.kubernetes\.io/name`,
+							"d.spec.template.spec.nodeSelector",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "detach delete",
+			input: "MATCH (d:Deployment) DETACH DELETE d",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+						},
+					},
+					&DeleteClause{
+						NodeIds: []string{"d"},
+						Detach:  true,
+					},
+				},
+			},
+		},
+		{
+			name:  "set with expressions",
+			input: `MATCH (ns:Namespace)->(d:Deployment) SET d.spec.replicas = (d.spec.replicas + 1) * 2, d.metadata.labels.team = ns.metadata.labels.team, d.spec.template.spec.containers[0].resources.limits.memory = "128Mi" * 1.5 - -1`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}},
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "ns", Kind: "Namespace"}},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+							},
+						},
+					},
+					&SetClause{
+						KeyValuePairs: []*KeyValuePair{
+							{
+								Key: "d.spec.replicas",
+								Value: &ArithmeticExpression{
+									Operator: "*",
+									Left: &ArithmeticExpression{
+										Operator: "+",
+										Left:     &FieldReference{JsonPath: "d.spec.replicas"},
+										Right:    1,
+									},
+									Right: 2,
+								},
+								Operator: "EQUALS",
+							},
+							{Key: "d.metadata.labels.team", Value: &FieldReference{JsonPath: "ns.metadata.labels.team"}, Operator: "EQUALS"},
+							{
+								Key: "d.spec.template.spec.containers[0].resources.limits.memory",
+								Value: &ArithmeticExpression{
+									Operator: "-",
+									Left:     &ArithmeticExpression{Operator: "*", Left: "128Mi", Right: 1.5},
+									Right:    -1,
+								},
+								Operator: "EQUALS",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "function calls in WHERE and RETURN",
+			input: `MATCH (p:Pod) WHERE size(p.spec.containers) > 1 RETURN toLower(p.metadata.name) AS name, coalesce(p.metadata.labels.app, head(split(p.metadata.name, "-")), -1) ORDER BY name`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p",
+									Value:    1,
+									Operator: "GREATER_THAN",
+									Function: &FunctionCall{Name: "size", Args: []interface{}{&FieldReference{JsonPath: "p.spec.containers"}}},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{
+								JsonPath: "p",
+								Alias:    "name",
+								Function: &FunctionCall{Name: "toLower", Args: []interface{}{&FieldReference{JsonPath: "p.metadata.name"}}},
+							},
+							{
+								JsonPath: "p",
+								Function: &FunctionCall{Name: "coalesce", Args: []interface{}{
+									&FieldReference{JsonPath: "p.metadata.labels.app"},
+									&FunctionCall{Name: "head", Args: []interface{}{
+										&FunctionCall{Name: "split", Args: []interface{}{&FieldReference{JsonPath: "p.metadata.name"}, "-"}},
+									}},
+									-1,
+								}},
+							},
+						},
+						OrderBy: []*OrderItem{
+							{
+								JsonPath: "p",
+								Function: &FunctionCall{Name: "toLower", Args: []interface{}{&FieldReference{JsonPath: "p.metadata.name"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "comparison with a function call",
+			input: `MATCH (p:Pod) WHERE p.metadata.creationTimestamp > ago("24h") RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p.metadata.creationTimestamp",
+									Value:    &FunctionCall{Name: "ago", Args: []interface{}{"24h"}},
+									Operator: "GREATER_THAN",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{{JsonPath: "p"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "list and null operators",
+			input: `MATCH (p:Pod) WHERE p.status.phase IN ["Failed", "Unknown"] AND p.metadata.name STARTS WITH "canary-", p.spec.priorityClassName IS NULL OR EXISTS(p.metadata.annotations.foo) RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p.status.phase",
+									Value:    []interface{}{"Failed", "Unknown"},
+									Operator: "IN",
+								},
+							},
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p.metadata.name",
+									Value:    "canary-",
+									Operator: "STARTS_WITH",
+								},
+							},
+							{
+								Type: OrFilter,
+								Operands: []*Filter{
+									{
+										Type:         KeyValuePairFilter,
+										KeyValuePair: &KeyValuePair{Key: "p.spec.priorityClassName", Operator: "IS_NULL"},
+									},
+									{
+										Type:         KeyValuePairFilter,
+										KeyValuePair: &KeyValuePair{Key: "p.metadata.annotations.foo", Operator: "IS_NOT_NULL"},
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{{JsonPath: "p"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "ends with and is not null",
+			input: `MATCH (p:Pod) WHERE p.spec.containers[0].image ENDS WITH ":latest", p.spec.nodeName IS NOT NULL, p.status.restartCount IN [] RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p.spec.containers[0].image",
+									Value:    ":latest",
+									Operator: "ENDS_WITH",
+								},
+							},
+							{
+								Type:         KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{Key: "p.spec.nodeName", Operator: "IS_NOT_NULL"},
+							},
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "p.status.restartCount",
+									Value:    []interface{}{},
+									Operator: "IN",
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{{JsonPath: "p"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "wildcard and ANY conditions",
+			input: `MATCH (p:Pod) WHERE p.spec.containers[*].image =~ ":latest$" OR ANY(c IN p.spec.containers WHERE c.image STARTS WITH "docker.io/", c.name != "proxy") RETURN p`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						ExtraFilters: []*Filter{
+							{
+								Type: OrFilter,
+								Operands: []*Filter{
+									{
+										Type: KeyValuePairFilter,
+										KeyValuePair: &KeyValuePair{
+											Key:      "p.spec.containers[*].image",
+											Value:    ":latest$",
+											Operator: "REGEX_COMPARE",
+										},
+									},
+									{
+										Type:     AnyFilter,
+										Variable: "c",
+										ListPath: "p.spec.containers",
+										Operands: []*Filter{
+											{
+												Type: AndFilter,
+												Operands: []*Filter{
+													{
+														Type: KeyValuePairFilter,
+														KeyValuePair: &KeyValuePair{
+															Key:      "c.image",
+															Value:    "docker.io/",
+															Operator: "STARTS_WITH",
+														},
+													},
+													{
+														Type: KeyValuePairFilter,
+														KeyValuePair: &KeyValuePair{
+															Key:      "c.name",
+															Value:    "proxy",
+															Operator: "NOT_EQUALS",
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{{JsonPath: "p"}},
+					},
+				},
+			},
+		},
+		{
+			name:  "kind-less and anonymous nodes",
+			input: "MATCH (d:Deployment)->(x), (d)->(:Service) RETURN x",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+							{ResourceProperties: &ResourceProperties{Name: "x"}},
+							{ResourceProperties: &ResourceProperties{Name: "d"}},
+							{ResourceProperties: &ResourceProperties{Name: "_anon1", Kind: "Service"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "x"}},
+							},
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "d"}},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "_anon1", Kind: "Service"}},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "x"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "relationship types",
+			input: "MATCH (i:Ingress)-[:ROUTE]->(s:Service)-[r:SERVICE_EXPOSE_POD|SERVICE_EXPOSE_DEPLOYMENT]->(p:Pod) RETURN type(r)",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "i", Kind: "Ingress"}},
+							{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction:          Right,
+								ResourceProperties: &ResourceProperties{Kind: "ROUTE"},
+								LeftNode:           &NodePattern{ResourceProperties: &ResourceProperties{Name: "i", Kind: "Ingress"}},
+								RightNode:          &NodePattern{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+							},
+							{
+								Direction:          Right,
+								ResourceProperties: &ResourceProperties{Name: "r", Kind: "SERVICE_EXPOSE_POD|SERVICE_EXPOSE_DEPLOYMENT"},
+								LeftNode:           &NodePattern{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+								RightNode:          &NodePattern{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "r", Function: &FunctionCall{Name: "type", Args: []interface{}{&FieldReference{JsonPath: "r"}}}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "union all",
+			input: "MATCH (w:Deployment) RETURN w.metadata.name AS name UNION ALL MATCH (w:StatefulSet) RETURN w.metadata.name AS name",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "w", Kind: "Deployment"}},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{{JsonPath: "w.metadata.name", Alias: "name"}},
+					},
+				},
+				Unions: []*Expression{
+					{
+						Clauses: []Clause{
+							&MatchClause{
+								Nodes: []*NodePattern{
+									{ResourceProperties: &ResourceProperties{Name: "w", Kind: "StatefulSet"}},
+								},
+							},
+							&ReturnClause{
+								Items: []*ReturnItem{{JsonPath: "w.metadata.name", Alias: "name"}},
+							},
+						},
+					},
+				},
+				UnionAll: true,
+			},
+		},
+		{
+			name:  "path variable",
+			input: "MATCH p = (i:Ingress)->(s:Service), (d:Deployment) RETURN length(p)",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "i", Kind: "Ingress"}},
+							{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+							{ResourceProperties: &ResourceProperties{Name: "d", Kind: "Deployment"}},
+						},
+						Relationships: []*Relationship{
+							{
+								Direction: Right,
+								LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "i", Kind: "Ingress"}},
+								RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+							},
+						},
+						Paths: []*PathPattern{
+							{
+								Name:  "p",
+								Nodes: []string{"i", "s"},
+								Relationships: []*Relationship{
+									{
+										Direction: Right,
+										LeftNode:  &NodePattern{ResourceProperties: &ResourceProperties{Name: "i", Kind: "Ingress"}},
+										RightNode: &NodePattern{ResourceProperties: &ResourceProperties{Name: "s", Kind: "Service"}},
+									},
+								},
+							},
+						},
+					},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "p", Function: &FunctionCall{Name: "length", Args: []interface{}{&FieldReference{JsonPath: "p"}}}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "return distinct",
+			input: "MATCH (p:Pod) RETURN DISTINCT p.spec.nodeName, COUNT{DISTINCT p.spec.containers[*].image} AS images",
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+					},
+					&ReturnClause{
+						Distinct: true,
+						Items: []*ReturnItem{
+							{JsonPath: "p.spec.nodeName"},
+							{JsonPath: "p.spec.containers[*].image", Alias: "images", Aggregate: "COUNT", Distinct: true},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "unwind",
+			input: `MATCH (p:Pod) UNWIND p.spec.containers AS c WHERE c.image CONTAINS "nginx" UNWIND c.ports AS port RETURN c.name, port.containerPort`,
+			want: &Expression{
+				Clauses: []Clause{
+					&MatchClause{
+						Nodes: []*NodePattern{
+							{ResourceProperties: &ResourceProperties{Name: "p", Kind: "Pod"}},
+						},
+					},
+					&UnwindClause{
+						JsonPath: "p.spec.containers",
+						Alias:    "c",
+						Filters: []*Filter{
+							{
+								Type: KeyValuePairFilter,
+								KeyValuePair: &KeyValuePair{
+									Key:      "c.image",
+									Value:    "nginx",
+									Operator: "CONTAINS",
+								},
+							},
+						},
+					},
+					&UnwindClause{JsonPath: "c.ports", Alias: "port"},
+					&ReturnClause{
+						Items: []*ReturnItem{
+							{JsonPath: "c.name"},
+							{JsonPath: "port.containerPort"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseQuery() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				// Special handling for JSON data comparison
+				if len(got.Clauses) > 0 && len(tt.want.Clauses) > 0 {
+					if createClause, ok := got.Clauses[0].(*CreateClause); ok {
+						if wantCreateClause, ok := tt.want.Clauses[0].(*CreateClause); ok {
+							if len(createClause.Nodes) > 0 && len(wantCreateClause.Nodes) > 0 {
+								// Normalize the JSON data
+								var gotJSON, wantJSON interface{}
+								if err := json.Unmarshal([]byte(createClause.Nodes[0].ResourceProperties.JsonData), &gotJSON); err == nil {
+									if err := json.Unmarshal([]byte(wantCreateClause.Nodes[0].ResourceProperties.JsonData), &wantJSON); err == nil {
+										if reflect.DeepEqual(gotJSON, wantJSON) {
+											// If the JSON content matches, update the JsonData to match formatting
+											createClause.Nodes[0].ResourceProperties.JsonData = wantCreateClause.Nodes[0].ResourceProperties.JsonData
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+
+				// Now do the final comparison
+				if !reflect.DeepEqual(got, tt.want) {
+					gotJSON, _ := json.MarshalIndent(got, "", "  ")
+					wantJSON, _ := json.MarshalIndent(tt.want, "", "  ")
+					t.Errorf("ParseQuery() mismatch:\nGOT:\n%s\n\nWANT:\n%s", string(gotJSON), string(wantJSON))
+				}
+			}
+		})
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "incomplete match query",
+			input:   "MATCH (pod:Pod)",
+			wantErr: "incomplete expression",
+		},
+		{
+			name:    "missing closing parenthesis",
+			input:   "MATCH (pod:Pod RETURN pod",
+			wantErr: "expected )",
+		},
+		{
+			name:    "invalid relationship direction",
+			input:   "MATCH (pod:Pod)<<(svc:Service) RETURN pod",
+			wantErr: "unexpected relationship token",
+		},
+		{
+			name:    "missing kind after colon",
+			input:   "MATCH (pod:) RETURN pod",
+			wantErr: "expected kind identifier",
+		},
+		{
+			name:    "invalid operator in where clause",
+			input:   "MATCH (pod:Pod) WHERE pod.metadata.name ?? 'nginx' RETURN pod",
+			wantErr: "expected operator",
+		},
+		{
+			name:    "invalid clause combination",
+			input:   "CREATE (pod:Pod) DELETE pod",
+			wantErr: "DELETE can only follow MATCH",
+		},
+		{
+			name:    "missing return value",
+			input:   "MATCH (pod:Pod) RETURN",
+			wantErr: "expected identifier",
+		},
+		{
+			name:    "invalid array index",
+			input:   "MATCH (pod:Pod) WHERE pod.spec.containers[a].image = 'nginx' RETURN pod",
+			wantErr: "expected number or * in array index",
+		},
+		{
+			name:    "invalid relationship property",
+			input:   `MATCH (d:Deployment)-[r:EXPOSES {invalid}]->(s:Service) RETURN d`,
+			wantErr: "expected :",
+		},
+		{
+			name:    "invalid IN clause",
+			input:   `IN production, MATCH (d:Deployment) RETURN d`,
+			wantErr: "expected identifier",
+		},
+		{
+			name:    "unclosed parenthesis in where clause",
+			input:   `MATCH (p:Pod) WHERE (p.status.phase = "Failed" OR p.status.phase = "Unknown" RETURN p`,
+			wantErr: "expected )",
+		},
+		{
+			name:    "dangling OR in where clause",
+			input:   `MATCH (p:Pod) WHERE p.status.phase = "Failed" OR RETURN p`,
+			wantErr: "expected identifier",
+		},
+		{
+			name:    "invalid array index in SET",
+			input:   `MATCH (d:Deployment) SET d.spec.containers[a].image = "nginx" RETURN d`,
+			wantErr: "expected number or * in array index",
+		},
+		{
+			name:    "order without by",
+			input:   "MATCH (pod:Pod) RETURN pod ORDER pod.metadata.name",
+			wantErr: "expected BY after ORDER",
+		},
+		{
+			name:    "limit without number",
+			input:   "MATCH (pod:Pod) RETURN pod LIMIT ten",
+			wantErr: "expected number after LIMIT",
+		},
+		{
+			name:    "inverted hop range",
+			input:   "MATCH (i:Ingress)-[*4..2]->(p:Pod) RETURN p",
+			wantErr: "invalid hop range",
+		},
+		{
+			name:    "zero hop range",
+			input:   "MATCH (i:Ingress)-[*0..2]->(p:Pod) RETURN p",
+			wantErr: "at least 1 hop",
+		},
+		{
+			name:    "variable-length relationship in create",
+			input:   "MATCH (d:Deployment) CREATE (d)-[*1..2]->(s:Service)",
+			wantErr: "not supported in CREATE",
+		},
+		{
+			name:    "optional match without a bound node",
+			input:   "MATCH (d:Deployment) OPTIONAL MATCH (s:Service)->(p:Pod) RETURN d",
+			wantErr: "must reference a node from the preceding MATCH",
+		},
+		{
+			name:    "optional match where on a required node",
+			input:   "MATCH (d:Deployment) OPTIONAL MATCH (d)->(h:HorizontalPodAutoscaler) WHERE d.spec.replicas > 1 RETURN d",
+			wantErr: "may only reference nodes introduced by the OPTIONAL MATCH",
+		},
+		{
+			name:    "optional match after create",
+			input:   "CREATE (d:Deployment) OPTIONAL MATCH (d)->(h:HorizontalPodAutoscaler) RETURN d",
+			wantErr: "OPTIONAL MATCH can only follow MATCH",
+		},
+		{
+			name:    "pattern predicate with two node variables",
+			input:   "MATCH (s:Service), (p:Pod) WHERE (s)->(p) RETURN s",
+			wantErr: "patterns must reference exactly one node variable",
+		},
+		{
+			name:    "pattern predicate with an anonymous node without kind",
+			input:   "MATCH (s:Service) WHERE (s)->() RETURN s",
+			wantErr: "expected identifier or :",
+		},
+		{
+			name:    "pattern predicate without relationship",
+			input:   "MATCH (s:Service) WHERE (s) RETURN s",
+			wantErr: "expected relationship in pattern",
+		},
+		{
+			name:    "with an unbound variable",
+			input:   "MATCH (d:Deployment) WITH s RETURN s",
+			wantErr: "node s is not bound by a preceding clause",
+		},
+		{
+			name:    "with a property path",
+			input:   "MATCH (d:Deployment) WITH d.spec.replicas RETURN d",
+			wantErr: "WITH can't project d.spec.replicas, it only carries node variables and aggregations: carry d",
+		},
+		{
+			name:    "with a projection",
+			input:   "MATCH (d:Deployment) WITH d.spec.replicas AS replicas RETURN d",
+			wantErr: "WITH can't project d.spec.replicas AS replicas",
+		},
+		{
+			name:    "with an aggregation without alias",
+			input:   "MATCH (d:Deployment) WITH COUNT{d.metadata.name} RETURN d",
+			wantErr: "aggregations in WITH must have an alias",
+		},
+		{
+			name:    "with where on a variable that isn't carried",
+			input:   "MATCH (d:Deployment)->(p:Pod) WITH d WHERE p.status.phase = \"Running\" RETURN d",
+			wantErr: "may only reference the variables and aliases it carries",
+		},
+		{
+			name:    "return an alias of a with aggregation",
+			input:   "MATCH (s:Service)->(p:Pod) WITH s, COUNT{p} AS n RETURN s.metadata.name, n",
+			wantErr: "RETURN can't reference n, the alias of an aggregation in WITH",
+		},
+		{
+			name:    "return an alias of a with aggregation after match",
+			input:   "MATCH (s:Service)->(p:Pod) WITH s, COUNT{p} AS n MATCH (s)->(d:Deployment) RETURN d, n.value",
+			wantErr: "RETURN can't reference n, the alias of an aggregation in WITH",
+		},
+		{
+			name:    "match after return",
+			input:   "MATCH (d:Deployment) RETURN d MATCH (p:Pod) RETURN p",
+			wantErr: "MATCH can only start a query or follow WITH",
+		},
+		{
+			name:    "trailing with",
+			input:   "MATCH (d:Deployment) WITH d",
+			wantErr: "WITH must be followed by another clause",
+		},
+		{
+			name:    "merge without a name",
+			input:   `MERGE (d:Deployment {app: "web"}) RETURN d`,
+			wantErr: "the node in MERGE must be identified by its name",
+		},
+		{
+			name:    "merge setting another node",
+			input:   `MATCH (s:Service) MERGE (d:Deployment {name: "web"}) ON CREATE SET s.spec.type = "NodePort"`,
+			wantErr: "ON CREATE SET may only reference the merged node d, got s",
+		},
+		{
+			name:    "merge with an unknown action",
+			input:   `MERGE (d:Deployment {name: "web"}) ON DELETE SET d.spec.replicas = 1`,
+			wantErr: "expected CREATE or MATCH after ON",
+		},
+		{
+			name:    "merge of a bound node",
+			input:   `MATCH (d:Deployment) MERGE (d:Deployment {name: "web"})`,
+			wantErr: "node d is already bound by a preceding clause",
+		},
+		{
+			name:    "merge after return",
+			input:   `MATCH (d:Deployment) RETURN d MERGE (s:Service {name: "web"})`,
+			wantErr: "MERGE can only start a query or follow MATCH, WITH or MERGE",
+		},
+		{
+			name:    "remove a node",
+			input:   "MATCH (d:Deployment) REMOVE d",
+			wantErr: "REMOVE items must be paths to fields, got d",
+		},
+		{
+			name:    "remove with a wildcard",
+			input:   "MATCH (d:Deployment) REMOVE d.spec.template.spec.containers[*].env",
+			wantErr: "wildcards are not supported in REMOVE",
+		},
+		{
+			name:    "remove after create",
+			input:   `CREATE (d:Deployment {"name": "web"}) REMOVE d.metadata.labels.app`,
+			wantErr: "REMOVE can only follow MATCH, WITH or MERGE",
+		},
+		{
+			name:    "detach without delete",
+			input:   "MATCH (d:Deployment) DETACH d",
+			wantErr: "expected DELETE, got \"d\"",
+		},
+		{
+			name:    "comparison in SET",
+			input:   `MATCH (d:Deployment) SET d.spec.replicas > 3`,
+			wantErr: "expected = after d.spec.replicas",
+		},
+		{
+			name:    "dangling operator in SET",
+			input:   `MATCH (d:Deployment) SET d.spec.replicas = d.spec.replicas +`,
+			wantErr: "expected value",
+		},
+		{
+			name:    "unclosed parenthesis in SET",
+			input:   `MATCH (d:Deployment) SET d.spec.replicas = (d.spec.replicas + 1 RETURN d`,
+			wantErr: "expected ), got \"RETURN\"",
+		},
+		{
+			name:    "node reference in SET",
+			input:   `MATCH (d:Deployment) SET d.metadata.name = d`,
+			wantErr: "expected a field reference, got d",
+		},
+		{
+			name:    "wildcard reference in SET",
+			input:   `MATCH (d:Deployment) SET d.spec.replicas = d.spec.containers[*].replicas`,
+			wantErr: "wildcards are not supported in field references",
+		},
+		{
+			name:    "unknown function",
+			input:   `MATCH (p:Pod) RETURN lower(p.metadata.name)`,
+			wantErr: "unknown function lower",
+		},
+		{
+			name:    "function with too many arguments",
+			input:   `MATCH (p:Pod) RETURN toLower(p.metadata.name, "x")`,
+			wantErr: "toLower expects 1 arguments, got 2",
+		},
+		{
+			name:    "unclosed function call",
+			input:   `MATCH (p:Pod) RETURN toLower(p.metadata.name`,
+			wantErr: "expected ) after arguments of toLower",
+		},
+		{
+			name:    "function in RETURN without a node",
+			input:   `MATCH (p:Pod) RETURN now()`,
+			wantErr: "function calls in RETURN must reference exactly one node, got now()",
+		},
+		{
+			name:    "function in WHERE with several nodes",
+			input:   `MATCH (p:Pod), (d:Deployment) WHERE coalesce(p.metadata.name, d.metadata.name) = "web" RETURN p`,
+			wantErr: "function calls in WHERE must reference exactly one node",
+		},
+		{
+			name:    "function in WITH",
+			input:   `MATCH (p:Pod) WITH toLower(p.metadata.name) RETURN p`,
+			wantErr: "WITH can't project toLower(p.metadata.name), it only carries node variables and aggregations: carry p",
+		},
+		{
+			name:    "comparison with a function call on another node",
+			input:   `MATCH (p:Pod), (d:Deployment) WHERE p.metadata.name = toLower(d.metadata.name) RETURN p`,
+			wantErr: "function calls in WHERE may only reference the node of their condition p, got toLower(d.metadata.name)",
+		},
+		{
+			name:    "unterminated IN list",
+			input:   `MATCH (p:Pod) WHERE p.status.phase IN ["Failed" "Unknown"] RETURN p`,
+			wantErr: `expected , or ], got ""Unknown""`,
+		},
+		{
+			name:    "IN without a list",
+			input:   `MATCH (p:Pod) WHERE p.status.phase IN "Failed" RETURN p`,
+			wantErr: `expected [, got ""Failed""`,
+		},
+		{
+			name:    "STARTS without WITH",
+			input:   `MATCH (p:Pod) WHERE p.metadata.name STARTS "canary-" RETURN p`,
+			wantErr: `expected WITH, got ""canary-""`,
+		},
+		{
+			name:    "IS without NULL",
+			input:   `MATCH (p:Pod) WHERE p.spec.nodeName IS "node-1" RETURN p`,
+			wantErr: `expected NULL, got ""node-1""`,
+		},
+		{
+			name:    "ANY condition on another node",
+			input:   `MATCH (p:Pod), (d:Deployment) WHERE ANY(c IN p.spec.containers WHERE d.metadata.name = "web") RETURN p`,
+			wantErr: "ANY conditions may only reference c, got d",
+		},
+		{
+			name:    "ANY without WHERE",
+			input:   `MATCH (p:Pod) WHERE ANY(c IN p.spec.containers) RETURN p`,
+			wantErr: `expected WHERE, got ")"`,
+		},
+		{
+			name:    "wildcard in SET",
+			input:   `MATCH (p:Pod) SET p.spec.containers[*].image = "nginx"`,
+			wantErr: "wildcards are not supported in SET, got p.spec.containers[*].image",
+		},
+		{
+			name:    "with a kind-less node",
+			input:   "MATCH (d:Deployment)->(x) WITH x RETURN x",
+			wantErr: "WITH can't carry kind-less node x",
+		},
+		{
+			name:    "relationship without type after colon",
+			input:   "MATCH (s:Service)-[r:]->(p:Pod) RETURN p",
+			wantErr: `expected relationship type, got "]->"`,
+		},
+		{
+			name:    "relationship type alternative without type",
+			input:   "MATCH (s:Service)-[:ROUTE|]->(p:Pod) RETURN p",
+			wantErr: `expected relationship type, got "]->"`,
+		},
+		{
+			name:    "union without return",
+			input:   "MATCH (d:Deployment) RETURN d.metadata.name UNION MATCH (d:Deployment) DELETE d",
+			wantErr: "all queries combined by UNION must end with RETURN",
+		},
+		{
+			name:    "union of different columns",
+			input:   "MATCH (d:Deployment) RETURN d.metadata.name AS name UNION MATCH (s:StatefulSet) RETURN s.metadata.name AS workload",
+			wantErr: "all queries combined by UNION must return the same columns, got name and workload",
+		},
+		{
+			name:    "union of columns of one variable under two",
+			input:   "MATCH (d:Deployment)->(rs:ReplicaSet) RETURN d.metadata.name AS owner, rs.metadata.name AS name UNION MATCH (s:StatefulSet) RETURN s.metadata.name AS owner, s.metadata.name AS name",
+			wantErr: "queries combined by UNION must return the columns of each variable together, the columns of s are returned under both d and rs",
+		},
+		{
+			name:    "union and union all",
+			input:   "MATCH (w:Deployment) RETURN w UNION MATCH (w:StatefulSet) RETURN w UNION ALL MATCH (w:DaemonSet) RETURN w",
+			wantErr: "can't combine UNION and UNION ALL in one query",
+		},
+		{
+			name:    "union of aggregations",
+			input:   "MATCH (w:Deployment) RETURN COUNT{w} AS total UNION MATCH (w:StatefulSet) RETURN COUNT{w} AS total",
+			wantErr: "queries combined by UNION can't return aggregations without grouping them",
+		},
+		{
+			name:    "path variable bound to a node",
+			input:   "MATCH p = (p:Pod) RETURN p",
+			wantErr: "path variable p is already bound to a node",
+		},
+		{
+			name:    "path variable in optional match",
+			input:   "MATCH (d:Deployment) OPTIONAL MATCH p = (d)->(rs:ReplicaSet) RETURN d",
+			wantErr: "paths can't be bound in OPTIONAL MATCH",
+		},
+		{
+			name:    "path variable in create",
+			input:   "MATCH (d:Deployment) CREATE p = (d)<-(s:Service)",
+			wantErr: "paths can't be bound in CREATE",
+		},
+		{
+			name:    "distinct outside of an aggregation",
+			input:   "MATCH (p:Pod) RETURN p.metadata.name, DISTINCT p.spec.nodeName",
+			wantErr: "expected identifier, got \"DISTINCT\"",
+		},
+		{
+			name:    "unwind of an unbound variable",
+			input:   "MATCH (p:Pod) UNWIND d.spec.containers AS c RETURN c",
+			wantErr: "d is not bound by a preceding clause",
+		},
+		{
+			name:    "unwind of a node",
+			input:   "MATCH (p:Pod) UNWIND p AS c RETURN c",
+			wantErr: "expected a path to a list, got p",
+		},
+		{
+			name:    "unwind into a bound variable",
+			input:   "MATCH (p:Pod) UNWIND p.spec.containers AS p RETURN p",
+			wantErr: "variable p is already bound",
+		},
+		{
+			name:    "unwind where referencing another variable",
+			input:   `MATCH (p:Pod) UNWIND p.spec.containers AS c WHERE p.metadata.name = "web" RETURN c`,
+			wantErr: "UNWIND WHERE clause may only reference c, got p",
+		},
+		{
+			name:    "unwind at the end of a query",
+			input:   "MATCH (p:Pod) UNWIND p.spec.containers AS c",
+			wantErr: "UNWIND must be followed by another clause",
+		},
+		{
+			name:    "unwind after return",
+			input:   "MATCH (p:Pod) RETURN p UNWIND p.spec.containers AS c",
+			wantErr: "UNWIND can only follow MATCH, WITH or UNWIND",
+		},
+	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1065,3 +2393,88 @@ func TestParserErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQueryWithParams(t *testing.T) {
+	params := map[string]interface{}{
+		"kind":     "Deployment",
+		"name":     `web" OR d.metadata.name = "api`,
+		"replicas": float64(3),
+		"labels":   map[string]interface{}{"app": "web"},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		check   func(t *testing.T, expr *Expression)
+		wantErr string
+	}{
+		{
+			name:  "parameters as kind and property",
+			input: "MATCH (d:$kind {name: $name}) RETURN d",
+			check: func(t *testing.T, expr *Expression) {
+				node := expr.Clauses[0].(*MatchClause).Nodes[0]
+				if node.ResourceProperties.Kind != "Deployment" {
+					t.Errorf("got kind %q, want %q", node.ResourceProperties.Kind, "Deployment")
+				}
+				if got := node.ResourceProperties.Properties.PropertyList[0].Value; got != params["name"] {
+					t.Errorf("got name %v, want %v", got, params["name"])
+				}
+			},
+		},
+		{
+			name:  "parameters in WHERE and SET are typed",
+			input: "MATCH (d:Deployment) WHERE d.spec.replicas < $replicas SET d.spec.replicas = $replicas",
+			check: func(t *testing.T, expr *Expression) {
+				filter := expr.Clauses[0].(*MatchClause).ExtraFilters[0]
+				if got := filter.KeyValuePair.Value; got != 3 {
+					t.Errorf("got WHERE value %#v, want 3", got)
+				}
+				set := expr.Clauses[1].(*SetClause).KeyValuePairs[0]
+				if got := set.Value; got != 3 {
+					t.Errorf("got SET value %#v, want 3", got)
+				}
+			},
+		},
+		{
+			name:  "parameters in JSON data are encoded",
+			input: `CREATE (d:Deployment {"metadata": {"name": $name, "labels": $labels}})`,
+			check: func(t *testing.T, expr *Expression) {
+				jsonData := expr.Clauses[0].(*CreateClause).Nodes[0].ResourceProperties.JsonData
+				want := `{"metadata":{"name":"web\" OR d.metadata.name = \"api","labels":{"app":"web"}}}`
+				if jsonData != want {
+					t.Errorf("got JSON data %s, want %s", jsonData, want)
+				}
+			},
+		},
+		{
+			name:    "missing parameter",
+			input:   "MATCH (d:Deployment {name: $missing}) RETURN d",
+			wantErr: "missing value for parameter $missing",
+		},
+		{
+			name:    "non-string kind parameter",
+			input:   "MATCH (d:$replicas) RETURN d",
+			wantErr: "parameter used as kind must be a non-empty string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseQueryWithParams(tt.input, params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseQueryWithParams() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQueryWithParams() error = %v", err)
+			}
+			tt.check(t, expr)
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
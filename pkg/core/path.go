@@ -0,0 +1,216 @@
+package core
+
+import "fmt"
+
+// pathSegment is the part of a path that follows a single relationship of its pattern: the
+// resources it reaches, ending with a resource of the relationship's right node, and the
+// relationships between them. Only variable-length relationships reach more than one resource.
+type pathSegment struct {
+	nodes         []map[string]interface{}
+	relationships []map[string]interface{}
+}
+
+// processPathVariables binds each path variable of a match clause to the paths matched by its
+// pattern, one for every chain of related resources from its first node to its last
+func (q *QueryExecutor) processPathVariables(c *MatchClause) error {
+	for _, path := range c.Paths {
+		entries, err := q.matchPaths(path, func(name string) []map[string]interface{} {
+			resultMapMutex.RLock()
+			defer resultMapMutex.RUnlock()
+			resources, _ := resultMap[name].([]map[string]interface{})
+			return resources
+		})
+		if err != nil {
+			return err
+		}
+
+		resultMapMutex.Lock()
+		resultMap[path.Name] = entries
+		resultMapMutex.Unlock()
+	}
+	return nil
+}
+
+// matchPaths returns the entries of the paths that follow a path pattern through the resources
+// of its nodes
+func (q *QueryExecutor) matchPaths(path *PathPattern, resources func(name string) []map[string]interface{}) ([]map[string]interface{}, error) {
+	entries := []map[string]interface{}{}
+	for _, resource := range resources(path.Nodes[0]) {
+		extended, err := q.extendPath(path, resources, []map[string]interface{}{resource}, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, extended...)
+	}
+	return entries, nil
+}
+
+// extendPath follows the relationships of a path pattern from the given one on, and returns the
+// entries of the complete paths that continue the partial path
+func (q *QueryExecutor) extendPath(path *PathPattern, resources func(name string) []map[string]interface{}, nodes, relationships []map[string]interface{}, step int) ([]map[string]interface{}, error) {
+	if step == len(path.Relationships) {
+		return []map[string]interface{}{pathEntry(nodes, relationships)}, nil
+	}
+
+	segments, err := q.pathSegments(path.Relationships[step], nodes[len(nodes)-1], resources(path.Nodes[step+1]))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []map[string]interface{}
+	for _, segment := range segments {
+		extended, err := q.extendPath(
+			path,
+			resources,
+			append(append([]map[string]interface{}{}, nodes...), segment.nodes...),
+			append(append([]map[string]interface{}{}, relationships...), segment.relationships...),
+			step+1,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, extended...)
+	}
+	return entries, nil
+}
+
+// pathSegments returns the segments that follow a relationship from a resource of its left node
+// to any of the given resources of its right node
+func (q *QueryExecutor) pathSegments(rel *Relationship, from map[string]interface{}, candidates []map[string]interface{}) ([]pathSegment, error) {
+	var segments []pathSegment
+	if rel.MaxHops == 0 {
+		for _, candidate := range candidates {
+			rules, err := q.relatingRules(rel, from, candidate)
+			if err != nil {
+				return nil, err
+			}
+			for _, rule := range rules {
+				segments = append(segments, pathSegment{
+					nodes:         []map[string]interface{}{candidate},
+					relationships: []map[string]interface{}{directedRelationshipEntry(rule, from, candidate, rel.Direction)},
+				})
+			}
+		}
+		return segments, nil
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// The nodes of variable-length relationships have a single kind
+	leftKind, err := q.findGVR(fmt.Sprint(from["kind"]))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+	rightKind, err := q.findGVR(fmt.Sprint(candidates[0]["kind"]))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+	for _, hops := range findRelationshipPaths(leftKind.Resource, rightKind.Resource, rel.MinHops, rel.MaxHops, rel.Direction, relationshipTypes(rel)) {
+		hopSegments, err := q.hopSegments(hops, rel.Direction, from, candidates)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, hopSegments...)
+	}
+	return segments, nil
+}
+
+// relatingRules returns the rules of a relationship's types that relate a resource of its left
+// node to a resource of its right node in its direction. The kinds of the resources are used, as
+// kind-less nodes match resources of several kinds.
+func (q *QueryExecutor) relatingRules(rel *Relationship, left, right map[string]interface{}) ([]RelationshipRule, error) {
+	leftKind, err := q.findGVR(fmt.Sprint(left["kind"]))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+	rightKind, err := q.findGVR(fmt.Sprint(right["kind"]))
+	if err != nil {
+		return nil, fmt.Errorf("error finding API resource >> %s", err)
+	}
+
+	// Kinds that no rule relates in the relationship's direction aren't related
+	rules, err := findRulesBetweenKinds(leftKind.Resource, rightKind.Resource, rel.Direction, relationshipTypes(rel))
+	if err != nil {
+		return nil, nil
+	}
+
+	var relating []RelationshipRule
+	for _, rule := range rules {
+		if ruleRelates(rule, leftKind.Resource, rightKind.Resource, rel.Direction, left, right) {
+			relating = append(relating, rule)
+		}
+	}
+	return relating, nil
+}
+
+// hopSegments returns the segments that follow a kind path from a resource to any of the given
+// resources, through the resources of the kinds in between
+func (q *QueryExecutor) hopSegments(hops []pathHop, direction Direction, from map[string]interface{}, candidates []map[string]interface{}) ([]pathSegment, error) {
+	hop := hops[0]
+	next := candidates
+	if len(hops) > 1 {
+		var err error
+		next, err = q.getHopResources(hop.toKind)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var segments []pathSegment
+	for _, resource := range next {
+		if !hopMatches(hop, from, resource) {
+			continue
+		}
+		segment := pathSegment{
+			nodes:         []map[string]interface{}{resource},
+			relationships: []map[string]interface{}{directedRelationshipEntry(hop.rule, from, resource, direction)},
+		}
+		if len(hops) == 1 {
+			segments = append(segments, segment)
+			continue
+		}
+
+		rest, err := q.hopSegments(hops[1:], direction, resource, candidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, tail := range rest {
+			segments = append(segments, pathSegment{
+				nodes:         append(append([]map[string]interface{}{}, segment.nodes...), tail.nodes...),
+				relationships: append(append([]map[string]interface{}{}, segment.relationships...), tail.relationships...),
+			})
+		}
+	}
+	return segments, nil
+}
+
+// pathEntry describes a path bound to a variable by the resources along it, in order, and the
+// relationships between them
+func pathEntry(resources, relationships []map[string]interface{}) map[string]interface{} {
+	nodes := make([]interface{}, len(resources))
+	for i, resource := range resources {
+		metadata, _ := resource["metadata"].(map[string]interface{})
+		node := map[string]interface{}{
+			"kind": resource["kind"],
+			"name": metadata["name"],
+		}
+		if namespace, ok := metadata["namespace"].(string); ok && namespace != "" {
+			node["namespace"] = namespace
+		}
+		nodes[i] = node
+	}
+
+	rels := make([]interface{}, len(relationships))
+	for i, relationship := range relationships {
+		rels[i] = relationship
+	}
+	return map[string]interface{}{"nodes": nodes, "relationships": rels}
+}
+
+// isPathEntry reports whether a matched item is a path rather than a resource
+func isPathEntry(item map[string]interface{}) bool {
+	_, hasNodes := item["nodes"]
+	_, hasRelationships := item["relationships"]
+	return hasNodes && hasRelationships && item["kind"] == nil
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecutePathVariables(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"ingresses": {
+			mockResource("Ingress", "web", routingTo("web")),
+		},
+		"services": {
+			mockResource("Service", "web", selecting(map[string]interface{}{"app": "web"})),
+		},
+		"pods": {
+			mockResource("Pod", "web-1", labeled(map[string]interface{}{"app": "web"})),
+			mockResource("Pod", "web-2", labeled(map[string]interface{}{"app": "web"})),
+			mockResource("Pod", "api-1", labeled(map[string]interface{}{"app": "api"})),
+		},
+	}
+
+	node := func(kind, name string) interface{} {
+		return map[string]interface{}{"kind": kind, "name": name, "namespace": "default"}
+	}
+	routeToWeb := map[string]interface{}{"type": "ROUTE", "start": "Ingress/web", "end": "Service/web"}
+	exposePod := func(pod string) interface{} {
+		return map[string]interface{}{"type": "SERVICE_EXPOSE_POD", "start": "Service/web", "end": "Pod/" + pod}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name:  "path with one entry per chain",
+			query: `MATCH p = (i:Ingress)->(s:Service)->(pod:Pod) RETURN p`,
+			want: []interface{}{
+				map[string]interface{}{"$": map[string]interface{}{
+					"nodes":         []interface{}{node("Ingress", "web"), node("Service", "web"), node("Pod", "web-1")},
+					"relationships": []interface{}{routeToWeb, exposePod("web-1")},
+				}},
+				map[string]interface{}{"$": map[string]interface{}{
+					"nodes":         []interface{}{node("Ingress", "web"), node("Service", "web"), node("Pod", "web-2")},
+					"relationships": []interface{}{routeToWeb, exposePod("web-2")},
+				}},
+			},
+		},
+		{
+			name:  "nodes and length of a variable-length path",
+			query: `MATCH p = (i:Ingress)-[*2]->(pod:Pod {name: "web-1"}) RETURN nodes(p) AS chain, length(p) AS hops`,
+			want: []interface{}{
+				map[string]interface{}{
+					"chain": []interface{}{node("Ingress", "web"), node("Service", "web"), node("Pod", "web-1")},
+					"hops":  2,
+				},
+			},
+		},
+		{
+			name:  "relationships of a path to a kind-less node",
+			query: `MATCH p = (s:Service)->(x) RETURN relationships(p) AS rels`,
+			want: []interface{}{
+				map[string]interface{}{"rels": []interface{}{exposePod("web-1")}},
+				map[string]interface{}{"rels": []interface{}{exposePod("web-2")}},
+			},
+		},
+		{
+			name:    "path function of a node",
+			query:   `MATCH (pod:Pod) RETURN length(pod)`,
+			wantErr: "expected a path, got pod/web-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("executeMockQuery() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data["p"], tt.want) {
+				t.Errorf("got %v, want %v", result.Data["p"], tt.want)
+			}
+		})
+	}
+}
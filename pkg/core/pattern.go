@@ -0,0 +1,208 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// matchPatterns evaluates the pattern predicates of a filter that are anchored on the given node,
+// and records the keys of the resources that satisfy each pattern in matches.
+func (q *QueryExecutor) matchPatterns(resources []map[string]interface{}, filter *Filter, n *NodePattern, matches map[*Filter]map[string]bool) error {
+	if filter.Type != PatternFilter {
+		for _, operand := range filter.Operands {
+			if err := q.matchPatterns(resources, operand, n, matches); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	anchor := -1
+	for i, node := range filter.Pattern.Nodes {
+		if node.ResourceProperties.Name == n.ResourceProperties.Name {
+			anchor = i
+		}
+	}
+	if anchor == -1 {
+		return nil
+	}
+
+	// Pattern nodes other than the anchor are bound to the kind they declare
+	kinds := make([]string, len(filter.Pattern.Nodes))
+	for i, node := range filter.Pattern.Nodes {
+		kind := node.ResourceProperties.Kind
+		if i == anchor {
+			kind = n.ResourceProperties.Kind
+		}
+		gvr, err := q.findGVR(kind)
+		if err != nil {
+			return fmt.Errorf("error finding API resource >> %s", err)
+		}
+		kinds[i] = gvr.Resource
+	}
+
+	// Walk the pattern from the anchor to each of its ends
+	var toLeft, toRight []int
+	for i := anchor - 1; i >= 0; i-- {
+		toLeft = append(toLeft, i)
+	}
+	for i := anchor + 1; i < len(filter.Pattern.Nodes); i++ {
+		toRight = append(toRight, i)
+	}
+
+	matched := resources
+	for _, walk := range [][]int{toLeft, toRight} {
+		var err error
+		matched, err = q.walkPattern(matched, filter.Pattern, kinds, anchor, walk)
+		if err != nil {
+			return err
+		}
+	}
+
+	matches[filter] = make(map[string]bool)
+	for _, resource := range matched {
+		matches[filter][resourceKey(resource)] = true
+	}
+	return nil
+}
+
+// walkPattern follows the pattern from the anchor node through the given node indices,
+// and returns the anchor resources that are part of at least one complete chain.
+func (q *QueryExecutor) walkPattern(anchorResources []map[string]interface{}, pattern *NodeRelationshipList, kinds []string, anchor int, walk []int) ([]map[string]interface{}, error) {
+	if len(walk) == 0 {
+		return anchorResources, nil
+	}
+
+	indices := append([]int{anchor}, walk...)
+	layers := [][]map[string]interface{}{anchorResources}
+
+	// Walk forward, keeping the resources reachable from the anchor resources
+	for i := 1; i < len(indices); i++ {
+		candidates, err := q.getPatternNodeResources(pattern.Nodes[indices[i]], kinds[indices[i]])
+		if err != nil {
+			return nil, err
+		}
+		rel := patternRelationship(pattern, pattern.Nodes[indices[i-1]], pattern.Nodes[indices[i]])
+		_, reached, err := q.relateResources(layers[i-1], candidates, kinds[indices[i-1]], kinds[indices[i]], rel, pattern.Nodes[indices[i-1]].ResourceProperties.Name)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, reached)
+	}
+
+	// Walk backward, keeping the resources that lead to the end of the pattern
+	for i := len(indices) - 2; i >= 0; i-- {
+		rel := patternRelationship(pattern, pattern.Nodes[indices[i]], pattern.Nodes[indices[i+1]])
+		leading, _, err := q.relateResources(layers[i], layers[i+1], kinds[indices[i]], kinds[indices[i+1]], rel, pattern.Nodes[indices[i]].ResourceProperties.Name)
+		if err != nil {
+			return nil, err
+		}
+		layers[i] = leading
+	}
+
+	return layers[0], nil
+}
+
+// relateResources returns the resources on each side that are related to at least one resource on the other side.
+// The resources on the from side are those of the given node of the relationship.
+func (q *QueryExecutor) relateResources(from, to []map[string]interface{}, fromKind, toKind string, rel *Relationship, fromNode string) ([]map[string]interface{}, []map[string]interface{}, error) {
+	if len(from) == 0 || len(to) == 0 {
+		return nil, nil, nil
+	}
+	direction := directionFrom(rel, fromNode)
+
+	if rel.MaxHops > 0 {
+		paths := findRelationshipPaths(fromKind, toKind, rel.MinHops, rel.MaxHops, direction, relationshipTypes(rel))
+		if len(paths) == 0 {
+			return nil, nil, fmt.Errorf("no relationship path of %d to %d hops found between %s and %s", rel.MinHops, rel.MaxHops, fromKind, toKind)
+		}
+
+		var matchedFrom, matchedTo []map[string]interface{}
+		for _, path := range paths {
+			layers, err := walkRelationshipPath(from, to, path, q.getHopResources)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, resource := range layers[0] {
+				if !containsResource(matchedFrom, resource) {
+					matchedFrom = append(matchedFrom, resource)
+				}
+			}
+			for _, resource := range layers[len(layers)-1] {
+				if !containsResource(matchedTo, resource) {
+					matchedTo = append(matchedTo, resource)
+				}
+			}
+		}
+		return matchedFrom, matchedTo, nil
+	}
+
+	rules, err := findRulesBetweenKinds(fromKind, toKind, direction, relationshipTypes(rel))
+	if err != nil {
+		return nil, nil, err
+	}
+	matchedFrom, matchedTo := relateByRules(from, to, fromKind, toKind, direction, rules)
+	return matchedFrom, matchedTo, nil
+}
+
+// patternRelationship returns the relationship of a pattern connecting two of its nodes
+func patternRelationship(pattern *NodeRelationshipList, a, b *NodePattern) *Relationship {
+	for _, rel := range pattern.Relationships {
+		if (rel.LeftNode == a && rel.RightNode == b) || (rel.LeftNode == b && rel.RightNode == a) {
+			return rel
+		}
+	}
+	return &Relationship{}
+}
+
+// getPatternNodeResources returns the resources of a pattern node's kind that match its properties
+func (q *QueryExecutor) getPatternNodeResources(node *NodePattern, kind string) ([]map[string]interface{}, error) {
+	resources, err := q.getHopResources(kind)
+	if err != nil {
+		return nil, err
+	}
+	if node.ResourceProperties.Properties == nil {
+		return resources, nil
+	}
+
+	var matched []map[string]interface{}
+	for _, resource := range resources {
+		if matchesNodeProperties(resource, node.ResourceProperties.Properties) {
+			matched = append(matched, resource)
+		}
+	}
+	return matched, nil
+}
+
+// matchesNodeProperties reports whether a resource matches the name, namespace and label
+// selectors of a node pattern's properties
+func matchesNodeProperties(resource map[string]interface{}, properties *Properties) bool {
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	labels, _ := metadata["labels"].(map[string]interface{})
+
+	for _, prop := range properties.PropertyList {
+		key := strings.Trim(prop.Key, `"`)
+		value := fmt.Sprintf("%v", prop.Value)
+		switch key {
+		case "name", "metadata.name":
+			if metadata["name"] != value {
+				return false
+			}
+		case "namespace", "metadata.namespace":
+			if getNamespaceName(metadata) != value {
+				return false
+			}
+		default:
+			if fmt.Sprintf("%v", labels[key]) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resourceKey identifies a resource by its namespace and name
+func resourceKey(resource map[string]interface{}) string {
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	return fmt.Sprintf("%s/%v", getNamespaceName(metadata), metadata["name"])
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecutePatternPredicate(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"services": {
+			mockResource("Service", "api", selecting(map[string]interface{}{"app": "api"})),
+			mockResource("Service", "orphan", selecting(map[string]interface{}{"app": "orphan"})),
+		},
+		"endpoints": {
+			mockResource("Endpoints", "api", nil),
+		},
+		"pods": {
+			mockResource("Pod", "api-1", map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "api-1", "labels": map[string]interface{}{"app": "api"}},
+			}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "services with endpoints",
+			query:    "MATCH (s:Service) WHERE (s)->(:Endpoints) RETURN s.metadata.name",
+			expected: []string{"api"},
+		},
+		{
+			name:     "services without endpoints",
+			query:    "MATCH (s:Service) WHERE NOT (s)->(:Endpoints) RETURN s.metadata.name",
+			expected: []string{"orphan"},
+		},
+		{
+			name:     "pattern with properties",
+			query:    `MATCH (s:Service) WHERE (s)->(:Pod {name: "api-1"}) RETURN s.metadata.name`,
+			expected: []string{"api"},
+		},
+		{
+			name:     "pattern combined with comparison",
+			query:    `MATCH (s:Service) WHERE s.metadata.name = "api" AND NOT (s)->(:Endpoints) RETURN s.metadata.name`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []string
+			for _, service := range result.Data["s"].([]interface{}) {
+				got = append(got, service.(map[string]interface{})["name"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
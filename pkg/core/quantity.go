@@ -0,0 +1,135 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// quantityKind is the kind of resource quantity a string holds
+type quantityKind int
+
+const (
+	cpuQuantity quantityKind = iota + 1
+	memoryQuantity
+)
+
+// quantity is a CPU quantity in millicores or a memory quantity in bytes
+type quantity struct {
+	kind  quantityKind
+	value float64
+}
+
+var (
+	cpuQuantityRegex    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?m$`)
+	memoryQuantityRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([EPTGMk]|[EPTGMK]i)$`)
+	plainQuantityRegex  = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+// parseQuantity parses a CPU quantity to millicores or a memory quantity to bytes. Strings with a
+// millicore or memory unit suffix are quantities of their own. Numeric strings, such as "1" or
+// "0.5" cores and "1048576" bytes, are quantities when the kind of the field they were read from
+// is given, and plain numbers otherwise. WHERE, ORDER BY, aggregations and SET all parse
+// quantities here, so that they agree on their values.
+func parseQuantity(value interface{}, kind quantityKind) (quantity, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return quantity{}, false
+	}
+	switch {
+	case cpuQuantityRegex.MatchString(s):
+		milliCPU, err := strconv.ParseFloat(strings.TrimSuffix(s, "m"), 64)
+		return quantity{kind: cpuQuantity, value: milliCPU}, err == nil
+	case memoryQuantityRegex.MatchString(s):
+		bytes, err := convertMemoryToBytes(s)
+		return quantity{kind: memoryQuantity, value: float64(bytes)}, err == nil
+	case kind != 0 && plainQuantityRegex.MatchString(s):
+		n, err := strconv.ParseFloat(s, 64)
+		return quantity{kind: kind, value: plainQuantity(kind, n)}, err == nil
+	}
+	return quantity{}, false
+}
+
+// plainQuantity converts a plain number to the base unit of a kind of quantity. Plain numbers
+// count cores and bytes, like they do in resource specs.
+func plainQuantity(kind quantityKind, n float64) float64 {
+	if kind == cpuQuantity {
+		return n * 1000
+	}
+	return n
+}
+
+// formatQuantity formats a CPU quantity in millicores or a memory quantity in bytes
+func formatQuantity(kind quantityKind, value float64) string {
+	if kind == cpuQuantity {
+		return convertMilliCPUToStandard(int(math.Round(value)))
+	}
+	return formatBytes(int64(math.Round(value)))
+}
+
+// formatBytes formats a number of bytes exactly, with the largest binary or decimal unit that
+// divides it evenly
+func formatBytes(bytes int64) string {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"Ei", 1 << 60}, {"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+		{"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"k", 1e3},
+	}
+	if bytes == 0 {
+		return "0"
+	}
+	for _, unit := range units {
+		if bytes%unit.multiplier == 0 {
+			return strconv.FormatInt(bytes/unit.multiplier, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(bytes, 10)
+}
+
+// quantitiesToFloat64 converts two CPU or memory quantities of the same kind to millicores or
+// bytes. A plain number compared with a quantity counts cores or bytes, so "500m" < 1.
+func quantitiesToFloat64(a, b interface{}) (float64, float64, bool) {
+	aQuantity, aIsQuantity := parseQuantity(a, 0)
+	bQuantity, bIsQuantity := parseQuantity(b, 0)
+	switch {
+	case aIsQuantity && bIsQuantity:
+		return aQuantity.value, bQuantity.value, aQuantity.kind == bQuantity.kind
+	case aIsQuantity:
+		n, err := toFloat64(b)
+		return aQuantity.value, plainQuantity(aQuantity.kind, n), err == nil
+	case bIsQuantity:
+		n, err := toFloat64(a)
+		return plainQuantity(bQuantity.kind, n), bQuantity.value, err == nil
+	}
+	return 0, 0, false
+}
+
+// quantityValue converts a value of a CPU or memory field to millicores or bytes. Numbers count
+// cores or bytes, like numeric strings do.
+func quantityValue(value interface{}, kind quantityKind) (float64, error) {
+	if q, ok := parseQuantity(value, kind); ok && q.kind == kind {
+		return q.value, nil
+	}
+	if _, isString := value.(string); !isString {
+		if n, err := toFloat64(value); err == nil {
+			return plainQuantity(kind, n), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid quantity: %v", value)
+}
+
+// pathQuantityKind returns the kind of quantity the resource requests and limits at a path hold,
+// or 0 for paths that don't lead to CPU or memory
+func pathQuantityKind(path string) quantityKind {
+	switch {
+	case strings.Contains(path, "resources.limits.cpu") || strings.Contains(path, "resources.requests.cpu"):
+		return cpuQuantity
+	case strings.Contains(path, "resources.limits.memory") || strings.Contains(path, "resources.requests.memory"):
+		return memoryQuantity
+	}
+	return 0
+}
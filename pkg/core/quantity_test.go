@@ -0,0 +1,74 @@
+package core
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		kind     quantityKind
+		expected quantity
+		ok       bool
+	}{
+		{"millicores", "500m", 0, quantity{cpuQuantity, 500}, true},
+		{"fractional millicores", "1.5m", 0, quantity{cpuQuantity, 1.5}, true},
+		{"binary memory", "512Mi", 0, quantity{memoryQuantity, 512 << 20}, true},
+		{"decimal memory", "1G", 0, quantity{memoryQuantity, 1e9}, true},
+		{"numeric string without a kind", "1", 0, quantity{}, false},
+		{"whole cores", "1", cpuQuantity, quantity{cpuQuantity, 1000}, true},
+		{"fractional cores", "0.5", cpuQuantity, quantity{cpuQuantity, 500}, true},
+		{"plain bytes", "1048576", memoryQuantity, quantity{memoryQuantity, 1 << 20}, true},
+		{"suffix wins over the kind", "500m", memoryQuantity, quantity{cpuQuantity, 500}, true},
+		{"numbers aren't parsed", 1, cpuQuantity, quantity{}, false},
+		{"other strings", "nginx", cpuQuantity, quantity{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseQuantity(tt.value, tt.kind)
+			if ok != tt.ok || (ok && got != tt.expected) {
+				t.Errorf("parseQuantity(%v, %v) = %v, %v, want %v, %v", tt.value, tt.kind, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestQuantitiesAgree(t *testing.T) {
+	// WHERE, ORDER BY and aggregations compare the same quantities the same way
+	tests := []struct {
+		name         string
+		smaller      interface{}
+		larger       interface{}
+		isCPU        bool
+		isMemory     bool
+		expectedMax  interface{}
+		expectedSum  interface{}
+		expectedMean interface{}
+	}{
+		{"millicores and cores", "500m", "1", true, false, "1", "1.5", "750m"},
+		{"fractional cores and millicores", "0.25", "300m", true, false, "300m", "550m", "275m"},
+		{"millicores and a number of cores", "500m", 2, true, false, 2, "2.5", "1.25"},
+		{"memory units and bytes", "512Mi", "1073741824", false, true, "1073741824", "1.5Gi", "768Mi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(tt.smaller, tt.larger, "<"); got != conditionTrue {
+				t.Errorf("WHERE %v < %v = %v, want true", tt.smaller, tt.larger, got)
+			}
+			if got := compareOrderValues(tt.smaller, tt.larger); got != -1 {
+				t.Errorf("ORDER BY compares %v to %v as %d, want -1", tt.smaller, tt.larger, got)
+			}
+			values := []interface{}{tt.smaller, tt.larger}
+			for aggregate, expected := range map[string]interface{}{"MAX": tt.expectedMax, "SUM": tt.expectedSum, "AVG": tt.expectedMean} {
+				got, err := aggregateValues(aggregate, values, tt.isCPU, tt.isMemory)
+				if err != nil {
+					t.Fatalf("%s(%v) error = %v", aggregate, values, err)
+				}
+				if got != expected {
+					t.Errorf("%s(%v) = %v, want %v", aggregate, values, got, expected)
+				}
+			}
+		})
+	}
+}
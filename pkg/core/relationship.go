@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,6 +18,10 @@ var (
 	relationships      = make(map[string][]string)
 )
 
+// errReversedRelationship is wrapped by the errors of relationships that point against the relationship
+// rules between their kinds
+var errReversedRelationship = errors.New("relationships between them point the other way")
+
 func AddRelationshipRule(rule RelationshipRule) {
 	relationshipRules = append(relationshipRules, rule)
 }
@@ -34,6 +39,218 @@ func findRuleByRelationshipType(relType RelationshipType) (RelationshipRule, err
 	return RelationshipRule{}, fmt.Errorf("no rule found for relationship type: %s", relType)
 }
 
+// relationshipTypes returns the types a relationship is restricted to, e.g. -[:ROUTE|SERVICE_EXPOSE_POD]->.
+// A relationship without types may be of any type.
+func relationshipTypes(rel *Relationship) []RelationshipType {
+	if rel.ResourceProperties == nil || rel.ResourceProperties.Kind == "" {
+		return nil
+	}
+	var types []RelationshipType
+	for _, relType := range strings.Split(rel.ResourceProperties.Kind, "|") {
+		types = append(types, RelationshipType(relType))
+	}
+	return types
+}
+
+// ruleHasType reports whether a rule is of one of the given relationship types, any rule is when there are none
+func ruleHasType(rule RelationshipRule, types []RelationshipType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, relType := range types {
+		if strings.EqualFold(string(rule.Relationship), string(relType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findRulesBetweenKinds returns the relationship rules of the given types relating the kinds of the
+// left and right nodes of a relationship in its direction
+func findRulesBetweenKinds(leftKind, rightKind string, direction Direction, types []RelationshipType) ([]RelationshipRule, error) {
+	var rules []RelationshipRule
+	reversed := false
+	for _, rule := range relationshipRules {
+		if !ruleHasType(rule, types) {
+			continue
+		}
+		leftIsA, leftIsB := ruleSides(rule, leftKind, rightKind, direction)
+		if leftIsA || leftIsB {
+			rules = append(rules, rule)
+			continue
+		}
+		if leftIsA, leftIsB = ruleSides(rule, leftKind, rightKind, None); leftIsA || leftIsB {
+			reversed = true
+		}
+	}
+
+	if len(rules) == 0 {
+		switch {
+		case reversed:
+			from, to, arrow, reversedArrow := leftKind, rightKind, "->", "<-"
+			if direction == Left {
+				from, to, arrow, reversedArrow = rightKind, leftKind, "<-", "->"
+			}
+			return nil, fmt.Errorf("no relationship points from %s to %s, %w, did you mean %s instead of %s?", from, to, errReversedRelationship, reversedArrow, arrow)
+		case len(types) > 0:
+			names := make([]string, len(types))
+			for i, relType := range types {
+				names[i] = string(relType)
+			}
+			return nil, fmt.Errorf("no relationship of type %s between %s and %s", strings.Join(names, "|"), leftKind, rightKind)
+		default:
+			return nil, fmt.Errorf("relationship type not found between %s and %s", leftKind, rightKind)
+		}
+	}
+	return rules, nil
+}
+
+// warnReversedRelationships warns about each of the relationships of a match clause that points against
+// the relationship rules between its kinds. Such relationships relate nothing, like they do in Cypher,
+// the warning suggests the arrow that matches instead.
+func (q *QueryExecutor) warnReversedRelationships(relationships []*Relationship, results *QueryResult) {
+	for _, rel := range relationships {
+		if (rel.Direction != Left && rel.Direction != Right) || rel.MaxHops > 0 ||
+			rel.LeftNode.ResourceProperties.Kind == "" || rel.RightNode.ResourceProperties.Kind == "" {
+			continue
+		}
+		// Unknown kinds are reported when the relationship is processed
+		leftKind, err := q.findGVR(rel.LeftNode.ResourceProperties.Kind)
+		if err != nil {
+			continue
+		}
+		rightKind, err := q.findGVR(rel.RightNode.ResourceProperties.Kind)
+		if err != nil {
+			continue
+		}
+		types := relationshipTypes(rel)
+		if _, err := findRulesBetweenKinds(leftKind.Resource, rightKind.Resource, rel.Direction, types); err == nil {
+			continue
+		}
+		if _, err := findRulesBetweenKinds(leftKind.Resource, rightKind.Resource, None, types); err != nil {
+			continue
+		}
+
+		arrow, reversedArrow := "->", "<-"
+		if rel.Direction == Left {
+			arrow, reversedArrow = "<-", "->"
+		}
+		left := fmt.Sprintf("(%s:%s)", rel.LeftNode.ResourceProperties.Name, rel.LeftNode.ResourceProperties.Kind)
+		right := fmt.Sprintf("(%s:%s)", rel.RightNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Kind)
+		results.Warnings = append(results.Warnings, fmt.Sprintf(
+			"%s%s%s points against the relationships between %s and %s and matches nothing, did you mean %s%s%s?",
+			left, arrow, right, leftKind.Resource, rightKind.Resource, left, reversedArrow, right))
+	}
+}
+
+// ruleSides reports whether a rule relates the left node of a relationship as its KindA, and whether
+// it relates it as its KindB, given the kinds of the relationship's nodes and its direction
+func ruleSides(rule RelationshipRule, leftKind, rightKind string, direction Direction) (bool, bool) {
+	leftIsA := ruleKindMatches(rule.KindA, leftKind) && ruleKindMatches(rule.KindB, rightKind) && ruleFollows(rule, true, direction)
+	leftIsB := ruleKindMatches(rule.KindB, leftKind) && ruleKindMatches(rule.KindA, rightKind) && ruleFollows(rule, false, direction)
+	return leftIsA, leftIsB
+}
+
+// ruleKindMatches reports whether a kind of a rule is the given resource kind, the namespace rule relates every kind
+func ruleKindMatches(ruleKind, kind string) bool {
+	return ruleKind == "*" || strings.EqualFold(ruleKind, kind)
+}
+
+// ruleFollows reports whether relating the left node of a relationship as a rule's KindA, or as its KindB,
+// follows the direction of the relationship. Relationships that are drawn without an arrow have no direction.
+func ruleFollows(rule RelationshipRule, leftIsA bool, direction Direction) bool {
+	switch direction {
+	case Right:
+		return leftIsA == rule.KindAToKindB
+	case Left:
+		return leftIsA != rule.KindAToKindB
+	}
+	return true
+}
+
+// directionFrom returns the direction of a relationship as seen from one of its nodes, e.g. (a)->(b) points
+// right from a and left from b
+func directionFrom(rel *Relationship, node string) Direction {
+	if rel.RightNode == nil || rel.RightNode.ResourceProperties.Name != node {
+		return rel.Direction
+	}
+	switch rel.Direction {
+	case Right:
+		return Left
+	case Left:
+		return Right
+	}
+	return rel.Direction
+}
+
+// relateByRules returns the resources on each side of a relationship that any of the given rules relates
+// to a resource on the other side in the relationship's direction
+func relateByRules(left, right []map[string]interface{}, leftKind, rightKind string, direction Direction, rules []RelationshipRule) ([]map[string]interface{}, []map[string]interface{}) {
+	matchedLeft := []map[string]interface{}{}
+	matchedRight := []map[string]interface{}{}
+	for _, rule := range rules {
+		leftIsA, leftIsB := ruleSides(rule, leftKind, rightKind, direction)
+		var matches []map[string]interface{}
+		if leftIsA {
+			matches = append(matches, applyRelationshipRule(left, right, rule, Right))
+		}
+		if leftIsB {
+			matches = append(matches, applyRelationshipRule(right, left, rule, Left))
+		}
+		for _, matchedResources := range matches {
+			for _, resource := range matchedResources["left"].([]map[string]interface{}) {
+				if !containsResource(matchedLeft, resource) {
+					matchedLeft = append(matchedLeft, resource)
+				}
+			}
+			for _, resource := range matchedResources["right"].([]map[string]interface{}) {
+				if !containsResource(matchedRight, resource) {
+					matchedRight = append(matchedRight, resource)
+				}
+			}
+		}
+	}
+	return matchedLeft, matchedRight
+}
+
+// ruleRelates reports whether a rule relates a resource of the left node of a relationship to a resource
+// of its right node in the relationship's direction
+func ruleRelates(rule RelationshipRule, leftKind, rightKind string, direction Direction, left, right map[string]interface{}) bool {
+	leftIsA, leftIsB := ruleSides(rule, leftKind, rightKind, direction)
+	for _, criterion := range rule.MatchCriteria {
+		if (leftIsA && matchByCriterion(left, right, criterion)) || (leftIsB && matchByCriterion(right, left, criterion)) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationshipEntry describes a relationship bound to a variable, e.g. -[r]->, by its type and the
+// resources at its start and end
+func relationshipEntry(rule RelationshipRule, start, end map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":  string(rule.Relationship),
+		"start": fmt.Sprintf("%v/%v", start["kind"], start["metadata"].(map[string]interface{})["name"]),
+		"end":   fmt.Sprintf("%v/%v", end["kind"], end["metadata"].(map[string]interface{})["name"]),
+	}
+}
+
+// directedRelationshipEntry describes the relationship between the left and right resources of a
+// pattern, which starts at the right resource when the pattern points left
+func directedRelationshipEntry(rule RelationshipRule, left, right map[string]interface{}, direction Direction) map[string]interface{} {
+	if direction == Left {
+		return relationshipEntry(rule, right, left)
+	}
+	return relationshipEntry(rule, left, right)
+}
+
+// isRelationshipEntry reports whether a matched item is a relationship rather than a resource
+func isRelationshipEntry(item map[string]interface{}) bool {
+	_, hasStart := item["start"]
+	_, hasEnd := item["end"]
+	return hasStart && hasEnd && item["kind"] == nil
+}
+
 func applyRelationshipRule(resourcesA, resourcesB []map[string]interface{}, rule RelationshipRule, direction Direction) map[string]interface{} {
 	var matchedResourcesA []map[string]interface{}
 	var matchedResourcesB []map[string]interface{}
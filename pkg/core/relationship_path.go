@@ -0,0 +1,266 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultMaxHops bounds variable-length relationships that have no upper bound, i.e. -[*]-> or -[*2..]->
+const defaultMaxHops = 5
+
+// hopResourceCache holds the resources of the intermediate kinds walked by variable-length relationships
+var hopResourceCache = make(map[string][]map[string]interface{})
+
+// pathHop is a single step of a path between two resource kinds
+type pathHop struct {
+	rule     RelationshipRule
+	fromKind string
+	toKind   string
+	// fromKindA is set when the hop relates the rule's KindA to its KindB
+	fromKindA bool
+}
+
+// findRelationshipPaths walks the relationship rules breadth-first from one resource kind
+// to another, and returns all the kind paths whose length is between minHops and maxHops, shortest first.
+// A kind is never visited twice on the same path, and every hop follows the given direction and is
+// of one of the given types if any.
+func findRelationshipPaths(fromKind, toKind string, minHops, maxHops int, direction Direction, types []RelationshipType) [][]pathHop {
+	type partialPath struct {
+		kind string
+		hops []pathHop
+	}
+
+	var found [][]pathHop
+	frontier := []partialPath{{kind: fromKind}}
+	for depth := 1; depth <= maxHops && len(frontier) > 0; depth++ {
+		var next []partialPath
+
+		for _, path := range frontier {
+			for _, rule := range relationshipRules {
+				// The namespace rule relates every kind, it's not a meaningful hop
+				if rule.KindA == "*" || rule.KindB == "*" || !ruleHasType(rule, types) {
+					continue
+				}
+
+				for _, fromKindA := range []bool{true, false} {
+					kind, neighbour := rule.KindA, rule.KindB
+					if !fromKindA {
+						kind, neighbour = rule.KindB, rule.KindA
+					}
+					if !strings.EqualFold(kind, path.kind) || !ruleFollows(rule, fromKindA, direction) {
+						continue
+					}
+
+					hops := append(append([]pathHop{}, path.hops...), pathHop{rule: rule, fromKind: path.kind, toKind: neighbour, fromKindA: fromKindA})
+					if strings.EqualFold(neighbour, toKind) {
+						if depth >= minHops {
+							found = append(found, hops)
+						}
+						continue
+					}
+					if pathVisits(fromKind, path.hops, neighbour) {
+						continue
+					}
+					next = append(next, partialPath{kind: neighbour, hops: hops})
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return found
+}
+
+func pathVisits(fromKind string, hops []pathHop, kind string) bool {
+	if strings.EqualFold(fromKind, kind) {
+		return true
+	}
+	for _, hop := range hops {
+		if strings.EqualFold(hop.toKind, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkRelationshipPath follows a kind path from the left resources to the right resources.
+// It returns one layer of resources per kind on the path, holding only the resources that
+// are part of at least one complete chain from a left resource to a right resource.
+func walkRelationshipPath(left, right []map[string]interface{}, path []pathHop, fetch func(kind string) ([]map[string]interface{}, error)) ([][]map[string]interface{}, error) {
+	layers := [][]map[string]interface{}{left}
+
+	// Walk forward, keeping the resources reachable from the left resources
+	for i, hop := range path {
+		candidates := right
+		if i < len(path)-1 {
+			var err error
+			candidates, err = fetch(hop.toKind)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var reached []map[string]interface{}
+		for _, candidate := range candidates {
+			for _, resource := range layers[i] {
+				if hopMatches(hop, resource, candidate) {
+					reached = append(reached, candidate)
+					break
+				}
+			}
+		}
+		layers = append(layers, reached)
+	}
+
+	// Walk backward, keeping the resources that lead to the right resources
+	for i := len(path) - 1; i >= 0; i-- {
+		var leading []map[string]interface{}
+		for _, resource := range layers[i] {
+			for _, next := range layers[i+1] {
+				if hopMatches(path[i], resource, next) {
+					leading = append(leading, resource)
+					break
+				}
+			}
+		}
+		layers[i] = leading
+	}
+
+	return layers, nil
+}
+
+// hopMatches reports whether a resource of the hop's source kind is related to a resource of its target kind
+func hopMatches(hop pathHop, from, to map[string]interface{}) bool {
+	resourceA, resourceB := from, to
+	if !hop.fromKindA {
+		resourceA, resourceB = to, from
+	}
+
+	for _, criterion := range hop.rule.MatchCriteria {
+		if matchByCriterion(resourceA, resourceB, criterion) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *QueryExecutor) getHopResources(kind string) ([]map[string]interface{}, error) {
+	cacheKey := fmt.Sprintf("%s_%s", Namespace, kind)
+	if resources, ok := hopResourceCache[cacheKey]; ok {
+		return resources, nil
+	}
+
+	resources, err := q.provider.GetK8sResources(kind, "", "", Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error getting resources: %v", err)
+	}
+	resourceList, _ := resources.([]map[string]interface{})
+	hopResourceCache[cacheKey] = resourceList
+	return resourceList, nil
+}
+
+func (q *QueryExecutor) processVariableLengthRelationship(rel *Relationship, c *MatchClause, results *QueryResult, filteredResults map[string][]map[string]interface{}, leftKind, rightKind string) (bool, error) {
+	paths := findRelationshipPaths(leftKind, rightKind, rel.MinHops, rel.MaxHops, rel.Direction, relationshipTypes(rel))
+	if len(paths) == 0 {
+		return false, fmt.Errorf("no relationship path of %d to %d hops found between %s and %s", rel.MinHops, rel.MaxHops, leftKind, rightKind)
+	}
+
+	if err := q.fetchRelationshipNodes(rel, c, results); err != nil {
+		return false, err
+	}
+
+	leftName := rel.LeftNode.ResourceProperties.Name
+	rightName := rel.RightNode.ResourceProperties.Name
+	resourcesLeft := getResourcesFromMap(filteredResults, leftName)
+	resourcesRight := getResourcesFromMap(filteredResults, rightName)
+
+	matchedLeft := []map[string]interface{}{}
+	matchedRight := []map[string]interface{}{}
+
+	for _, path := range paths {
+		layers, err := walkRelationshipPath(resourcesLeft, resourcesRight, path, q.getHopResources)
+		if err != nil {
+			return false, err
+		}
+
+		for _, resource := range layers[0] {
+			if !containsResource(matchedLeft, resource) {
+				matchedLeft = append(matchedLeft, resource)
+			}
+		}
+		for _, resource := range layers[len(layers)-1] {
+			if !containsResource(matchedRight, resource) {
+				matchedRight = append(matchedRight, resource)
+			}
+		}
+
+		// Add the intermediate resources and an edge for every hop to the graph
+		for i, hop := range path {
+			if i > 0 {
+				addGraphNodes(results, "", layers[i])
+			}
+			for _, from := range layers[i] {
+				for _, to := range layers[i+1] {
+					if hopMatches(hop, from, to) {
+						results.Graph.Edges = append(results.Graph.Edges, Edge{
+							From: fmt.Sprintf("%s/%s", from["kind"], from["metadata"].(map[string]interface{})["name"]),
+							To:   fmt.Sprintf("%s/%s", to["kind"], to["metadata"].(map[string]interface{})["name"]),
+							Type: string(hop.rule.Relationship),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	// Resources on the required side of an optional relationship are kept even when nothing is related to them
+	if rel.LeftNode.Optional != rel.RightNode.Optional {
+		if rel.RightNode.Optional {
+			matchedLeft = resourcesLeft
+		} else {
+			matchedRight = resourcesRight
+		}
+	}
+
+	filteredResults[leftName] = matchedLeft
+	filteredResults[rightName] = matchedRight
+
+	resultMapMutex.Lock()
+	for name, matched := range map[string][]map[string]interface{}{leftName: matchedLeft, rightName: matchedRight} {
+		if existing, ok := resultMap[name].([]map[string]interface{}); !ok || len(existing) > len(matched) {
+			resultMap[name] = matched
+		}
+	}
+	resultMapMutex.Unlock()
+
+	addGraphNodes(results, leftName, matchedLeft)
+	addGraphNodes(results, rightName, matchedRight)
+
+	return len(matchedLeft) < len(resourcesLeft) || len(matchedRight) < len(resourcesRight), nil
+}
+
+// addGraphNodes adds a graph node for each of the resources matched by the given node id
+func addGraphNodes(results *QueryResult, nodeId string, resources []map[string]interface{}) {
+	for _, resource := range resources {
+		metadata, ok := resource["metadata"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := metadata["name"].(string)
+		if !ok {
+			continue
+		}
+		kind, _ := resource["kind"].(string)
+
+		node := Node{
+			Id:   nodeId,
+			Kind: kind,
+			Name: name,
+		}
+		if node.Kind != "Namespace" {
+			node.Namespace = getNamespaceName(metadata)
+		}
+		results.Graph.Nodes = append(results.Graph.Nodes, node)
+	}
+}
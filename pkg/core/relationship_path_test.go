@@ -0,0 +1,217 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindRelationshipPaths(t *testing.T) {
+	tests := []struct {
+		name      string
+		fromKind  string
+		toKind    string
+		minHops   int
+		maxHops   int
+		direction Direction
+		types     []RelationshipType
+		expected  [][]string
+	}{
+		{
+			name:     "direct relationship",
+			fromKind: "ingresses",
+			toKind:   "services",
+			minHops:  1,
+			maxHops:  4,
+			expected: [][]string{{"ingresses", "services"}},
+		},
+		{
+			name:     "paths of every length up to the maximum",
+			fromKind: "ingresses",
+			toKind:   "pods",
+			minHops:  1,
+			maxHops:  4,
+			expected: [][]string{
+				{"ingresses", "services", "pods"},
+				{"ingresses", "services", "replicasets", "pods"},
+				{"ingresses", "services", "statefulsets", "pods"},
+				{"ingresses", "services", "daemonsets", "pods"},
+				{"ingresses", "services", "deployments", "replicasets", "pods"},
+			},
+		},
+		{
+			name:     "minimum hops skips shorter paths",
+			fromKind: "ingresses",
+			toKind:   "pods",
+			minHops:  3,
+			maxHops:  4,
+			expected: [][]string{
+				{"ingresses", "services", "replicasets", "pods"},
+				{"ingresses", "services", "statefulsets", "pods"},
+				{"ingresses", "services", "daemonsets", "pods"},
+				{"ingresses", "services", "deployments", "replicasets", "pods"},
+			},
+		},
+		{
+			name:     "multiple paths of the same length",
+			fromKind: "deployments",
+			toKind:   "pods",
+			minHops:  1,
+			maxHops:  2,
+			expected: [][]string{
+				{"deployments", "replicasets", "pods"},
+				{"deployments", "services", "pods"},
+			},
+		},
+		{
+			name:     "hops restricted to relationship types",
+			fromKind: "deployments",
+			toKind:   "pods",
+			minHops:  1,
+			maxHops:  2,
+			types:    []RelationshipType{DeploymentOwnReplicaset, ReplicasetOwnPod},
+			expected: [][]string{{"deployments", "replicasets", "pods"}},
+		},
+		{
+			name:     "path longer than maximum",
+			fromKind: "ingresses",
+			toKind:   "pods",
+			minHops:  1,
+			maxHops:  1,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, path := range findRelationshipPaths(tt.fromKind, tt.toKind, tt.minHops, tt.maxHops, tt.direction, tt.types) {
+				kinds := []string{path[0].fromKind}
+				for _, hop := range path {
+					kinds = append(kinds, hop.toKind)
+				}
+				got = append(got, kinds)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("findRelationshipPaths() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWalkRelationshipPath(t *testing.T) {
+	resource := func(kind, name, parent string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind":     kind,
+			"metadata": map[string]interface{}{"name": name},
+			"parent":   parent,
+		}
+	}
+	ownerRule := func(kindA, kindB string) RelationshipRule {
+		return RelationshipRule{
+			KindA: kindA,
+			KindB: kindB,
+			MatchCriteria: []MatchCriterion{
+				{FieldA: "$.parent", FieldB: "$.metadata.name", ComparisonType: ExactMatch},
+			},
+		}
+	}
+
+	// deployments <- replicasets <- pods, walked from the deployment side
+	path := []pathHop{
+		{rule: ownerRule("replicasets", "deployments"), fromKind: "deployments", toKind: "replicasets"},
+		{rule: ownerRule("pods", "replicasets"), fromKind: "replicasets", toKind: "pods"},
+	}
+	deployments := []map[string]interface{}{resource("Deployment", "api", ""), resource("Deployment", "web", "")}
+	replicaSets := []map[string]interface{}{resource("ReplicaSet", "api-1", "api"), resource("ReplicaSet", "web-1", "web"), resource("ReplicaSet", "web-2", "web")}
+	pods := []map[string]interface{}{resource("Pod", "api-1-a", "api-1"), resource("Pod", "web-2-a", "web-2"), resource("Pod", "orphan", "")}
+
+	fetch := func(kind string) ([]map[string]interface{}, error) {
+		if kind != "replicasets" {
+			t.Fatalf("unexpected fetch of %s", kind)
+		}
+		return replicaSets, nil
+	}
+
+	// Only pods of the "web" deployment are on the right side of the relationship
+	layers, err := walkRelationshipPath(deployments, pods[1:], path, fetch)
+	if err != nil {
+		t.Fatalf("walkRelationshipPath() error = %v", err)
+	}
+
+	names := func(resources []map[string]interface{}) []string {
+		var result []string
+		for _, r := range resources {
+			result = append(result, r["metadata"].(map[string]interface{})["name"].(string))
+		}
+		return result
+	}
+
+	expected := [][]string{{"web"}, {"web-2"}, {"web-2-a"}}
+	for i, layer := range layers {
+		if !reflect.DeepEqual(names(layer), expected[i]) {
+			t.Errorf("layer %d = %v, want %v", i, names(layer), expected[i])
+		}
+	}
+}
+
+func TestExecuteVariableLengthRelationship(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"ingresses": {
+			mockResource("Ingress", "web", routingTo("web")),
+		},
+		"services": {
+			mockResource("Service", "web", selecting(map[string]interface{}{"app": "web"})),
+		},
+		"replicasets": {
+			mockResource("ReplicaSet", "web", map[string]interface{}{
+				"spec": map[string]interface{}{"template": map[string]interface{}{
+					"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+				}},
+			}),
+		},
+		"pods": {
+			mockResource("Pod", "web-1", labeled(map[string]interface{}{"app": "web"}), ownedBy("web")),
+			// Relabelled out of the Service, still owned by the ReplicaSet it exposes
+			mockResource("Pod", "web-debug", labeled(map[string]interface{}{"app": "debug"}), ownedBy("web")),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		node     string
+		key      string
+		expected []interface{}
+	}{
+		{
+			name:     "resources reached through longer paths",
+			query:    `MATCH (i:Ingress)-[*1..4]->(p:Pod) RETURN p.metadata.name AS name`,
+			node:     "p",
+			key:      "name",
+			expected: []interface{}{"web-1", "web-debug"},
+		},
+		{
+			name:     "path of every length",
+			query:    `MATCH p = (i:Ingress)-[*1..4]->(pod:Pod {name: "web-1"}) RETURN length(p) AS hops`,
+			node:     "p",
+			key:      "hops",
+			expected: []interface{}{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []interface{}
+			for _, item := range result.Data[tt.node].([]interface{}) {
+				got = append(got, item.(map[string]interface{})[tt.key])
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package core
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -504,3 +506,206 @@ func TestStringContainsComparison(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteRelationshipDirection(t *testing.T) {
+	originalRules := relationshipRules
+	defer func() { relationshipRules = originalRules }()
+	AddRelationshipRule(RelationshipRule{
+		KindA:        "pods",
+		KindB:        "pods",
+		Relationship: "POD_SPAWN_POD",
+		MatchCriteria: []MatchCriterion{
+			{FieldA: "$.metadata.annotations.parent", FieldB: "$.metadata.name", ComparisonType: ExactMatch},
+		},
+	})
+
+	worker := func(name string) map[string]interface{} {
+		return mockResource("Pod", name, map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{"parent": "driver"}},
+		})
+	}
+	resources := map[string][]map[string]interface{}{
+		"pods":       {mockResource("Pod", "driver", nil), worker("worker-1"), worker("worker-2")},
+		"namespaces": {mockResource("Namespace", "default", nil)},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		node     string
+		expected []string
+		warning  string
+		wantErr  string
+	}{
+		{
+			name:     "relationship from KindB to KindA",
+			query:    `MATCH (p:Pod)->(w:Pod) RETURN w`,
+			node:     "w",
+			expected: []string{"worker-1", "worker-2"},
+		},
+		{
+			name:     "relationship drawn the other way",
+			query:    `MATCH (w:Pod)<-(p:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"driver"},
+		},
+		{
+			name:     "relationship without direction",
+			query:    `MATCH (w:Pod {name: "worker-1"})--(p:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"driver"},
+		},
+		{
+			name:     "typed relationship without direction",
+			query:    `MATCH (w:Pod {name: "worker-1"})-[:POD_SPAWN_POD]-(p:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"driver"},
+		},
+		{
+			name:     "relationship direction in a pattern predicate",
+			query:    `MATCH (p:Pod) WHERE NOT (p)<-(:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"driver"},
+		},
+		{
+			name:     "relationship from KindA to KindB",
+			query:    `MATCH (n:Namespace)->(p:Pod {name: "driver"}) RETURN n`,
+			node:     "n",
+			expected: []string{"default"},
+		},
+		{
+			name:    "relationship against the direction of its rules",
+			query:   `MATCH (p:Pod)->(n:Namespace) RETURN n`,
+			node:    "n",
+			warning: "(p:Pod)->(n:Namespace) points against the relationships between pods and namespaces and matches nothing, did you mean (p:Pod)<-(n:Namespace)?",
+		},
+		{
+			name:     "optional relationship against the direction of its rules",
+			query:    `MATCH (w:Pod {name: "worker-1"}) OPTIONAL MATCH (w)->(n:Namespace) RETURN w, n`,
+			node:     "w",
+			expected: []string{"worker-1"},
+			warning:  "(w:Pod)->(n:Namespace) points against the relationships between pods and namespaces and matches nothing",
+		},
+		{
+			name:    "pattern predicate against the direction of its rules",
+			query:   `MATCH (n:Namespace) WHERE (n)<-(:Pod) RETURN n`,
+			wantErr: "no relationship points from pods to namespaces, relationships between them point the other way, did you mean -> instead of <-?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []string
+			for _, item := range result.Data[tt.node].([]interface{}) {
+				got = append(got, fmt.Sprintf("%v", item.(map[string]interface{})["name"]))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+			if tt.warning == "" && len(result.Warnings) > 0 {
+				t.Errorf("unexpected warnings %v", result.Warnings)
+			}
+			if tt.warning != "" && (len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], tt.warning)) {
+				t.Errorf("got warnings %v, want a warning containing %q", result.Warnings, tt.warning)
+			}
+		})
+	}
+
+	// Executing a query doesn't change it, running it again gives the same results and warnings
+	ast, err := ParseQuery(`MATCH (p:Pod)->(n:Namespace) RETURN n`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	executor, err := NewQueryExecutor(&mockProvider{resources: resources})
+	if err != nil {
+		t.Fatalf("NewQueryExecutor() error = %v", err)
+	}
+	for run := 1; run <= 2; run++ {
+		result, err := executor.Execute(ast, "default")
+		if err != nil {
+			t.Fatalf("run %d: Execute() error = %v", run, err)
+		}
+		if len(result.Data["n"].([]interface{})) != 0 || len(result.Warnings) != 1 {
+			t.Errorf("run %d: got %v with warnings %v, want no namespaces and a warning", run, result.Data["n"], result.Warnings)
+		}
+	}
+}
+
+func TestExecuteSelectorRelationships(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"pods": {
+			mockResource("Pod", "web-1", labeled(map[string]interface{}{"app": "web", "pod-template-hash": "5d4f8"})),
+			mockResource("Pod", "api-1", labeled(map[string]interface{}{"app": "api"})),
+		},
+		"poddisruptionbudgets": {
+			mockResource("PodDisruptionBudget", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+		},
+		"networkpolicies": {
+			mockResource("NetworkPolicy", "web", map[string]interface{}{
+				"spec": map[string]interface{}{"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}}},
+			}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		node     string
+		expected []string
+	}{
+		{
+			name:     "pod disruption budget protects the pods it selects",
+			query:    `MATCH (pdb:PodDisruptionBudget)->(p:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"web-1"},
+		},
+		{
+			name:     "pods protected by a pod disruption budget",
+			query:    `MATCH (p:Pod)<-(pdb:PodDisruptionBudget) RETURN pdb`,
+			node:     "pdb",
+			expected: []string{"web"},
+		},
+		{
+			name:     "network policy applies to the pods it selects",
+			query:    `MATCH (np:NetworkPolicy)->(p:Pod) RETURN p`,
+			node:     "p",
+			expected: []string{"web-1"},
+		},
+		{
+			name:     "pods a network policy applies to",
+			query:    `MATCH (p:Pod)<-(np:NetworkPolicy) RETURN np`,
+			node:     "np",
+			expected: []string{"web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if len(result.Warnings) > 0 {
+				t.Errorf("unexpected warnings %v", result.Warnings)
+			}
+			var got []string
+			for _, item := range result.Data[tt.node].([]interface{}) {
+				got = append(got, fmt.Sprintf("%v", item.(map[string]interface{})["name"]))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
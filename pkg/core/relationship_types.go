@@ -61,6 +61,10 @@ type RelationshipRule struct {
 	KindB         string           `yaml:"kindB"`
 	Relationship  RelationshipType `yaml:"relationship"`
 	MatchCriteria []MatchCriterion `yaml:"matchCriteria"`
+	// KindAToKindB is set for rules whose relationships point from KindA to KindB, e.g. an Ingress
+	// routes to a Service. The relationships of other rules point from KindB to KindA, e.g. a
+	// ReplicaSet owns a Pod.
+	KindAToKindB bool `yaml:"kindAToKindB,omitempty"`
 }
 
 var relationshipRules = []RelationshipRule{
@@ -125,13 +129,13 @@ var relationshipRules = []RelationshipRule{
 		},
 	},
 	{
-		KindA:        "networkpolicies",
-		KindB:        "pods",
+		KindA:        "pods",
+		KindB:        "networkpolicies",
 		Relationship: NetworkPolicyApplyPod,
 		MatchCriteria: []MatchCriterion{
 			{
-				FieldA:         "$.spec.podSelector.matchLabels",
-				FieldB:         "$.metadata.labels",
+				FieldA:         "$.metadata.labels",
+				FieldB:         "$.spec.podSelector.matchLabels",
 				ComparisonType: ContainsAll,
 			},
 		},
@@ -140,6 +144,7 @@ var relationshipRules = []RelationshipRule{
 		KindA:        "horizontalpodautoscalers",
 		KindB:        "deployments",
 		Relationship: HPAScaleDeployment,
+		KindAToKindB: true,
 		MatchCriteria: []MatchCriterion{
 			{
 				FieldA:         "$.spec.scaleTargetRef.name",
@@ -207,6 +212,7 @@ var relationshipRules = []RelationshipRule{
 		KindA:        "ingresses",
 		KindB:        "services",
 		Relationship: Route,
+		KindAToKindB: true,
 		MatchCriteria: []MatchCriterion{
 			{
 				FieldA:         "$.spec.rules[].http.paths[].backend.service.name",
@@ -312,6 +318,7 @@ var relationshipRules = []RelationshipRule{
 		KindA:        "mutatingwebhookconfigurations",
 		KindB:        "services",
 		Relationship: MutatingWebhookTargetService,
+		KindAToKindB: true,
 		MatchCriteria: []MatchCriterion{
 			{
 				FieldA:         "$.webhooks[].clientConfig.service.name",
@@ -324,6 +331,7 @@ var relationshipRules = []RelationshipRule{
 		KindA:        "validatingwebhookconfigurations",
 		KindB:        "services",
 		Relationship: ValidatingWebhookTargetService,
+		KindAToKindB: true,
 		MatchCriteria: []MatchCriterion{
 			{
 				FieldA:         "$.webhooks[].clientConfig.service.name",
@@ -333,13 +341,13 @@ var relationshipRules = []RelationshipRule{
 		},
 	},
 	{
-		KindA:        "poddisruptionbudgets",
-		KindB:        "pods",
+		KindA:        "pods",
+		KindB:        "poddisruptionbudgets",
 		Relationship: PDBProtectPod,
 		MatchCriteria: []MatchCriterion{
 			{
-				FieldA:         "$.spec.selector.matchLabels",
-				FieldB:         "$.metadata.labels",
+				FieldA:         "$.metadata.labels",
+				FieldB:         "$.spec.selector.matchLabels",
 				ComparisonType: ContainsAll,
 			},
 		},
@@ -349,6 +357,7 @@ var relationshipRules = []RelationshipRule{
 		KindA:        "namespaces",
 		KindB:        "*",
 		Relationship: NamespaceHasResource,
+		KindAToKindB: true,
 		MatchCriteria: []MatchCriterion{
 			{
 				FieldA:         "$.metadata.name",
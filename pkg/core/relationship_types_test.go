@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExecuteRelationshipTypes(t *testing.T) {
+	originalRules := relationshipRules
+	defer func() { relationshipRules = originalRules }()
+	AddRelationshipRule(RelationshipRule{
+		KindA:        "pods",
+		KindB:        "services",
+		Relationship: "SERVICE_INSPEC_POD",
+		MatchCriteria: []MatchCriterion{
+			{FieldA: "$.metadata.annotations.owner", FieldB: "$.metadata.name", ComparisonType: ExactMatch},
+		},
+	})
+
+	resources := map[string][]map[string]interface{}{
+		"pods": {
+			mockResource("Pod", "web-1", labeled(map[string]interface{}{"app": "web"})),
+			mockResource("Pod", "batch-1", map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels":      map[string]interface{}{"app": "batch"},
+					"annotations": map[string]interface{}{"owner": "web"},
+				},
+			}),
+		},
+		"services": {
+			mockResource("Service", "web", selecting(map[string]interface{}{"app": "web"})),
+		},
+		"deployments": {
+			mockResource("Deployment", "web", matchingLabels(map[string]interface{}{"app": "web"})),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		node     string
+		key      string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "relationship without type",
+			query:    `MATCH (s:Service)->(p:Pod) RETURN p`,
+			node:     "p",
+			key:      "name",
+			expected: []string{"batch-1", "web-1"},
+		},
+		{
+			name:     "relationship type",
+			query:    `MATCH (s:Service)-[:SERVICE_EXPOSE_POD]->(p:Pod) RETURN p`,
+			node:     "p",
+			key:      "name",
+			expected: []string{"web-1"},
+		},
+		{
+			name:     "alternative relationship types",
+			query:    `MATCH (s:Service)-[r:service_expose_pod|SERVICE_INSPEC_POD]->(p:Pod) RETURN type(r)`,
+			node:     "r",
+			key:      "type(r)",
+			expected: []string{"SERVICE_EXPOSE_POD", "SERVICE_INSPEC_POD"},
+		},
+		{
+			name:     "relationship variable follows the arrow",
+			query:    `MATCH (p:Pod)<-[r:SERVICE_INSPEC_POD]-(s:Service) RETURN r.start, r.end`,
+			node:     "r",
+			key:      "start",
+			expected: []string{"Service/web"},
+		},
+		{
+			name:     "relationship type of a kind-less node",
+			query:    `MATCH (s:Service)-[:SERVICE_EXPOSE_DEPLOYMENT]->(x) RETURN x.kind`,
+			node:     "x",
+			key:      "kind",
+			expected: []string{"Deployment"},
+		},
+		{
+			name:     "relationship type in a pattern predicate",
+			query:    `MATCH (p:Pod) WHERE (p)<-[:SERVICE_INSPEC_POD]-(:Service) RETURN p`,
+			node:     "p",
+			key:      "name",
+			expected: []string{"batch-1"},
+		},
+		{
+			name:    "relationship type not relating the kinds",
+			query:   `MATCH (s:Service)-[:ROUTE]->(p:Pod) RETURN p`,
+			wantErr: "no relationship of type ROUTE between services and pods",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []string
+			for _, item := range result.Data[tt.node].([]interface{}) {
+				got = append(got, fmt.Sprintf("%v", item.(map[string]interface{})[tt.key]))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
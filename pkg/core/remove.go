@@ -0,0 +1,80 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// removeFields removes the fields of a REMOVE clause from the resources of the nodes they
+// refer to. Resources that don't have a field are left alone, as removing it would fail.
+func (q *QueryExecutor) removeFields(jsonPaths []string) error {
+	for _, jsonPath := range jsonPaths {
+		resultMapKey, path := splitKeyPath(jsonPath)
+		resources, _ := resultMap[resultMapKey].([]map[string]interface{})
+		for _, resource := range resources {
+			if !removeField(resource, path) {
+				continue
+			}
+
+			patches := []interface{}{
+				map[string]interface{}{
+					"op":   "remove",
+					"path": jsonPointer(path),
+				},
+			}
+			patchJSON, err := json.Marshal(patches)
+			if err != nil {
+				return fmt.Errorf("error marshalling patches: %s", err)
+			}
+
+			err = q.PatchK8sResource(resource, patchJSON)
+			if err != nil {
+				return fmt.Errorf("error patching resource: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeField removes the field at the given path from a resource, and reports whether it existed
+func removeField(resource map[string]interface{}, path []string) bool {
+	var segments []string
+	for _, part := range path {
+		part, indices := splitIndices(part)
+		segments = append(append(segments, part), indices...)
+	}
+	_, removed := removeSegments(resource, segments)
+	return removed
+}
+
+// removeSegments removes the field at the given path segments from a value, and returns the
+// updated value since removing an array element reslices the array
+func removeSegments(value interface{}, segments []string) (interface{}, bool) {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		child, ok := node[segments[0]]
+		if !ok {
+			return node, false
+		}
+		if len(segments) == 1 {
+			delete(node, segments[0])
+			return node, true
+		}
+		updated, removed := removeSegments(child, segments[1:])
+		node[segments[0]] = updated
+		return node, removed
+	case []interface{}:
+		index, err := strconv.Atoi(segments[0])
+		if err != nil || index < 0 || index >= len(node) {
+			return node, false
+		}
+		if len(segments) == 1 {
+			return append(node[:index:index], node[index+1:]...), true
+		}
+		updated, removed := removeSegments(node[index], segments[1:])
+		node[index] = updated
+		return node, removed
+	}
+	return value, false
+}
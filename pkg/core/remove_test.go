@@ -0,0 +1,125 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteRemove(t *testing.T) {
+	provider := &mockProvider{resources: map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app": "web", "app.kubernetes.io/name": "web"},
+				},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"nodeSelector": map[string]interface{}{"disk": "ssd"},
+						},
+					},
+				},
+			}),
+			mockResource("Deployment", "api", nil),
+		},
+	}}
+
+	ast, err := ParseQuery(`MATCH (d:Deployment) REMOVE d.metadata.labels.app\.kubernetes\.io/name, d.spec.template.spec.nodeSelector RETURN d.metadata.labels`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	executor, err := NewQueryExecutor(provider)
+	if err != nil {
+		t.Fatalf("NewQueryExecutor() error = %v", err)
+	}
+	result, err := executor.Execute(ast, "default")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	// Resources without the fields aren't patched
+	wantPatched := []string{"Deployment/web", "Deployment/web"}
+	if !reflect.DeepEqual(provider.patched, wantPatched) {
+		t.Errorf("patched %v, want %v", provider.patched, wantPatched)
+	}
+
+	for _, resource := range result.Data["d"].([]interface{}) {
+		resource := resource.(map[string]interface{})
+		if resource["name"] != "web" {
+			continue
+		}
+		labels := resource["metadata"].(map[string]interface{})["labels"]
+		if want := map[string]interface{}{"app": "web"}; !reflect.DeepEqual(labels, want) {
+			t.Errorf("labels = %v, want %v", labels, want)
+		}
+	}
+}
+
+func TestRemoveField(t *testing.T) {
+	newResource := func() map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{"app": "web"},
+			},
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "web"},
+					map[string]interface{}{"name": "sidecar"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        []string
+		wantRemoved bool
+		check       func(resource map[string]interface{}) bool
+	}{
+		{
+			name:        "map key",
+			path:        []string{"metadata", "labels", "app"},
+			wantRemoved: true,
+			check: func(resource map[string]interface{}) bool {
+				return len(resource["metadata"].(map[string]interface{})["labels"].(map[string]interface{})) == 0
+			},
+		},
+		{
+			name:        "array element",
+			path:        []string{"spec", "containers[0]"},
+			wantRemoved: true,
+			check: func(resource map[string]interface{}) bool {
+				containers := resource["spec"].(map[string]interface{})["containers"].([]interface{})
+				return len(containers) == 1 && containers[0].(map[string]interface{})["name"] == "sidecar"
+			},
+		},
+		{
+			name:        "missing field",
+			path:        []string{"spec", "nodeSelector"},
+			wantRemoved: false,
+			check: func(resource map[string]interface{}) bool {
+				return reflect.DeepEqual(resource, newResource())
+			},
+		},
+		{
+			name:        "index out of range",
+			path:        []string{"spec", "containers[2]", "name"},
+			wantRemoved: false,
+			check: func(resource map[string]interface{}) bool {
+				return reflect.DeepEqual(resource, newResource())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := newResource()
+			if removed := removeField(resource, tt.path); removed != tt.wantRemoved {
+				t.Errorf("removeField() = %v, want %v", removed, tt.wantRemoved)
+			}
+			if !tt.check(resource) {
+				t.Errorf("unexpected resource after removeField(): %v", resource)
+			}
+		})
+	}
+}
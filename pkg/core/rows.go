@@ -0,0 +1,299 @@
+package core
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// rowBinding binds the variables of a row to a resource, a relationship or a path.
+// Nodes of an OPTIONAL MATCH that nothing is related to are bound to nil.
+type rowBinding map[string]map[string]interface{}
+
+// buildRows computes the rows of a return clause: one row per combination of resources, related
+// as the query's patterns require, bound to the returned variables and the variables that relate
+// them, mapping each of the clause's items to its value. Nodes that are neither returned nor
+// related to a returned node don't affect the rows. The rows of aggregations are their groups,
+// or a single row when they aren't grouped.
+// Rows are ordered, made distinct and paged as the clause requires, and so are the returned items
+// of each variable, which only keep the resources bound in the remaining rows. Rows are only
+// computed to do so, or when returnRows is set, and only reported in the latter case.
+func (q *QueryExecutor) buildRows(c *ReturnClause, items []*ReturnItem, nodes []*NodePattern, relationships []*Relationship, paths []*PathPattern, unwinds []*UnwindClause, results *QueryResult, returnRows bool) error {
+	for _, item := range items {
+		if item.Aggregate == "" {
+			continue
+		}
+		if !returnRows {
+			return nil
+		}
+		// Grouped rows are already ordered and paged
+		switch aggregate := results.Data["aggregate"].(type) {
+		case []interface{}:
+			for _, row := range aggregate {
+				results.Rows = append(results.Rows, row.(map[string]interface{}))
+			}
+		case map[string]interface{}:
+			results.Rows = pageItems([]map[string]interface{}{aggregate}, c.Skip, c.Limit)
+		}
+		return nil
+	}
+	if !returnRows && !isOrdered(c) {
+		return nil
+	}
+
+	var returned []string
+	for _, item := range items {
+		name := strings.Split(item.JsonPath, ".")[0]
+		if !slices.Contains(returned, name) {
+			returned = append(returned, name)
+		}
+	}
+
+	bindings, err := q.rowBindings(returned, nodes, relationships, paths, unwinds)
+	if err != nil {
+		return err
+	}
+
+	rows := make([]map[string]interface{}, len(bindings))
+	for i, binding := range bindings {
+		row := make(map[string]interface{})
+		for _, item := range items {
+			value, err := rowValue(binding, item.JsonPath, item.Function)
+			if err != nil {
+				return err
+			}
+			row[returnItemKey(item)] = value
+		}
+		rows[i] = row
+	}
+
+	if isOrdered(c) {
+		bindings, rows, err = orderRows(c, bindings, rows)
+		if err != nil {
+			return err
+		}
+		if err := applyOrdering(c, bindings, results); err != nil {
+			return err
+		}
+	}
+	if returnRows {
+		results.Rows = rows
+	}
+	return nil
+}
+
+// rowValue returns the value of a return path or, for computed items, of its function call, in
+// the resource bound to the variable it refers to. Paths of variables bound to nil yield nil.
+func rowValue(binding rowBinding, path string, function *FunctionCall) (interface{}, error) {
+	resource := binding[strings.Split(path, ".")[0]]
+	if resource == nil {
+		return nil, nil
+	}
+	return returnItemValue(resource, path, function)
+}
+
+// rowBindings returns the bindings of the returned variables, along with the node variables
+// related to them directly or through other nodes, to every combination of related resources.
+// Each binding of a node is then bound to every element the node's resource unwinds to.
+func (q *QueryExecutor) rowBindings(returned []string, nodes []*NodePattern, relationships []*Relationship, paths []*PathPattern, unwinds []*UnwindClause) ([]rowBinding, error) {
+	optional := make(map[string]bool)
+	for _, node := range nodes {
+		optional[node.ResourceProperties.Name] = node.Optional
+	}
+	inScope := func(name string) bool {
+		_, isNode := optional[name]
+		return isNode && resultMap[name] != nil
+	}
+
+	// Relationships of nodes that went out of scope no longer relate anything
+	var scoped []*Relationship
+	for _, rel := range relationships {
+		if inScope(rel.LeftNode.ResourceProperties.Name) && inScope(rel.RightNode.ResourceProperties.Name) {
+			scoped = append(scoped, rel)
+		}
+	}
+
+	// Returned relationships and paths bind the nodes they relate
+	var relationshipVariables []*Relationship
+	var pathVariables []*PathPattern
+	var seeds []string
+	for _, name := range returned {
+		for _, rel := range scoped {
+			if rel.ResourceProperties != nil && rel.ResourceProperties.Name == name {
+				relationshipVariables = append(relationshipVariables, rel)
+				seeds = append(seeds, rel.LeftNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Name)
+			}
+		}
+		for _, path := range paths {
+			if path.Name == name {
+				pathVariables = append(pathVariables, path)
+				seeds = append(seeds, path.Nodes...)
+			}
+		}
+		if index := slices.IndexFunc(unwinds, func(u *UnwindClause) bool { return u.Alias == name }); index >= 0 {
+			seeds = append(seeds, strings.Split(unwindChain(unwinds[index], unwinds)[0].JsonPath, ".")[0])
+		}
+		if inScope(name) {
+			seeds = append(seeds, name)
+		}
+	}
+
+	order := connectedVariables(seeds, scoped)
+
+	bindings := []rowBinding{{}}
+	for _, name := range order {
+		resources, _ := resultMap[name].([]map[string]interface{})
+		var next []rowBinding
+		for _, binding := range bindings {
+			bound := false
+			for _, resource := range resources {
+				related, err := q.relatedToBinding(name, resource, binding, scoped)
+				if err != nil {
+					return nil, err
+				}
+				if related {
+					next = append(next, withBinding(binding, name, resource))
+					bound = true
+				}
+			}
+			if !bound && optional[name] {
+				next = append(next, withBinding(binding, name, nil))
+			}
+		}
+		bindings = next
+	}
+
+	for _, rel := range relationshipVariables {
+		var next []rowBinding
+		for _, binding := range bindings {
+			left, right := binding[rel.LeftNode.ResourceProperties.Name], binding[rel.RightNode.ResourceProperties.Name]
+			if left == nil || right == nil {
+				next = append(next, withBinding(binding, rel.ResourceProperties.Name, nil))
+				continue
+			}
+			rules, err := q.relatingRules(rel, left, right)
+			if err != nil {
+				return nil, err
+			}
+			for _, rule := range rules {
+				next = append(next, withBinding(binding, rel.ResourceProperties.Name, directedRelationshipEntry(rule, left, right, rel.Direction)))
+			}
+		}
+		bindings = next
+	}
+
+	for _, path := range pathVariables {
+		var next []rowBinding
+		for _, binding := range bindings {
+			if slices.ContainsFunc(path.Nodes, func(name string) bool { return binding[name] == nil }) {
+				next = append(next, withBinding(binding, path.Name, nil))
+				continue
+			}
+			entries, err := q.matchPaths(path, func(name string) []map[string]interface{} {
+				return []map[string]interface{}{binding[name]}
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				next = append(next, withBinding(binding, path.Name, entry))
+			}
+		}
+		bindings = next
+	}
+
+	// Elements are unwound from resources bound before them, a binding with nothing to unwind is dropped
+	for _, u := range unwinds {
+		variable := strings.Split(u.JsonPath, ".")[0]
+		var next []rowBinding
+		for _, binding := range bindings {
+			resource, bound := binding[variable]
+			if !bound {
+				next = append(next, binding)
+				continue
+			}
+			if resource == nil {
+				continue
+			}
+			elements, err := unwindElements(resource, u)
+			if err != nil {
+				return nil, err
+			}
+			for _, element := range elements {
+				next = append(next, withBinding(binding, u.Alias, element))
+			}
+		}
+		bindings = next
+	}
+
+	return bindings, nil
+}
+
+// relatedToBinding reports whether a resource of a node is related to the resources bound to the
+// nodes it's related to, as each of their relationships requires
+func (q *QueryExecutor) relatedToBinding(name string, resource map[string]interface{}, binding rowBinding, relationships []*Relationship) (bool, error) {
+	for _, rel := range relationships {
+		left, right := rel.LeftNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Name
+		var leftResource, rightResource map[string]interface{}
+		switch {
+		case left == name:
+			other, ok := binding[right]
+			if !ok || other == nil {
+				continue
+			}
+			leftResource, rightResource = resource, other
+		case right == name:
+			other, ok := binding[left]
+			if !ok || other == nil {
+				continue
+			}
+			leftResource, rightResource = other, resource
+		default:
+			continue
+		}
+
+		segments, err := q.pathSegments(rel, leftResource, []map[string]interface{}{rightResource})
+		if err != nil {
+			return false, err
+		}
+		if len(segments) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// connectedVariables returns the given node variables along with the node variables related to
+// them directly or through other nodes, breadth-first, so that every variable but the first of a
+// group of related variables is related to a variable before it
+func connectedVariables(seeds []string, relationships []*Relationship) []string {
+	var order []string
+	for _, seed := range seeds {
+		if slices.Contains(order, seed) {
+			continue
+		}
+		queue := []string{seed}
+		order = append(order, seed)
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			for _, rel := range relationships {
+				left, right := rel.LeftNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Name
+				for _, pair := range [][2]string{{left, right}, {right, left}} {
+					if pair[0] == name && !slices.Contains(order, pair[1]) {
+						order = append(order, pair[1])
+						queue = append(queue, pair[1])
+					}
+				}
+			}
+		}
+	}
+	return order
+}
+
+// withBinding returns a copy of a binding that also binds a variable
+func withBinding(binding rowBinding, name string, value map[string]interface{}) rowBinding {
+	copied := maps.Clone(binding)
+	copied[name] = value
+	return copied
+}
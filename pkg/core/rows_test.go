@@ -0,0 +1,177 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteRows(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", nil),
+			mockResource("Deployment", "api", nil),
+		},
+		"replicasets": {
+			mockResource("ReplicaSet", "web-1", ownedBy("web")),
+			mockResource("ReplicaSet", "web-2", ownedBy("web")),
+			mockResource("ReplicaSet", "api-1", ownedBy("api")),
+		},
+		"pods": {
+			mockResource("Pod", "web-2-a", ownedBy("web-2")),
+			mockResource("Pod", "api-1-a", ownedBy("api-1")),
+		},
+		"horizontalpodautoscalers": {
+			mockResource("HorizontalPodAutoscaler", "web", map[string]interface{}{
+				"spec": map[string]interface{}{"scaleTargetRef": map[string]interface{}{"name": "web"}, "maxReplicas": 5},
+			}),
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []map[string]interface{}
+	}{
+		{
+			name:  "one row per related pair",
+			query: `MATCH (d:Deployment)->(rs:ReplicaSet) RETURN d.metadata.name AS deployment, rs.metadata.name AS replicaset`,
+			want: []map[string]interface{}{
+				{"deployment": "web", "replicaset": "web-1"},
+				{"deployment": "web", "replicaset": "web-2"},
+				{"deployment": "api", "replicaset": "api-1"},
+			},
+		},
+		{
+			name:  "rows pair nodes related through a node that isn't returned",
+			query: `MATCH (d:Deployment)->(rs:ReplicaSet)->(p:Pod) RETURN d.metadata.name, p.metadata.name`,
+			want: []map[string]interface{}{
+				{"d.metadata.name": "web", "p.metadata.name": "web-2-a"},
+				{"d.metadata.name": "api", "p.metadata.name": "api-1-a"},
+			},
+		},
+		{
+			name:  "optional nodes that aren't matched are null",
+			query: `MATCH (d:Deployment) OPTIONAL MATCH (d)<-(h:HorizontalPodAutoscaler) RETURN d.metadata.name AS deployment, h.spec.maxReplicas AS maxReplicas`,
+			want: []map[string]interface{}{
+				{"deployment": "web", "maxReplicas": 5},
+				{"deployment": "api", "maxReplicas": nil},
+			},
+		},
+		{
+			name:  "rows are ordered and paged",
+			query: `MATCH (d:Deployment)->(rs:ReplicaSet) RETURN d.metadata.name AS deployment, rs.metadata.name AS replicaset ORDER BY replicaset DESC LIMIT 2`,
+			want: []map[string]interface{}{
+				{"deployment": "web", "replicaset": "web-2"},
+				{"deployment": "web", "replicaset": "web-1"},
+			},
+		},
+		{
+			name:  "relationship variables",
+			query: `MATCH (d:Deployment {name: "api"})-[r]->(rs:ReplicaSet) RETURN type(r) AS type, rs.metadata.name AS replicaset`,
+			want: []map[string]interface{}{
+				{"type": "DEPLOYMENT_OWN_REPLICASET", "replicaset": "api-1"},
+			},
+		},
+		{
+			name:  "aggregations",
+			query: `MATCH (rs:ReplicaSet) RETURN COUNT{rs.metadata.name} AS replicasets`,
+			want: []map[string]interface{}{
+				{"replicasets": 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Rows, tt.want) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRowsNotRequested(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {mockResource("Deployment", "web", nil)},
+	}
+
+	for _, query := range []string{
+		`MATCH (d:Deployment) RETURN d.metadata.name`,
+		`MATCH (d:Deployment) RETURN d.metadata.name ORDER BY d.metadata.name`,
+		`MATCH (d:Deployment) RETURN COUNT{d.metadata.name} AS deployments`,
+	} {
+		result, err := executeMockQuery(query, resources)
+		if err != nil {
+			t.Fatalf("executeMockQuery(%q) error = %v", query, err)
+		}
+		if result.Rows != nil {
+			t.Errorf("executeMockQuery(%q) returned rows %v without asking for them", query, result.Rows)
+		}
+	}
+}
+
+func TestExecuteOptionalMatch(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "api", nil),
+			mockResource("Deployment", "web", nil),
+		},
+		"horizontalpodautoscalers": {
+			mockResource("HorizontalPodAutoscaler", "api-hpa", map[string]interface{}{
+				"spec": map[string]interface{}{"scaleTargetRef": map[string]interface{}{"name": "api"}},
+			}),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedDeps  int
+		expectedHPAs  int
+		expectedEdges int
+	}{
+		{
+			name:          "required match drops unscaled deployments",
+			query:         "MATCH (d:Deployment)<-(h:HorizontalPodAutoscaler) RETURN d, h",
+			expectedDeps:  1,
+			expectedHPAs:  1,
+			expectedEdges: 1,
+		},
+		{
+			name:          "optional match keeps unscaled deployments",
+			query:         "MATCH (d:Deployment) OPTIONAL MATCH (d)<-(h:HorizontalPodAutoscaler) RETURN d, h",
+			expectedDeps:  2,
+			expectedHPAs:  1,
+			expectedEdges: 1,
+		},
+		{
+			name:          "optional match without related resources",
+			query:         `MATCH (d:Deployment) OPTIONAL MATCH (d)<-(h:HorizontalPodAutoscaler) WHERE h.metadata.name = "none" RETURN d, h`,
+			expectedDeps:  2,
+			expectedHPAs:  0,
+			expectedEdges: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := len(result.Data["d"].([]interface{})); got != tt.expectedDeps {
+				t.Errorf("got %d deployments, want %d", got, tt.expectedDeps)
+			}
+			if got := len(result.Data["h"].([]interface{})); got != tt.expectedHPAs {
+				t.Errorf("got %d HPAs, want %d", got, tt.expectedHPAs)
+			}
+			if got := len(result.Graph.Edges); got != tt.expectedEdges {
+				t.Errorf("got %d edges, want %d", got, tt.expectedEdges)
+			}
+		})
+	}
+}
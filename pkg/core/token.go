@@ -14,6 +14,30 @@ const (
 	RETURN
 	IN
 	AS
+	AND
+	OR
+	NOT
+	ORDER
+	BY
+	ASC
+	DESC
+	SKIP
+	LIMIT
+	OPTIONAL
+	WITH
+	MERGE
+	ON
+	REMOVE
+	DETACH
+	IS
+	EXISTS
+	STARTS
+	ENDS
+	ANY
+	UNION
+	ALL
+	DISTINCT
+	UNWIND
 
 	// Identifiers and literals
 	IDENT
@@ -23,6 +47,7 @@ const (
 	BOOLEAN
 	JSONPATH
 	JSONDATA
+	PARAM // $name
 
 	// Delimiters
 	LPAREN   // (
@@ -34,6 +59,7 @@ const (
 	COLON    // :
 	COMMA    // ,
 	DOT      // .
+	DOTDOT   // ..
 
 	// Operators
 	EQUALS
@@ -58,4 +84,8 @@ const (
 	// Aggregation functions
 	COUNT
 	SUM
+	AVG
+	MIN
+	MAX
+	COLLECT
 )
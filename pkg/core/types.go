@@ -19,10 +19,13 @@ type Token struct {
 // TokenType represents the type of a lexical token
 type TokenType int
 
-// Expression represents a complete Cyphernetes query
+// Expression represents a complete Cyphernetes query. Unions holds the queries combined with
+// the query's clauses by UNION, whose duplicate rows are removed unless UnionAll is set.
 type Expression struct {
 	Contexts []string
 	Clauses  []Clause
+	Unions   []*Expression
+	UnionAll bool
 }
 
 // Clause is an interface implemented by all clause types
@@ -34,7 +37,8 @@ type Clause interface {
 type MatchClause struct {
 	Nodes         []*NodePattern
 	Relationships []*Relationship
-	ExtraFilters  []*KeyValuePair
+	ExtraFilters  []*Filter
+	Paths         []*PathPattern
 }
 
 // CreateClause represents a CREATE clause
@@ -48,26 +52,77 @@ type SetClause struct {
 	KeyValuePairs []*KeyValuePair
 }
 
-// DeleteClause represents a DELETE clause
+// RemoveClause represents a REMOVE clause, which removes fields from the resources of its nodes
+type RemoveClause struct {
+	JsonPaths []string
+}
+
+// DeleteClause represents a DELETE clause. A DETACH DELETE clause also deletes
+// the resources that depend on the deleted ones.
 type DeleteClause struct {
 	NodeIds []string
+	Detach  bool
+}
+
+// MergeClause represents a MERGE clause, which matches a resource by its name and creates it
+// when it doesn't exist. OnCreate and OnMatch are applied depending on which happened.
+type MergeClause struct {
+	Node     *NodePattern
+	OnCreate []*KeyValuePair
+	OnMatch  []*KeyValuePair
+}
+
+// WithClause represents a WITH clause, which carries node variables and aggregations
+// over to the following clauses
+type WithClause struct {
+	Items   []*ReturnItem
+	Filters []*Filter
 }
 
-// ReturnClause represents a RETURN clause
+// UnwindClause represents an UNWIND clause, which binds Alias to each element of the list found
+// at JsonPath. Filters hold the conditions of its WHERE clause.
+type UnwindClause struct {
+	JsonPath string
+	Alias    string
+	Filters  []*Filter
+}
+
+// ReturnClause represents a RETURN clause. Distinct is set by RETURN DISTINCT, which drops
+// duplicate results. Limit is nil unless the clause has a LIMIT.
 type ReturnClause struct {
-	Items []*ReturnItem
+	Items    []*ReturnItem
+	Distinct bool
+	OrderBy  []*OrderItem
+	Skip     int
+	Limit    *int
 }
 
-// ReturnItem represents an item in a RETURN clause
+// ReturnItem represents an item in a RETURN clause.
+// Function is set for items computed by a function call, JsonPath then holds the node it refers to.
+// Distinct is set for aggregations over distinct values, such as COUNT{DISTINCT p.spec.nodeName}.
 type ReturnItem struct {
 	JsonPath  string
 	Alias     string
 	Aggregate string
+	Distinct  bool
+	Function  *FunctionCall
+}
+
+// OrderItem represents a sort key in an ORDER BY clause. Function is set when sorting
+// by the alias of a computed return item.
+type OrderItem struct {
+	JsonPath   string
+	Descending bool
+	Function   *FunctionCall
 }
 
 // NodePattern represents a node pattern in a query
 type NodePattern struct {
 	ResourceProperties *ResourceProperties
+	// Optional is set for nodes introduced by an OPTIONAL MATCH
+	Optional bool
+	// Bound is set for nodes carried over from a preceding WITH clause
+	Bound bool
 }
 
 // ResourceProperties represents the properties of a resource
@@ -89,11 +144,62 @@ type Property struct {
 	Value interface{}
 }
 
-// KeyValuePair represents a key-value pair with an operator
+// KeyValuePair represents a key-value pair with an operator.
+// In SET clauses, Value may be a *FieldReference, an *ArithmeticExpression or a *FunctionCall.
+// In WHERE clauses, Function is set for conditions on a function call, Key then holds the node it refers to.
 type KeyValuePair struct {
 	Key      string
 	Value    interface{}
 	Operator string
+	Function *FunctionCall
+}
+
+// FieldReference represents a reference to a field of a node in a SET value, e.g. d.spec.replicas
+type FieldReference struct {
+	JsonPath string
+}
+
+// ArithmeticExpression represents an arithmetic operation in a SET value. Its operands are
+// literal values, field references or nested arithmetic expressions.
+type ArithmeticExpression struct {
+	Operator string // +, -, * or /
+	Left     interface{}
+	Right    interface{}
+}
+
+// FunctionCall represents a call to a built-in function, e.g. toLower(p.metadata.name).
+// Its arguments are literal values, field references or nested function calls.
+type FunctionCall struct {
+	Name string
+	Args []interface{}
+}
+
+// FilterType represents the type of a node in a WHERE expression tree
+type FilterType string
+
+const (
+	KeyValuePairFilter FilterType = "KeyValuePair"
+	AndFilter          FilterType = "AND"
+	OrFilter           FilterType = "OR"
+	NotFilter          FilterType = "NOT"
+	PatternFilter      FilterType = "Pattern"
+	AnyFilter          FilterType = "ANY"
+)
+
+// Filter represents a node in a WHERE expression tree.
+// Leaf filters hold a KeyValuePair or a Pattern, AND and OR filters combine their operands
+// and NOT filters negate their single operand.
+// A Pattern filter holds if the resource is related as described by the pattern, e.g. (s)->(:Endpoints).
+// An ANY filter holds if its single operand holds for any element of the list at ListPath,
+// where the operand refers to the element as Variable, e.g. ANY(c IN p.spec.containers WHERE c.image =~ ":latest$").
+// The top-level filters of a MatchClause are implicitly ANDed together.
+type Filter struct {
+	Type         FilterType
+	KeyValuePair *KeyValuePair
+	Pattern      *NodeRelationshipList
+	Operands     []*Filter
+	Variable     string
+	ListPath     string
 }
 
 // Relationship represents a relationship between nodes
@@ -102,17 +208,35 @@ type Relationship struct {
 	Direction          Direction
 	LeftNode           *NodePattern
 	RightNode          *NodePattern
+	// MinHops and MaxHops bound the length of a variable-length relationship (-[*1..4]->),
+	// both are 0 for a regular single-hop relationship
+	MinHops int
+	MaxHops int
+}
+
+// PathPattern represents a path bound to a variable, e.g. p = (i:Ingress)->(s:Service).
+// Nodes holds the names of the path's nodes in order, and Relationships the relationships
+// between each node and the next.
+type PathPattern struct {
+	Name          string
+	Nodes         []string
+	Relationships []*Relationship
 }
 
 // NodeRelationshipList represents a list of nodes and relationships
 type NodeRelationshipList struct {
 	Nodes         []*NodePattern
 	Relationships []*Relationship
+	Paths         []*PathPattern
 }
 
 // Implement isClause for all clause types
 func (*MatchClause) isClause()  {}
 func (*CreateClause) isClause() {}
 func (*SetClause) isClause()    {}
+func (*RemoveClause) isClause() {}
 func (*DeleteClause) isClause() {}
 func (*ReturnClause) isClause() {}
+func (*WithClause) isClause()   {}
+func (*UnwindClause) isClause() {}
+func (*MergeClause) isClause()  {}
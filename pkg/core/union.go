@@ -0,0 +1,198 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// executeUnion executes each of the queries combined by UNION and combines their results.
+// The rows of each variable are concatenated, and duplicate rows are removed unless the queries
+// are combined by UNION ALL. The results of the queries that follow the first are reported under
+// the first query's variables and keys.
+func (q *QueryExecutor) executeUnion(ast *Expression, namespace string, opts ExecuteOptions) (QueryResult, error) {
+	combined := QueryResult{
+		Data: make(map[string]interface{}),
+		Graph: Graph{
+			Nodes: []Node{},
+			Edges: []Edge{},
+		},
+	}
+
+	queries := append([]*Expression{{Clauses: ast.Clauses}}, ast.Unions...)
+	for i, query := range queries {
+		// The namespace set for the first query is kept for the ones that follow it
+		if i > 0 {
+			namespace = ""
+		}
+		result, err := q.executeSingleQuery(query, namespace, opts)
+		if err != nil {
+			return combined, err
+		}
+		if i > 0 {
+			if err := renameUnionResult(&result, queries[0], query); err != nil {
+				return combined, err
+			}
+		}
+
+		for key, value := range result.Data {
+			rows, ok := value.([]interface{})
+			if !ok {
+				combined.Data[key] = value
+				continue
+			}
+			existing, _ := combined.Data[key].([]interface{})
+			combined.Data[key] = append(existing, rows...)
+		}
+		combined.Rows = append(combined.Rows, result.Rows...)
+		for _, node := range result.Graph.Nodes {
+			if !slices.Contains(combined.Graph.Nodes, node) {
+				combined.Graph.Nodes = append(combined.Graph.Nodes, node)
+			}
+		}
+		for _, edge := range result.Graph.Edges {
+			if !slices.Contains(combined.Graph.Edges, edge) {
+				combined.Graph.Edges = append(combined.Graph.Edges, edge)
+			}
+		}
+	}
+
+	if !ast.UnionAll {
+		for key, value := range combined.Data {
+			if rows, ok := value.([]interface{}); ok {
+				combined.Data[key] = distinctRows(rows)
+			}
+		}
+		if combined.Rows != nil {
+			combined.Rows = distinctRows(combined.Rows)
+		}
+	}
+	return combined, nil
+}
+
+// distinctRows returns the rows without their duplicates, keeping the first occurrence of each
+func distinctRows[T any](rows []T) []T {
+	seen := make(map[string]bool)
+	distinct := []T{}
+	for _, row := range rows {
+		key, err := json.Marshal(row)
+		if err != nil {
+			key = []byte(fmt.Sprintf("%v", row))
+		}
+		if !seen[string(key)] {
+			seen[string(key)] = true
+			distinct = append(distinct, row)
+		}
+	}
+	return distinct
+}
+
+// unionColumn returns the column a return item fills in the results of queries combined by UNION:
+// its alias or, since each query names its own variables, its path without the variable, prefixed
+// with its aggregation, e.g. metadata.name for d.metadata.name, count:metadata.name for
+// COUNT{d.metadata.name} and * for d. Computed items without an alias are told apart by their call.
+func unionColumn(item *ReturnItem) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	if item.Function != nil {
+		return item.Function.String()
+	}
+	path := "*"
+	if _, rest, found := strings.Cut(item.JsonPath, "."); found {
+		path = rest
+	}
+	if item.Aggregate == "" {
+		return path
+	}
+	column := strings.ToLower(item.Aggregate) + ":"
+	if item.Distinct {
+		column += "distinct "
+	}
+	return column + path
+}
+
+// unionItemKey returns the key under which a return item is reported in the rows of the results
+func unionItemKey(item *ReturnItem) string {
+	if item.Aggregate != "" {
+		return aggregateKey(item)
+	}
+	return returnItemKey(item)
+}
+
+// unionMapping maps the variables under which a query combined by UNION returns its columns to
+// those under which the first query returns them, and the keys it reports them under to the first
+// query's keys. Each variable must return the columns of exactly one of the first query's variables.
+func unionMapping(first, query *ReturnClause) (map[string]string, map[string]string, error) {
+	firstItems := make(map[string]*ReturnItem)
+	for _, item := range first.Items {
+		firstItems[unionColumn(item)] = item
+	}
+
+	variables := make(map[string]string)
+	sources := make(map[string]string)
+	keys := make(map[string]string)
+	for _, item := range query.Items {
+		firstItem := firstItems[unionColumn(item)]
+		if firstItem == nil {
+			continue
+		}
+		variable := strings.Split(item.JsonPath, ".")[0]
+		firstVariable := strings.Split(firstItem.JsonPath, ".")[0]
+		if mapped, ok := variables[variable]; ok && mapped != firstVariable {
+			return nil, nil, fmt.Errorf("queries combined by UNION must return the columns of each variable together, the columns of %s are returned under both %s and %s", variable, mapped, firstVariable)
+		}
+		if source, ok := sources[firstVariable]; ok && source != variable {
+			return nil, nil, fmt.Errorf("queries combined by UNION must return the columns of each variable together, the columns of %s are returned under both %s and %s", firstVariable, source, variable)
+		}
+		variables[variable] = firstVariable
+		sources[firstVariable] = variable
+		keys[unionItemKey(item)] = unionItemKey(firstItem)
+	}
+	return variables, keys, nil
+}
+
+// renameUnionResult reports the result of a query combined by UNION under the variables and keys
+// of the first query
+func renameUnionResult(result *QueryResult, first, query *Expression) error {
+	variables, keys, err := unionMapping(first.Clauses[len(first.Clauses)-1].(*ReturnClause), query.Clauses[len(query.Clauses)-1].(*ReturnClause))
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]interface{})
+	for name, value := range result.Data {
+		if variable, ok := variables[name]; ok {
+			name = variable
+		}
+		if rows, ok := value.([]interface{}); ok {
+			renamed := make([]interface{}, len(rows))
+			for i, row := range rows {
+				renamed[i] = row
+				if row, ok := row.(map[string]interface{}); ok {
+					renamed[i] = renameKeys(row, keys)
+				}
+			}
+			value = renamed
+		}
+		data[name] = value
+	}
+	result.Data = data
+	for i, row := range result.Rows {
+		result.Rows[i] = renameKeys(row, keys)
+	}
+	return nil
+}
+
+// renameKeys returns a copy of a row with its keys renamed
+func renameKeys(row map[string]interface{}, keys map[string]string) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		if newKey, ok := keys[key]; ok {
+			key = newKey
+		}
+		renamed[key] = value
+	}
+	return renamed
+}
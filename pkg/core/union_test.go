@@ -0,0 +1,145 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestExecuteUnion(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", nil),
+			mockResource("Deployment", "api", nil),
+		},
+		"statefulsets": {
+			mockResource("StatefulSet", "db", nil),
+			mockResource("StatefulSet", "web", nil),
+		},
+		"daemonsets": {
+			mockResource("DaemonSet", "logs", nil),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantNames []string
+		wantNodes int
+	}{
+		{
+			name: "union removes duplicate rows",
+			query: `MATCH (w:Deployment) RETURN w.metadata.name AS workload
+				UNION MATCH (w:StatefulSet) RETURN w.metadata.name AS workload
+				UNION MATCH (w:DaemonSet) RETURN w.metadata.name AS workload`,
+			wantNames: []string{"web", "api", "db", "logs"},
+			wantNodes: 5,
+		},
+		{
+			name: "union all keeps duplicate rows",
+			query: `MATCH (w:Deployment) RETURN w.metadata.name AS workload
+				UNION ALL MATCH (w:StatefulSet) RETURN w.metadata.name AS workload`,
+			wantNames: []string{"web", "api", "db", "web"},
+			wantNodes: 4,
+		},
+		{
+			name: "each query keeps its own conditions",
+			query: `MATCH (w:Deployment {name: "api"}) RETURN w.metadata.name AS workload
+				UNION MATCH (w:StatefulSet) WHERE w.metadata.name = "db" RETURN w.metadata.name AS workload`,
+			wantNames: []string{"api", "db"},
+			wantNodes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+
+			var names []string
+			for _, row := range result.Data["w"].([]interface{}) {
+				names = append(names, row.(map[string]interface{})["workload"].(string))
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("got workloads %v, want %v", names, tt.wantNames)
+			}
+			if len(result.Rows) != len(tt.wantNames) {
+				t.Errorf("got %d rows, want %d: %v", len(result.Rows), len(tt.wantNames), result.Rows)
+			}
+			if len(result.Graph.Nodes) != tt.wantNodes {
+				t.Errorf("got %d graph nodes, want %d: %v", len(result.Graph.Nodes), tt.wantNodes, result.Graph.Nodes)
+			}
+		})
+	}
+}
+
+func TestExecuteUnionOfVariables(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", nil),
+			mockResource("Deployment", "api", nil),
+		},
+		"statefulsets": {
+			mockResource("StatefulSet", "db", nil),
+			mockResource("StatefulSet", "web", nil),
+		},
+		"daemonsets": {
+			mockResource("DaemonSet", "logs", nil),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantKeys []string
+		wantRows []map[string]interface{}
+	}{
+		{
+			name: "aliased columns of different variables",
+			query: `MATCH (d:Deployment) RETURN d.metadata.name AS n
+				UNION MATCH (s:StatefulSet) RETURN s.metadata.name AS n`,
+			wantKeys: []string{"d"},
+			wantRows: []map[string]interface{}{{"n": "web"}, {"n": "api"}, {"n": "db"}},
+		},
+		{
+			name: "paths of different variables",
+			query: `MATCH (d:Deployment) RETURN d.metadata.name
+				UNION MATCH (x:DaemonSet) RETURN x.metadata.name`,
+			wantKeys: []string{"d"},
+			wantRows: []map[string]interface{}{
+				{"d.metadata.name": "web"}, {"d.metadata.name": "api"}, {"d.metadata.name": "logs"},
+			},
+		},
+		{
+			name: "grouped aggregations of different variables",
+			query: `MATCH (d:Deployment) RETURN d.metadata.namespace AS namespace, COUNT{d.metadata.name}
+				UNION MATCH (x:DaemonSet) RETURN x.metadata.namespace AS namespace, COUNT{x.metadata.name}`,
+			wantKeys: []string{"aggregate", "d"},
+			wantRows: []map[string]interface{}{
+				{"namespace": "default", "count:d.metadata.name": 2},
+				{"namespace": "default", "count:d.metadata.name": 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+
+			keys := slices.Sorted(maps.Keys(result.Data))
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("got results under %v, want %v", keys, tt.wantKeys)
+			}
+			if fmt.Sprint(result.Rows) != fmt.Sprint(tt.wantRows) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// executeUnwindClause binds the variable of an UNWIND clause to the elements of the lists found at
+// its path. The resources of the node the path leads into, directly or through the elements of
+// preceding UNWIND clauses, are narrowed down to those with at least one element, and so are the
+// resources related to them. nodes, relationships and unwinds are those of the preceding clauses.
+func (q *QueryExecutor) executeUnwindClause(c *UnwindClause, nodes []*NodePattern, relationships []*Relationship, unwinds []*UnwindClause, results *QueryResult) error {
+	// Elements are unwound anew from the node, so that an element without elements of its own
+	// no longer counts for the resource it was unwound from
+	chain := unwindChain(c, unwinds)
+	root := strings.Split(chain[0].JsonPath, ".")[0]
+
+	collected := make(map[string][]map[string]interface{})
+	for _, u := range chain {
+		collected[u.Alias] = []map[string]interface{}{}
+	}
+	resources, _ := resultMap[root].([]map[string]interface{})
+	kept := []map[string]interface{}{}
+	for _, resource := range resources {
+		found, err := collectElements(resource, chain, collected)
+		if err != nil {
+			return err
+		}
+		if found {
+			kept = append(kept, resource)
+		}
+	}
+	resultMap[root] = kept
+	for name, elements := range collected {
+		resultMap[name] = elements
+	}
+
+	var inScope, related []string
+	kinds := make(map[string]string)
+	for _, node := range nodes {
+		name := node.ResourceProperties.Name
+		if resultMap[name] == nil || slices.Contains(inScope, name) {
+			continue
+		}
+		inScope = append(inScope, name)
+		if node.ResourceProperties.Kind != "" {
+			kinds[name] = node.ResourceProperties.Kind
+			related = append(related, name)
+		}
+	}
+	if err := q.propagateFiltering(related, kinds, relationships); err != nil {
+		return err
+	}
+	pruneGraph(results, inScope)
+	return nil
+}
+
+// unwindChain returns the UNWIND clauses that unwound the elements an UNWIND clause's path leads
+// into, starting with the one whose path leads into a node, followed by the clause itself
+func unwindChain(c *UnwindClause, unwinds []*UnwindClause) []*UnwindClause {
+	chain := []*UnwindClause{c}
+	for {
+		variable := strings.Split(chain[0].JsonPath, ".")[0]
+		index := slices.IndexFunc(unwinds, func(u *UnwindClause) bool { return u.Alias == variable })
+		if index < 0 {
+			return chain
+		}
+		chain = append([]*UnwindClause{unwinds[index]}, chain...)
+	}
+}
+
+// collectElements collects the elements a resource unwinds to through a chain of UNWIND clauses,
+// keeping only the elements that unwind to elements through the rest of the chain, and reports
+// whether there are any
+func collectElements(resource map[string]interface{}, chain []*UnwindClause, collected map[string][]map[string]interface{}) (bool, error) {
+	elements, err := unwindElements(resource, chain[0])
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, element := range elements {
+		if len(chain) > 1 {
+			ok, err := collectElements(element, chain[1:], collected)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		collected[chain[0].Alias] = append(collected[chain[0].Alias], element)
+		found = true
+	}
+	return found, nil
+}
+
+// unwindElements returns the elements of the list found at the path of an UNWIND clause in a
+// resource that pass the clause's filters. Lists of lists, such as those found at paths with a
+// wildcard, are flattened, and an object that isn't in a list is a single element.
+func unwindElements(resource map[string]interface{}, c *UnwindClause) ([]map[string]interface{}, error) {
+	value := lookupReturnPath(resource, c.JsonPath)
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+
+	var elements []map[string]interface{}
+	for _, item := range list {
+		items := []interface{}{item}
+		if nested, ok := item.([]interface{}); ok {
+			items = nested
+		}
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			element, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("UNWIND expects a list of objects at %s, got %v", c.JsonPath, item)
+			}
+			matches := true
+			for _, filter := range c.Filters {
+				if !evaluateFilter(element, filter, c.Alias, nil) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements, nil
+}
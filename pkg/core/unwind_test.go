@@ -0,0 +1,133 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecuteUnwind(t *testing.T) {
+	pod := func(name, owner string, containers ...map[string]interface{}) map[string]interface{} {
+		list := []interface{}{}
+		for _, container := range containers {
+			list = append(list, container)
+		}
+		return mockResource("Pod", name, ownedBy(owner), map[string]interface{}{
+			"spec": map[string]interface{}{"containers": list},
+		})
+	}
+	container := func(name, image string, ports ...int) map[string]interface{} {
+		container := map[string]interface{}{"name": name, "image": image}
+		if len(ports) > 0 {
+			list := []interface{}{}
+			for _, port := range ports {
+				list = append(list, map[string]interface{}{"containerPort": port})
+			}
+			container["ports"] = list
+		}
+		return container
+	}
+	resources := map[string][]map[string]interface{}{
+		"deployments": {
+			mockResource("Deployment", "web", nil),
+			mockResource("Deployment", "api", nil),
+		},
+		"replicasets": {
+			mockResource("ReplicaSet", "web-1", ownedBy("web")),
+			mockResource("ReplicaSet", "api-1", ownedBy("api")),
+		},
+		"pods": {
+			pod("web-a", "web-1", container("nginx", "nginx:1.25", 80, 443), container("envoy", "envoy:1.30")),
+			pod("api-a", "api-1", container("api", "api:2.0", 8080)),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		want     interface{}
+		wantRows []map[string]interface{}
+	}{
+		{
+			name:     "one result per element",
+			query:    `MATCH (p:Pod) UNWIND p.spec.containers AS c RETURN c.image AS image`,
+			variable: "c",
+			want: []interface{}{
+				map[string]interface{}{"image": "nginx:1.25"},
+				map[string]interface{}{"image": "envoy:1.30"},
+				map[string]interface{}{"image": "api:2.0"},
+			},
+			wantRows: []map[string]interface{}{{"image": "nginx:1.25"}, {"image": "envoy:1.30"}, {"image": "api:2.0"}},
+		},
+		{
+			name:     "where narrows the resources the elements were unwound from",
+			query:    `MATCH (p:Pod) UNWIND p.spec.containers AS c WHERE c.image =~ "^envoy" RETURN p.metadata.name AS pod, c.name AS container`,
+			variable: "p",
+			want:     []interface{}{map[string]interface{}{"pod": "web-a", "name": "web-a"}},
+			wantRows: []map[string]interface{}{{"pod": "web-a", "container": "envoy"}},
+		},
+		{
+			name:     "related nodes are narrowed as well",
+			query:    `MATCH (d:Deployment)->(rs:ReplicaSet)->(p:Pod) UNWIND p.spec.containers AS c WHERE c.name = "api" RETURN d.metadata.name AS deployment`,
+			variable: "d",
+			want:     []interface{}{map[string]interface{}{"deployment": "api", "name": "api"}},
+			wantRows: []map[string]interface{}{{"deployment": "api"}},
+		},
+		{
+			name:     "aggregations over elements",
+			query:    `MATCH (p:Pod) UNWIND p.spec.containers AS c RETURN COUNT{c} AS containers, COLLECT{c.name} AS names`,
+			variable: "aggregate",
+			want:     map[string]interface{}{"containers": 3, "names": []interface{}{"nginx", "envoy", "api"}},
+			wantRows: []map[string]interface{}{{"containers": 3, "names": []interface{}{"nginx", "envoy", "api"}}},
+		},
+		{
+			name:     "aggregations grouped by elements",
+			query:    `MATCH (p:Pod) UNWIND p.spec.containers AS c RETURN c.name AS container, COUNT{c.ports} AS exposing`,
+			variable: "aggregate",
+			want: []interface{}{
+				map[string]interface{}{"container": "nginx", "exposing": 1},
+				map[string]interface{}{"container": "envoy", "exposing": 0},
+				map[string]interface{}{"container": "api", "exposing": 1},
+			},
+			wantRows: []map[string]interface{}{
+				{"container": "nginx", "exposing": 1},
+				{"container": "envoy", "exposing": 0},
+				{"container": "api", "exposing": 1},
+			},
+		},
+		{
+			name:     "elements unwound from elements",
+			query:    `MATCH (p:Pod) UNWIND p.spec.containers AS c UNWIND c.ports AS port RETURN c.name AS container, port.containerPort AS port`,
+			variable: "c",
+			want: []interface{}{
+				map[string]interface{}{"container": "nginx"},
+				map[string]interface{}{"container": "api"},
+			},
+			wantRows: []map[string]interface{}{
+				{"container": "nginx", "port": 80},
+				{"container": "nginx", "port": 443},
+				{"container": "api", "port": 8080},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQueryWithRows(tt.query, resources)
+			if err != nil {
+				t.Fatalf("executeMockQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(result.Data[tt.variable], tt.want) {
+				t.Errorf("got %s %v, want %v", tt.variable, result.Data[tt.variable], tt.want)
+			}
+			if !reflect.DeepEqual(result.Rows, tt.wantRows) {
+				t.Errorf("got rows %v, want %v", result.Rows, tt.wantRows)
+			}
+		})
+	}
+
+	if _, err := executeMockQuery(`MATCH (p:Pod) UNWIND p.spec.containers[*].image AS image RETURN image`, resources); err == nil || !strings.Contains(err.Error(), "UNWIND expects a list of objects") {
+		t.Errorf("got error %v, want an error for a list of strings", err)
+	}
+}
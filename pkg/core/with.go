@@ -0,0 +1,302 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// withAggregate holds the values of an aggregation computed by a WITH clause. Grouped aggregations
+// hold a value per group, ungrouped ones a single value.
+type withAggregate struct {
+	group  []string
+	value  interface{}
+	groups []*withGroup
+}
+
+// withGroup binds the carried variables an aggregation is grouped by to resources, and holds the
+// aggregation's value over the resources related to them
+type withGroup struct {
+	binding rowBinding
+	value   interface{}
+}
+
+// executeWithClause narrows the results of the preceding clauses down to the node variables
+// carried by a WITH clause, computes its aggregations and applies its filters.
+// nodes and relationships are those of the match clauses preceding the WITH clause.
+// It reports whether any results remain to be carried over.
+func (q *QueryExecutor) executeWithClause(c *WithClause, nodes []*NodePattern, relationships []*Relationship, results *QueryResult) (bool, error) {
+	var carried []string
+	for _, item := range c.Items {
+		if item.Aggregate == "" {
+			carried = append(carried, item.JsonPath)
+		}
+	}
+
+	kinds := make(map[string]string)
+	for _, node := range nodes {
+		if node.ResourceProperties.Kind != "" {
+			kinds[node.ResourceProperties.Name] = node.ResourceProperties.Kind
+		}
+	}
+
+	aggregates := make(map[string]*withAggregate)
+	for _, item := range c.Items {
+		if item.Aggregate == "" {
+			continue
+		}
+		aggregate, err := q.computeWithAggregate(item, carried, nodes, relationships)
+		if err != nil {
+			return false, err
+		}
+		aggregates[item.Alias] = aggregate
+	}
+
+	hasResults := true
+	for _, filter := range c.Filters {
+		names := getFilterNodeNames(filter)
+		if len(names) != 1 {
+			return false, fmt.Errorf("WITH WHERE conditions must reference exactly one carried variable or alias, got %d", len(names))
+		}
+		name := names[0]
+		if aggregate, ok := aggregates[name]; ok {
+			hasResults = filterByAggregate(filter, name, aggregate) && hasResults
+			continue
+		}
+
+		resources, _ := resultMap[name].([]map[string]interface{})
+		node := &NodePattern{ResourceProperties: &ResourceProperties{Name: name, Kind: kinds[name]}}
+		patternMatches := make(map[*Filter]map[string]bool)
+		if err := q.matchPatterns(resources, filter, node, patternMatches); err != nil {
+			return false, err
+		}
+
+		filtered := []map[string]interface{}{}
+		for _, resource := range resources {
+			if evaluateFilter(resource, filter, name, patternMatches) {
+				filtered = append(filtered, resource)
+			}
+		}
+		resultMap[name] = filtered
+	}
+
+	// Variables that aren't carried over go out of scope
+	for name := range resultMap {
+		if !slices.Contains(carried, name) {
+			delete(resultMap, name)
+		}
+	}
+
+	if err := q.propagateFiltering(carried, kinds, relationships); err != nil {
+		return false, err
+	}
+
+	// Like an ungrouped aggregation that was filtered out, a required variable left without
+	// resources leaves nothing to carry over
+	for _, node := range nodes {
+		resources, _ := resultMap[node.ResourceProperties.Name].([]map[string]interface{})
+		if slices.Contains(carried, node.ResourceProperties.Name) && !node.Optional && len(resources) == 0 {
+			hasResults = false
+		}
+	}
+	if !hasResults {
+		for _, name := range carried {
+			resultMap[name] = []map[string]interface{}{}
+		}
+	}
+	pruneGraph(results, carried)
+
+	// The following match clauses fetch their resources anew
+	resultCache = make(map[string]interface{})
+	return hasResults, nil
+}
+
+// computeWithAggregate computes an aggregation of a WITH clause. When node variables are carried,
+// the aggregation is grouped by those related to the variable it refers to, directly or through
+// other nodes, and computed over the resources related to each combination of their resources.
+// It isn't grouped when none of them is related to it.
+func (q *QueryExecutor) computeWithAggregate(item *ReturnItem, carried []string, nodes []*NodePattern, relationships []*Relationship) (*withAggregate, error) {
+	nodeId := strings.Split(item.JsonPath, ".")[0]
+	pathStr := toJsonPath(item.JsonPath)
+
+	aggregate := &withAggregate{}
+	for _, name := range connectedVariables([]string{nodeId}, relationships) {
+		if slices.Contains(carried, name) {
+			aggregate.group = append(aggregate.group, name)
+		}
+	}
+	if len(aggregate.group) == 0 {
+		resources, _ := resultMap[nodeId].([]map[string]interface{})
+		value, err := aggregateResources(item.Aggregate, item.Distinct, pathStr, resources)
+		if err != nil {
+			return nil, err
+		}
+		aggregate.value = value
+		return aggregate, nil
+	}
+
+	bindings, err := q.rowBindings([]string{nodeId}, nodes, relationships, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsByKey := make(map[string]*withGroup)
+	resourcesByKey := make(map[string][]map[string]interface{})
+	for _, binding := range bindings {
+		groupBinding := make(rowBinding)
+		var keys []string
+		for _, name := range aggregate.group {
+			groupBinding[name] = binding[name]
+			keys = append(keys, bindingKey(binding[name]))
+		}
+		key := strings.Join(keys, ",")
+		if groupsByKey[key] == nil {
+			groupsByKey[key] = &withGroup{binding: groupBinding}
+			aggregate.groups = append(aggregate.groups, groupsByKey[key])
+		}
+		if resource := binding[nodeId]; resource != nil && !slices.ContainsFunc(resourcesByKey[key], func(r map[string]interface{}) bool {
+			return bindingKey(r) == bindingKey(resource)
+		}) {
+			resourcesByKey[key] = append(resourcesByKey[key], resource)
+		}
+	}
+
+	for key, group := range groupsByKey {
+		value, err := aggregateResources(item.Aggregate, item.Distinct, pathStr, resourcesByKey[key])
+		if err != nil {
+			return nil, err
+		}
+		group.value = value
+	}
+	return aggregate, nil
+}
+
+// filterByAggregate applies a filter on an aggregation alias. A grouped aggregation keeps the
+// resources of its group variables that belong to a group whose value passes the filter, an
+// ungrouped one reports whether its value passes the filter.
+func filterByAggregate(filter *Filter, alias string, aggregate *withAggregate) bool {
+	matches := func(value interface{}) bool {
+		// Counts are compared as numbers
+		if count, ok := value.(int); ok {
+			value = float64(count)
+		}
+		return evaluateFilter(map[string]interface{}{alias: value}, filter, "", nil)
+	}
+
+	if len(aggregate.group) == 0 {
+		return matches(aggregate.value)
+	}
+
+	kept := make(map[string]bool)
+	for _, group := range aggregate.groups {
+		if !matches(group.value) {
+			continue
+		}
+		for name, resource := range group.binding {
+			if resource != nil {
+				kept[name+"/"+bindingKey(resource)] = true
+			}
+		}
+	}
+	for _, name := range aggregate.group {
+		resources, _ := resultMap[name].([]map[string]interface{})
+		filtered := []map[string]interface{}{}
+		for _, resource := range resources {
+			if kept[name+"/"+bindingKey(resource)] {
+				filtered = append(filtered, resource)
+			}
+		}
+		resultMap[name] = filtered
+	}
+	return true
+}
+
+// propagateFiltering drops the carried resources that are no longer related to the
+// resources of the carried variables they were matched with
+func (q *QueryExecutor) propagateFiltering(carried []string, kinds map[string]string, relationships []*Relationship) error {
+	var carriedRels []*Relationship
+	for _, rel := range relationships {
+		if rel.LeftNode.Optional != rel.RightNode.Optional {
+			continue
+		}
+		if slices.Contains(carried, rel.LeftNode.ResourceProperties.Name) && slices.Contains(carried, rel.RightNode.ResourceProperties.Name) {
+			carriedRels = append(carriedRels, rel)
+		}
+	}
+
+	for i := 0; i < len(carriedRels)*2; i++ {
+		filteringOccurred := false
+		for _, rel := range carriedRels {
+			leftName, rightName := rel.LeftNode.ResourceProperties.Name, rel.RightNode.ResourceProperties.Name
+			leftKind, err := q.findGVR(kinds[leftName])
+			if err != nil {
+				return fmt.Errorf("error finding API resource >> %s", err)
+			}
+			rightKind, err := q.findGVR(kinds[rightName])
+			if err != nil {
+				return fmt.Errorf("error finding API resource >> %s", err)
+			}
+
+			left, _ := resultMap[leftName].([]map[string]interface{})
+			right, _ := resultMap[rightName].([]map[string]interface{})
+			matchedLeft, matchedRight, err := q.relateResources(left, right, leftKind.Resource, rightKind.Resource, rel, leftName)
+			if err != nil {
+				return err
+			}
+			if len(matchedLeft) < len(left) || len(matchedRight) < len(right) {
+				filteringOccurred = true
+				resultMap[leftName] = append([]map[string]interface{}{}, matchedLeft...)
+				resultMap[rightName] = append([]map[string]interface{}{}, matchedRight...)
+			}
+		}
+		if !filteringOccurred {
+			break
+		}
+	}
+	return nil
+}
+
+// pruneGraph removes the graph nodes of variables that went out of scope or were filtered out,
+// along with their edges
+func pruneGraph(results *QueryResult, carried []string) {
+	kept := make(map[string]bool)
+	for _, name := range carried {
+		resources, _ := resultMap[name].([]map[string]interface{})
+		for _, resource := range resources {
+			metadata, _ := resource["metadata"].(map[string]interface{})
+			kept[fmt.Sprintf("%s/%v", resource["kind"], metadata["name"])] = true
+		}
+	}
+
+	// Intermediate nodes of variable-length relationships have no variable, they're kept while connected
+	for _, node := range results.Graph.Nodes {
+		if node.Id == "" {
+			kept[fmt.Sprintf("%s/%s", node.Kind, node.Name)] = true
+		}
+	}
+
+	edges := []Edge{}
+	connected := make(map[string]bool)
+	for _, edge := range results.Graph.Edges {
+		if kept[edge.From] && kept[edge.To] {
+			edges = append(edges, edge)
+			connected[edge.From] = true
+			connected[edge.To] = true
+		}
+	}
+
+	nodes := []Node{}
+	for _, node := range results.Graph.Nodes {
+		key := fmt.Sprintf("%s/%s", node.Kind, node.Name)
+		if node.Id == "" && !connected[key] {
+			continue
+		}
+		if node.Id != "" && (!slices.Contains(carried, node.Id) || !kept[key]) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	results.Graph.Nodes = nodes
+	results.Graph.Edges = edges
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecuteWithClause(t *testing.T) {
+	resources := map[string][]map[string]interface{}{
+		"namespaces": {
+			mockResource("Namespace", "busy", nil),
+			mockResource("Namespace", "quiet", nil),
+		},
+		"pods": {
+			mockResource("Pod", "busy-1", inNamespace("busy"), ownedBy("busy-web-1")),
+			mockResource("Pod", "busy-2", inNamespace("busy"), ownedBy("busy-web-1")),
+			mockResource("Pod", "quiet-1", inNamespace("quiet"), ownedBy("quiet-web-1"), map[string]interface{}{
+				"status": map[string]interface{}{"phase": "Failed"},
+			}),
+		},
+		"deployments": {
+			mockResource("Deployment", "busy-web", inNamespace("busy")),
+			mockResource("Deployment", "quiet-web", inNamespace("quiet")),
+		},
+		"replicasets": {
+			mockResource("ReplicaSet", "busy-web-1", inNamespace("busy"), ownedBy("busy-web")),
+			mockResource("ReplicaSet", "quiet-web-1", inNamespace("quiet"), ownedBy("quiet-web")),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "filter on a grouped aggregation",
+			query:    "MATCH (ns:Namespace)->(p:Pod) WITH ns, COUNT{p.metadata.name} AS pods WHERE pods > 1 MATCH (ns)->(d:Deployment) RETURN d.metadata.name",
+			variable: "d",
+			expected: []string{"busy-web"},
+		},
+		{
+			name:     "aggregation over a variable related through other nodes",
+			query:    "MATCH (d:Deployment)->(rs:ReplicaSet)->(p:Pod) WITH d, COUNT{p.metadata.name} AS pods WHERE pods > 1 RETURN d.metadata.name",
+			variable: "d",
+			expected: []string{"busy-web"},
+		},
+		{
+			name:     "aggregation grouped by several carried variables",
+			query:    "MATCH (ns:Namespace)->(d:Deployment)->(rs:ReplicaSet)->(p:Pod) WITH ns, d, COUNT{p.metadata.name} AS pods WHERE pods = 1 RETURN ns.metadata.name",
+			variable: "ns",
+			expected: []string{"quiet"},
+		},
+		{
+			name:     "aggregation unrelated to the carried variables",
+			query:    "MATCH (ns:Namespace), (p:Pod) WITH ns, COUNT{p.metadata.name} AS pods WHERE pods = 3 RETURN ns.metadata.name",
+			variable: "ns",
+			expected: []string{"busy", "quiet"},
+		},
+		{
+			name:     "filter on a carried variable propagates to related variables",
+			query:    `MATCH (ns:Namespace)->(p:Pod) WITH ns, p WHERE p.status.phase = "Failed" RETURN ns.metadata.name`,
+			variable: "ns",
+			expected: []string{"quiet"},
+		},
+		{
+			name:     "ungrouped aggregation filtered out",
+			query:    "MATCH (p:Pod) WITH COUNT{p.metadata.name} AS pods WHERE pods > 5 MATCH (d:Deployment) RETURN d.metadata.name",
+			variable: "d",
+			expected: nil,
+		},
+		{
+			name:     "ungrouped aggregation kept",
+			query:    "MATCH (p:Pod) WITH COUNT{p.metadata.name} AS pods WHERE pods = 3 MATCH (d:Deployment) RETURN d.metadata.name",
+			variable: "d",
+			expected: []string{"busy-web", "quiet-web"},
+		},
+		{
+			name:    "variables that aren't carried go out of scope",
+			query:   "MATCH (ns:Namespace)->(p:Pod) WITH ns RETURN p.metadata.name",
+			wantErr: "node identifier p not found",
+		},
+		{
+			name:    "aliases of aggregations aren't returned",
+			query:   "MATCH (ns:Namespace)->(p:Pod) WITH ns, COUNT{p} AS n RETURN ns.metadata.name, n",
+			wantErr: "RETURN can't reference n, the alias of an aggregation in WITH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executeMockQuery(tt.query, resources)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			var got []string
+			for _, resource := range result.Data[tt.variable].([]interface{}) {
+				got = append(got, resource.(map[string]interface{})["name"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecuteWithClauseFilterWithoutVariable(t *testing.T) {
+	ast, err := ParseQuery("MATCH (ns:Namespace)->(p:Pod) WITH ns, COUNT{p.metadata.name} AS pods WHERE pods > 1 RETURN ns")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	// Conditions built outside the parser may reference nothing
+	ast.Clauses[1].(*WithClause).Filters = []*Filter{{Type: AndFilter}}
+
+	executor, err := NewQueryExecutor(&mockProvider{resources: map[string][]map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("NewQueryExecutor() error = %v", err)
+	}
+	_, err = executor.Execute(ast, "default")
+	if err == nil || !strings.Contains(err.Error(), "must reference exactly one carried variable or alias") {
+		t.Errorf("Execute() error = %v, want an error about the condition's variables", err)
+	}
+}
@@ -30,18 +30,22 @@ type APIServerProviderConfig struct {
 	Clientset     kubernetes.Interface
 	DynamicClient dynamic.Interface
 	DryRun        bool
+	// PropagationPolicy of deletions: background, foreground or orphan.
+	// The API server's default for the resource is used when empty.
+	PropagationPolicy string
 }
 
 type APIServerProvider struct {
-	clientset      kubernetes.Interface
-	dynamicClient  dynamic.Interface
-	gvrCache       map[string]schema.GroupVersionResource
-	gvrCacheMutex  sync.RWMutex
-	openAPIDoc     *openapi_v3.Document
-	requestChannel chan *apiRequest
-	semaphore      chan struct{}
-	resourceMutex  sync.RWMutex
-	dryRun         bool
+	clientset         kubernetes.Interface
+	dynamicClient     dynamic.Interface
+	gvrCache          map[string]schema.GroupVersionResource
+	gvrCacheMutex     sync.RWMutex
+	openAPIDoc        *openapi_v3.Document
+	requestChannel    chan *apiRequest
+	semaphore         chan struct{}
+	resourceMutex     sync.RWMutex
+	dryRun            bool
+	propagationPolicy *metav1.DeletionPropagation
 }
 
 type apiRequest struct {
@@ -62,7 +66,11 @@ func NewAPIServerProvider() (provider.Provider, error) {
 }
 
 func NewAPIServerProviderWithOptions(config *APIServerProviderConfig) (provider.Provider, error) {
-	var err error
+	propagationPolicy, err := parsePropagationPolicy(config.PropagationPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	clientset := config.Clientset
 	dynamicClient := config.DynamicClient
 
@@ -98,12 +106,13 @@ func NewAPIServerProviderWithOptions(config *APIServerProviderConfig) (provider.
 	}
 
 	provider := &APIServerProvider{
-		clientset:      clientset,
-		dynamicClient:  dynamicClient,
-		gvrCache:       make(map[string]schema.GroupVersionResource),
-		requestChannel: make(chan *apiRequest),
-		semaphore:      make(chan struct{}, 1),
-		dryRun:         config.DryRun,
+		clientset:         clientset,
+		dynamicClient:     dynamicClient,
+		gvrCache:          make(map[string]schema.GroupVersionResource),
+		requestChannel:    make(chan *apiRequest),
+		semaphore:         make(chan struct{}, 1),
+		dryRun:            config.DryRun,
+		propagationPolicy: propagationPolicy,
 	}
 
 	if config.DryRun {
@@ -293,7 +302,7 @@ func (p *APIServerProvider) DeleteK8sResources(kind, name, namespace string) err
 		return err
 	}
 
-	var deleteOpts metav1.DeleteOptions
+	deleteOpts := metav1.DeleteOptions{PropagationPolicy: p.propagationPolicy}
 	if p.dryRun {
 		deleteOpts.DryRun = []string{metav1.DryRunAll}
 	}
@@ -616,6 +625,24 @@ func toUnstructured(obj interface{}) (*unstructured.Unstructured, error) {
 	}
 }
 
+// parsePropagationPolicy converts a cascade option, as accepted by kubectl, to a deletion propagation policy
+func parsePropagationPolicy(policy string) (*metav1.DeletionPropagation, error) {
+	var propagation metav1.DeletionPropagation
+	switch strings.ToLower(policy) {
+	case "":
+		return nil, nil
+	case "background":
+		propagation = metav1.DeletePropagationBackground
+	case "foreground":
+		propagation = metav1.DeletePropagationForeground
+	case "orphan":
+		propagation = metav1.DeletePropagationOrphan
+	default:
+		return nil, fmt.Errorf("invalid propagation policy %q, must be one of background, foreground or orphan", policy)
+	}
+	return &propagation, nil
+}
+
 // Add the parseSchema method and its helpers
 func (p *APIServerProvider) parseSchema(schema *openapi_v3.Schema, prefix string, visited map[string][]string, parentType string) []string {
 	var fields []string
@@ -783,6 +810,7 @@ func (p *APIServerProvider) CreateProviderForContext(context string) (provider.P
 
 	// Initialize the GVR cache for the new context
 	apiProvider := newProvider.(*APIServerProvider)
+	apiProvider.propagationPolicy = p.propagationPolicy
 	if err := apiProvider.initGVRCache(); err != nil {
 		return nil, err
 	}
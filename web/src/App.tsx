@@ -77,6 +77,7 @@ function App() {
       const results: QueryResponse[] = [];
       const uniqueResults = new Set<string>();
       let newAggregateResults: AggregateResult = {};
+      let newAggregateRows: AggregateResult[] = [];
 
       for (const singleQuery of queries) {
         const result = await executeQuery(singleQuery);
@@ -86,7 +87,10 @@ function App() {
           const parsedResult = JSON.parse(result.result);
           for (const [key, value] of Object.entries(parsedResult)) {
             if (key === 'aggregate') {
-              if (typeof value === 'object' && value !== null) {
+              if (Array.isArray(value)) {
+                // Grouped aggregations are returned as one row per group
+                newAggregateRows = [...newAggregateRows, ...value];
+              } else if (typeof value === 'object' && value !== null) {
                 newAggregateResults = { ...newAggregateResults, ...value };
               } else {
                 console.warn(`Unexpected aggregate value type: ${typeof value}`);
@@ -113,7 +117,8 @@ function App() {
               acc[key].push(parsed[key]);
               return acc;
             }, {}),
-            ...(Object.keys(newAggregateResults).length > 0 ? { aggregate: newAggregateResults } : {})
+            ...(Object.keys(newAggregateResults).length > 0 ? { aggregate: newAggregateResults } : {}),
+            ...(newAggregateRows.length > 0 ? { aggregate: newAggregateRows } : {})
           },
           null,
           2